@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/cosmos/iavl"
+	dbm "github.com/cosmos/iavl/db"
+
+	"github.com/dadamu/contract-wasmvm/internal/contract/executor"
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+	"github.com/dadamu/contract-wasmvm/internal/contract/runtime"
+	"github.com/dadamu/contract-wasmvm/internal/runner"
+	"github.com/dadamu/contract-wasmvm/internal/store"
+	"github.com/dadamu/contract-wasmvm/internal/transaction"
+)
+
+const treeCacheSize = 10_000
+
+// queryTimeout bounds the execution of the queries and simulations served by
+// the node
+const queryTimeout = 5 * time.Second
+
+type app struct {
+	cfg    config
+	db     *dbm.GoLevelDB
+	store  *store.Store
+	runner *runner.TxRunner
+}
+
+// openApp loads the latest version of the database, the options configure the
+// runner
+func openApp(cfg config, opts ...runner.Option) (*app, error) {
+	db, err := dbm.NewGoLevelDB("state", cfg.home)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	tree := iavl.NewMutableTree(db, treeCacheSize, false, iavl.NewNopLogger())
+	if _, err := tree.Load(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	s := store.NewStore(tree)
+	return &app{
+		cfg:    cfg,
+		db:     db,
+		store:  s,
+		runner: runner.NewTxRunner(*executor.NewContractExecutor(runtime.NewEngine()), s.GetCached(), opts...),
+	}, nil
+}
+
+// newQueryRunner creates the runner of the queries and simulations served by
+// the node, their execution is interrupted after queryTimeout. The returned
+// ticker must be stopped once the runner is no longer used.
+func (a *app) newQueryRunner() (*runner.TxRunner, *runtime.EpochTicker) {
+	engine := runtime.NewInterruptibleEngine()
+	ticker := runtime.NewEpochTicker(engine)
+	contractExecutor := executor.NewContractExecutor(engine, executor.WithTimeout(queryTimeout))
+	return runner.NewTxRunner(*contractExecutor, a.store.GetCached()), ticker
+}
+
+func (a *app) close() {
+	a.db.Close()
+}
+
+// runTransaction runs the messages and prints the result, the state is saved
+// as a new version if commit is set and the transaction succeeded
+func (a *app) runTransaction(out io.Writer, commit bool, messages ...interfaces.VMMessage) (*runner.TxResult, error) {
+	cache := a.store.GetCached()
+	defer cache.Rollback()
+
+	result, err := a.runner.RunTransaction(transaction.NewTx(a.cfg.gasLimit, []byte(a.cfg.state), messages...))
+	printResult(out, result)
+	if err != nil || !commit {
+		return result, err
+	}
+
+	cache.Commit()
+	version := a.store.WorkingVersion()
+	hash, err := a.store.SaveVersionWithId(version)
+	if err != nil {
+		return result, fmt.Errorf("failed to save state: %w", err)
+	}
+
+	fmt.Fprintf(out, "version: %d (%X)\n", version, hash)
+	return result, nil
+}
+
+func (a *app) deploy(out io.Writer, path string) error {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// The code is stored with the next code id
+	codeId := a.store.GetCached().GetTotalContractAmount()
+
+	_, err = a.runTransaction(out, true, interfaces.DeployContractCodeMessage{Code: code, Sender: a.cfg.sender})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "code id: %d\n", codeId)
+	return nil
+}
+
+func (a *app) instantiate(out io.Writer, codeIdArg string, args string) error {
+	codeId, err := strconv.ParseUint(codeIdArg, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid code id %s: %w", codeIdArg, err)
+	}
+
+	result, err := a.runTransaction(out, true, interfaces.InitializeContractMessage{
+		CodeId: codeId,
+		Args:   []byte(args),
+		Sender: a.cfg.sender,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, event := range result.Events {
+		if event.Event == "initialized" {
+			fmt.Fprintf(out, "contract: %s\n", event.ContractId)
+		}
+	}
+	return nil
+}
+
+func (a *app) execute(out io.Writer, contract, method, args string, commit bool) error {
+	_, err := a.runTransaction(out, commit, interfaces.NewContractMessage(contract, method, []byte(args), a.cfg.sender))
+	return err
+}
+
+func (a *app) dumpState(out io.Writer, prefix string) error {
+	return a.store.Iterate([]byte(prefix), func(key, value []byte) bool {
+		fmt.Fprintf(out, "%s = %s\n", key, formatBytes(value))
+		return true
+	})
+}
+
+func (a *app) versions(out io.Writer) error {
+	versions, err := a.store.Versions()
+	if err != nil {
+		return err
+	}
+
+	for _, version := range versions {
+		fmt.Fprintf(out, "%d %X\n", version.Version, version.Hash)
+	}
+	return nil
+}
+
+func printResult(out io.Writer, result *runner.TxResult) {
+	if result == nil {
+		return
+	}
+
+	report := result.GasReport
+	fmt.Fprintf(out, "gas used: %d / %d\n", report.Total, report.Limit)
+	for _, category := range slices.Sorted(maps.Keys(report.Categories)) {
+		fmt.Fprintf(out, "  %s: %d\n", category, report.Categories[category])
+	}
+
+	if len(result.Events) > 0 {
+		fmt.Fprintln(out, "events:")
+	}
+	for _, event := range result.Events {
+		fmt.Fprintf(out, "  [%d] %s %s", event.MsgIndex, event.ContractId, event.Event)
+		for _, attribute := range event.Attributes {
+			fmt.Fprintf(out, " %s=%s", attribute.Key, formatBytes(attribute.Value))
+		}
+		fmt.Fprintln(out)
+	}
+}
+
+// formatBytes prints the value as a quoted string, or as hex if it is binary
+func formatBytes(value []byte) string {
+	if strconv.CanBackquote(string(value)) {
+		return strconv.Quote(string(value))
+	}
+	return fmt.Sprintf("0x%X", value)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	dbm "github.com/cosmos/iavl/db"
+
+	"github.com/dadamu/contract-wasmvm/internal/block"
+	"github.com/dadamu/contract-wasmvm/internal/indexer"
+	"github.com/dadamu/contract-wasmvm/internal/mempool"
+	"github.com/dadamu/contract-wasmvm/internal/runner"
+	"github.com/dadamu/contract-wasmvm/internal/server"
+)
+
+// devnet runs a single node chain on the database until it is interrupted
+func devnet(cfg config, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("devnet", flag.ContinueOnError)
+	flags.SetOutput(out)
+	addr := flags.String("addr", "127.0.0.1:26657", "address to listen on")
+	chainId := flags.String("chain-id", "devnet", "chain id the transactions are signed for")
+	blockTime := flags.Duration("block-time", time.Second, "interval between blocks")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 || *blockTime <= 0 {
+		return errUsage
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return runDevnet(ctx, cfg, listener, *chainId, *blockTime, out)
+}
+
+// runDevnet serves the node API on the listener and executes a block from the
+// mempool at every interval, until the context is done. The transactions must
+// be signed for the chain, the blocks are saved as versions of the database so
+// the chain continues after a restart, the pending transactions are lost.
+func runDevnet(ctx context.Context, cfg config, listener net.Listener, chainId string, blockTime time.Duration, out io.Writer) error {
+	defer listener.Close()
+
+	return withApp(cfg, func(app *app) error {
+		eventsDB, err := dbm.NewGoLevelDB("events", cfg.home)
+		if err != nil {
+			return fmt.Errorf("failed to open events database: %w", err)
+		}
+		defer eventsDB.Close()
+
+		ei := indexer.NewEventIndexer(eventsDB)
+		mp := mempool.NewMempool(mempool.WithChainId(chainId))
+		blockExecutor := block.NewExecutor(app.store, app.runner, mp, block.WithEventIndexer(ei))
+
+		// Queries are not signed, so they are run without signature
+		// verification, as the simulations
+		queryRunner, epochTicker := app.newQueryRunner()
+		defer epochTicker.Stop()
+
+		node := server.NewServer(app.store, app.runner,
+			server.WithEventIndexer(ei),
+			server.WithMempool(mp),
+			server.WithQueryRunner(queryRunner),
+		)
+
+		// Subscriptions stream until their connection is closed, so the
+		// server is closed instead of shut down gracefully
+		httpServer := &http.Server{Handler: node}
+		defer httpServer.Close()
+
+		serveErr := make(chan error, 1)
+		go func() {
+			serveErr <- httpServer.Serve(listener)
+		}()
+		fmt.Fprintf(out, "chain %s at height %d listening on %s\n", chainId, app.store.Version(), listener.Addr())
+
+		ticker := time.NewTicker(blockTime)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+
+			case err := <-serveErr:
+				return err
+
+			case <-ticker.C:
+				// Empty blocks are not produced, so the versions stay meaningful
+				if mp.Size() == 0 {
+					continue
+				}
+
+				b, err := node.ExecuteBlock(blockExecutor)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(out, "block %d: %d txs, %d rejected (%X)\n", b.Height, len(b.Txs), len(b.Rejected), b.AppHash)
+			}
+		}
+	}, runner.WithSignatureVerification(chainId))
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	checker "github.com/dadamu/contract-wasmvm/internal/code-checker"
+	"github.com/dadamu/contract-wasmvm/internal/inspector"
+)
+
+// inspect prints the content of a module, it does not use the database
+func inspect(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	flags.SetOutput(out)
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	profile := flags.String("profile", checker.ProfileDefault, "code check profile of the verdict")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errUsage
+	}
+
+	policy, err := checker.Profile(*profile)
+	if err != nil {
+		return err
+	}
+
+	code, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	report, err := inspector.Inspect(code, inspector.WithPolicy(policy))
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	_, err = fmt.Fprint(out, report)
+	return err
+}
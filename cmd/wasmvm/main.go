@@ -0,0 +1,146 @@
+// Command wasmvm deploys and runs contracts against a local iavl database.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dadamu/contract-wasmvm/internal/runner"
+)
+
+const usage = `Usage: wasmvm [flags] <command> [arguments]
+
+Commands:
+  deploy <file.wasm>                    store the contract code
+  instantiate <codeId> [args]           create a contract from the code
+  execute <contract> <method> [args]    run a contract method and save the state
+  query <contract> <method> [args]      run a contract method without saving the state
+  state dump [prefix]                   print the stored keys and values
+  versions                              print the saved versions
+  inspect [-json] [-profile name] <file.wasm>
+                                        describe the module and check it can be deployed
+  serve [-addr host:port]               serve the node API over HTTP
+  devnet [-addr host:port] [-chain-id id] [-block-time duration]
+                                        run a single node chain producing blocks
+                                        from the mempool and serve the node API
+
+Flags:
+`
+
+// errUsage is returned for invalid command lines, the usage is printed
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	err := run(os.Args[1:], os.Stdout)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+type config struct {
+	home     string
+	gasLimit uint64
+	sender   string
+	state    string
+}
+
+func run(args []string, out io.Writer) error {
+	var cfg config
+
+	flags := flag.NewFlagSet("wasmvm", flag.ContinueOnError)
+	flags.SetOutput(out)
+	flags.StringVar(&cfg.home, "home", ".wasmvm", "directory of the database")
+	flags.Uint64Var(&cfg.gasLimit, "gas", 10_000_000, "gas limit of the transaction")
+	flags.StringVar(&cfg.sender, "sender", "alice", "sender of the messages")
+	flags.StringVar(&cfg.state, "state", "", "global state passed to the contracts")
+	flags.Usage = func() {
+		fmt.Fprint(out, usage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	err := runCommand(cfg, flags.Args(), out)
+	if errors.Is(err, errUsage) {
+		flags.Usage()
+	}
+	return err
+}
+
+func runCommand(cfg config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "deploy":
+		if len(args) != 1 {
+			return errUsage
+		}
+		return withApp(cfg, func(app *app) error { return app.deploy(out, args[0]) })
+
+	case "instantiate":
+		if len(args) < 1 || len(args) > 2 {
+			return errUsage
+		}
+		return withApp(cfg, func(app *app) error { return app.instantiate(out, args[0], optionalArg(args, 1)) })
+
+	case "execute", "query":
+		if len(args) < 2 || len(args) > 3 {
+			return errUsage
+		}
+		commit := command == "execute"
+		return withApp(cfg, func(app *app) error { return app.execute(out, args[0], args[1], optionalArg(args, 2), commit) })
+
+	case "state":
+		if len(args) < 1 || len(args) > 2 || args[0] != "dump" {
+			return errUsage
+		}
+		return withApp(cfg, func(app *app) error { return app.dumpState(out, optionalArg(args, 1)) })
+
+	case "versions":
+		if len(args) != 0 {
+			return errUsage
+		}
+		return withApp(cfg, func(app *app) error { return app.versions(out) })
+
+	case "inspect":
+		return inspect(args, out)
+
+	case "serve":
+		return serve(cfg, args, out)
+
+	case "devnet":
+		return devnet(cfg, args, out)
+
+	default:
+		return fmt.Errorf("%w: unknown command %s", errUsage, command)
+	}
+}
+
+func optionalArg(args []string, index int) string {
+	if index < len(args) {
+		return args[index]
+	}
+	return ""
+}
+
+func withApp(cfg config, fn func(app *app) error, opts ...runner.Option) error {
+	app, err := openApp(cfg, opts...)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+
+	return fn(app)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dadamu/contract-wasmvm/internal/auth"
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+	"github.com/dadamu/contract-wasmvm/internal/inspector"
+	"github.com/dadamu/contract-wasmvm/internal/server"
+	"github.com/dadamu/contract-wasmvm/internal/transaction"
+)
+
+// runWasmvm runs the command line against the database in home
+func runWasmvm(t *testing.T, home string, args ...string) (string, error) {
+	var out bytes.Buffer
+	err := run(append([]string{"-home", home}, args...), &out)
+	return out.String(), err
+}
+
+func TestDeployInstantiateExecute(t *testing.T) {
+	home := t.TempDir()
+
+	out, err := runWasmvm(t, home, "deploy", "../../internal/runner/testdata/test.wasm")
+	require.NoError(t, err)
+	require.Contains(t, out, "code id: 0")
+
+	out, err = runWasmvm(t, home, "instantiate", "0")
+	require.NoError(t, err)
+	matches := regexp.MustCompile(`contract: (\w+)`).FindStringSubmatch(out)
+	require.Len(t, matches, 2)
+	contract := matches[1]
+
+	// Queries do not save the state
+	out, err = runWasmvm(t, home, "query", contract, "addOne")
+	require.NoError(t, err)
+	require.Contains(t, out, "gas used:")
+	require.NotContains(t, out, "version:")
+
+	out, err = runWasmvm(t, home, "state", "dump", "contracts/initialized/")
+	require.NoError(t, err)
+	require.Empty(t, out)
+
+	out, err = runWasmvm(t, home, "execute", contract, "addOne")
+	require.NoError(t, err)
+	require.Contains(t, out, "version: 3")
+
+	out, err = runWasmvm(t, home, "state", "dump", "contracts/initialized/")
+	require.NoError(t, err)
+	require.Equal(t, "contracts/initialized/"+contract+"/test = 0x01000000\n", out)
+
+	out, err = runWasmvm(t, home, "versions")
+	require.NoError(t, err)
+	require.Len(t, regexp.MustCompile(`(?m)^\d+ [0-9A-F]+$`).FindAllString(out, -1), 3)
+}
+
+func TestFailedExecution(t *testing.T) {
+	home := t.TempDir()
+
+	_, err := runWasmvm(t, home, "deploy", "../../internal/runner/testdata/test.wasm")
+	require.NoError(t, err)
+
+	out, err := runWasmvm(t, home, "instantiate", "0")
+	require.NoError(t, err)
+	contract := regexp.MustCompile(`contract: (\w+)`).FindStringSubmatch(out)[1]
+
+	out, err = runWasmvm(t, home, "execute", contract, "crash")
+	require.ErrorContains(t, err, "WASM called abort")
+	require.Contains(t, out, "gas used:")
+
+	out, err = runWasmvm(t, home, "versions")
+	require.NoError(t, err)
+	require.Len(t, regexp.MustCompile(`(?m)^\d+ `).FindAllString(out, -1), 2)
+}
+
+func TestInvalidUsage(t *testing.T) {
+	home := t.TempDir()
+
+	out, err := runWasmvm(t, home, "execute", "contract")
+	require.ErrorIs(t, err, errUsage)
+	require.Contains(t, out, "Usage: wasmvm")
+
+	_, err = runWasmvm(t, home, "instantiate", "first")
+	require.ErrorContains(t, err, "invalid code id first")
+}
+
+func TestInspect(t *testing.T) {
+	out, err := runWasmvm(t, t.TempDir(), "inspect", "-json", "../../internal/runner/testdata/test.wasm")
+	require.NoError(t, err)
+
+	var report inspector.Report
+	require.NoError(t, json.Unmarshal([]byte(out), &report))
+	require.True(t, report.Verdict.Passed)
+
+	out, err = runWasmvm(t, t.TempDir(), "inspect", "-profile", "strict", "../../internal/runner/testdata/test.wasm")
+	require.NoError(t, err)
+	require.Contains(t, out, "verdict: rejected")
+}
+
+// startDevnet runs the devnet in the background until the returned function
+// is called
+func startDevnet(t *testing.T, home string) (*server.Client, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- runDevnet(ctx, config{home: home}, listener, "devnet", 10*time.Millisecond, io.Discard)
+	}()
+
+	return server.NewClient("http://" + listener.Addr().String()), func() {
+		cancel()
+		require.NoError(t, <-done)
+	}
+}
+
+// waitForTx waits for the transaction to be included in a block
+func waitForTx(t *testing.T, client *server.Client, hash string) *server.TxResponse {
+	var response *server.TxResponse
+	require.Eventually(t, func() bool {
+		var err error
+		response, err = client.Tx(hash)
+		require.NoError(t, err)
+		return !response.Pending
+	}, 5*time.Second, 10*time.Millisecond)
+	return response
+}
+
+func TestDevnet(t *testing.T) {
+	home := t.TempDir()
+	client, stop := startDevnet(t, home)
+
+	key, err := auth.GenerateEd25519PrivKey()
+	require.NoError(t, err)
+	address := key.PubKey().Address()
+
+	code, err := os.ReadFile("../../internal/runner/testdata/test.wasm")
+	require.NoError(t, err)
+
+	tx := transaction.NewTx(10_000_000, nil,
+		interfaces.DeployContractCodeMessage{Code: code, Sender: address},
+		interfaces.InitializeContractMessage{CodeId: 0, Sender: address},
+	)
+	tx.ChainId = "devnet"
+	require.NoError(t, tx.Sign(key))
+
+	response, err := client.BroadcastTx(tx)
+	require.NoError(t, err)
+	require.True(t, response.Pending)
+
+	response = waitForTx(t, client, response.Hash)
+	require.Empty(t, response.Error)
+	require.Equal(t, uint64(1), response.Height)
+	contract := response.Events[0].ContractId
+	stop()
+
+	// The chain continues from the saved state after a restart
+	client, stop = startDevnet(t, home)
+	defer stop()
+
+	_, err = client.Contract(contract)
+	require.NoError(t, err)
+
+	tx = transaction.NewTx(10_000_000, nil, interfaces.NewContractMessage(contract, "addOne", nil, address))
+	tx.ChainId = "devnet"
+	tx.Sequence = 1
+	require.NoError(t, tx.Sign(key))
+
+	response, err = client.BroadcastTx(tx)
+	require.NoError(t, err)
+	response = waitForTx(t, client, response.Hash)
+	require.Empty(t, response.Error)
+	require.Equal(t, uint64(2), response.Height)
+
+	// Queries are not signed
+	response, err = client.Query(server.QueryRequest{Contract: contract, Method: "addOne", Sender: address})
+	require.NoError(t, err)
+	require.Empty(t, response.Error)
+	require.NotEmpty(t, response.Changeset)
+}
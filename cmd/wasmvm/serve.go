@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+
+	dbm "github.com/cosmos/iavl/db"
+
+	"github.com/dadamu/contract-wasmvm/internal/indexer"
+	"github.com/dadamu/contract-wasmvm/internal/server"
+)
+
+// serve runs the node server on the database until it fails
+func serve(cfg config, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(out)
+	addr := flags.String("addr", "127.0.0.1:26657", "address to listen on")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errUsage
+	}
+
+	return withApp(cfg, func(app *app) error {
+		// The events are indexed next to the state, so subscriptions can resume
+		eventsDB, err := dbm.NewGoLevelDB("events", cfg.home)
+		if err != nil {
+			return fmt.Errorf("failed to open events database: %w", err)
+		}
+		defer eventsDB.Close()
+
+		queryRunner, ticker := app.newQueryRunner()
+		defer ticker.Stop()
+
+		handler := server.NewServer(app.store, app.runner,
+			server.WithEventIndexer(indexer.NewEventIndexer(eventsDB)),
+			server.WithQueryRunner(queryRunner),
+		)
+		fmt.Fprintf(out, "listening on %s\n", *addr)
+		return http.ListenAndServe(*addr, handler)
+	})
+}
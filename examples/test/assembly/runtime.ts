@@ -19,6 +19,14 @@ namespace contract {
 
 namespace event {
   export declare function emit(event: string, data: string): void;
+
+  export declare function emit_attrs(event: string, attrs: ArrayBuffer): void;
+}
+
+namespace bank {
+  export declare function balance(address: string, denom: string): u64;
+
+  export declare function transfer(to: string, denom: string, amount: u64): void;
 }
 
 export const save = db.save;
@@ -26,3 +34,43 @@ export const load = db.load;
 export const contractCall = contract.call;
 export const createContract = contract.create;
 export const emitEvent = event.emit;
+export const emitEventAttrs = event.emit_attrs;
+export const balance = bank.balance;
+export const transfer = bank.transfer;
+
+// EventAttributes encodes the attributes passed to `event.emit_attrs`:
+// a u32 attribute count followed by length-prefixed keys and values.
+export class EventAttributes {
+  private keys: ArrayBuffer[] = [];
+  private values: ArrayBuffer[] = [];
+
+  add(key: string, value: ArrayBuffer): EventAttributes {
+    this.keys.push(String.UTF8.encode(key));
+    this.values.push(value);
+    return this;
+  }
+
+  encode(): ArrayBuffer {
+    let size = 4;
+    for (let i = 0; i < this.keys.length; i++) {
+      size += 8 + this.keys[i].byteLength + this.values[i].byteLength;
+    }
+
+    const buffer = new ArrayBuffer(size);
+    const view = new DataView(buffer);
+    view.setUint32(0, this.keys.length, true);
+
+    let offset = 4;
+    for (let i = 0; i < this.keys.length; i++) {
+      offset = writeChunk(buffer, offset, this.keys[i]);
+      offset = writeChunk(buffer, offset, this.values[i]);
+    }
+    return buffer;
+  }
+}
+
+function writeChunk(buffer: ArrayBuffer, offset: i32, chunk: ArrayBuffer): i32 {
+  new DataView(buffer).setUint32(offset, chunk.byteLength, true);
+  Uint8Array.wrap(buffer).set(Uint8Array.wrap(chunk), offset + 4);
+  return offset + 4 + chunk.byteLength;
+}
@@ -0,0 +1,205 @@
+// Package auth defines the account keys, the addresses derived from them and
+// the signatures authenticating the senders of transactions.
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+type KeyType string
+
+const (
+	KeyTypeEd25519   KeyType = "ed25519"
+	KeyTypeSecp256k1 KeyType = "secp256k1"
+)
+
+// AddressSize is the number of hash bytes encoded in an address
+const AddressSize = 20
+
+// signatureSize is the size of both ed25519 and compact secp256k1 signatures
+const signatureSize = 64
+
+// Key type tags of the binary encoding of public keys
+const (
+	keyTagEd25519   byte = 0x01
+	keyTagSecp256k1 byte = 0x02
+)
+
+// PubKey is an ed25519 key or a compressed secp256k1 key
+type PubKey struct {
+	Type KeyType `json:"type"`
+	Key  []byte  `json:"key"`
+}
+
+// Validate checks the key is a valid point of its curve
+func (pk PubKey) Validate() error {
+	switch pk.Type {
+	case KeyTypeEd25519:
+		if len(pk.Key) != ed25519.PublicKeySize {
+			return fmt.Errorf("invalid ed25519 public key size %d", len(pk.Key))
+		}
+	case KeyTypeSecp256k1:
+		if len(pk.Key) != secp256k1.PubKeyBytesLenCompressed {
+			return fmt.Errorf("invalid secp256k1 public key size %d", len(pk.Key))
+		}
+		if _, err := secp256k1.ParsePubKey(pk.Key); err != nil {
+			return fmt.Errorf("invalid secp256k1 public key: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown key type %q", pk.Type)
+	}
+	return nil
+}
+
+// Address returns the base58 encoding of the first AddressSize bytes of the
+// SHA-256 of the key
+func (pk PubKey) Address() string {
+	hash := sha256.Sum256(pk.Key)
+	return base58.Encode(hash[:AddressSize])
+}
+
+// Verify checks the signature of the message, secp256k1 signatures are over
+// the SHA-256 of the message and must have a low S to be non malleable
+func (pk PubKey) Verify(msg, signature []byte) bool {
+	if len(signature) != signatureSize || pk.Validate() != nil {
+		return false
+	}
+
+	switch pk.Type {
+	case KeyTypeEd25519:
+		return ed25519.Verify(ed25519.PublicKey(pk.Key), msg, signature)
+
+	case KeyTypeSecp256k1:
+		key, err := secp256k1.ParsePubKey(pk.Key)
+		if err != nil {
+			return false
+		}
+
+		var r, s secp256k1.ModNScalar
+		if r.SetByteSlice(signature[:32]) || s.SetByteSlice(signature[32:]) {
+			return false
+		}
+		if r.IsZero() || s.IsZero() || s.IsOverHalfOrder() {
+			return false
+		}
+
+		hash := sha256.Sum256(msg)
+		return ecdsa.NewSignature(&r, &s).Verify(hash[:], key)
+	}
+	return false
+}
+
+// Bytes returns the binary encoding of the key, a type tag followed by the key
+func (pk PubKey) Bytes() []byte {
+	var tag byte
+	switch pk.Type {
+	case KeyTypeEd25519:
+		tag = keyTagEd25519
+	case KeyTypeSecp256k1:
+		tag = keyTagSecp256k1
+	}
+	return append([]byte{tag}, pk.Key...)
+}
+
+// ParsePubKey decodes and validates the binary encoding of a key
+func ParsePubKey(bz []byte) (PubKey, error) {
+	if len(bz) == 0 {
+		return PubKey{}, errors.New("empty public key")
+	}
+
+	var pk PubKey
+	switch bz[0] {
+	case keyTagEd25519:
+		pk.Type = KeyTypeEd25519
+	case keyTagSecp256k1:
+		pk.Type = KeyTypeSecp256k1
+	default:
+		return PubKey{}, fmt.Errorf("unknown key type tag 0x%02x", bz[0])
+	}
+
+	pk.Key = append([]byte(nil), bz[1:]...)
+	return pk, pk.Validate()
+}
+
+// PrivKey signs messages for the account of its public key
+type PrivKey interface {
+	PubKey() PubKey
+	Sign(msg []byte) ([]byte, error)
+}
+
+type ed25519PrivKey struct {
+	key ed25519.PrivateKey
+}
+
+// NewEd25519PrivKey derives the ed25519 key of the 32 bytes seed
+func NewEd25519PrivKey(seed []byte) (PrivKey, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid ed25519 seed size %d", len(seed))
+	}
+	return ed25519PrivKey{key: ed25519.NewKeyFromSeed(seed)}, nil
+}
+
+// GenerateEd25519PrivKey creates a random ed25519 key
+func GenerateEd25519PrivKey() (PrivKey, error) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return nil, err
+	}
+	return ed25519PrivKey{key: key}, nil
+}
+
+func (k ed25519PrivKey) PubKey() PubKey {
+	return PubKey{Type: KeyTypeEd25519, Key: k.key.Public().(ed25519.PublicKey)}
+}
+
+func (k ed25519PrivKey) Sign(msg []byte) ([]byte, error) {
+	return ed25519.Sign(k.key, msg), nil
+}
+
+type secp256k1PrivKey struct {
+	key *secp256k1.PrivateKey
+}
+
+// NewSecp256k1PrivKey parses the 32 bytes secp256k1 secret
+func NewSecp256k1PrivKey(secret []byte) (PrivKey, error) {
+	if len(secret) != secp256k1.PrivKeyBytesLen {
+		return nil, fmt.Errorf("invalid secp256k1 secret size %d", len(secret))
+	}
+
+	key := secp256k1.PrivKeyFromBytes(secret)
+	if key.Key.IsZero() {
+		return nil, errors.New("invalid secp256k1 secret")
+	}
+	return secp256k1PrivKey{key: key}, nil
+}
+
+// GenerateSecp256k1PrivKey creates a random secp256k1 key
+func GenerateSecp256k1PrivKey() (PrivKey, error) {
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	return secp256k1PrivKey{key: key}, nil
+}
+
+func (k secp256k1PrivKey) PubKey() PubKey {
+	return PubKey{Type: KeyTypeSecp256k1, Key: k.key.PubKey().SerializeCompressed()}
+}
+
+// Sign returns the R and S values of the signature of the SHA-256 of the
+// message, S is always low
+func (k secp256k1PrivKey) Sign(msg []byte) ([]byte, error) {
+	hash := sha256.Sum256(msg)
+	signature := ecdsa.Sign(k.key, hash[:])
+
+	r, s := signature.R(), signature.S()
+	rBytes, sBytes := r.Bytes(), s.Bytes()
+	return append(rBytes[:], sBytes[:]...), nil
+}
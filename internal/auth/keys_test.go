@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func generateKeys(t *testing.T) []PrivKey {
+	ed25519Key, err := GenerateEd25519PrivKey()
+	require.NoError(t, err)
+	secp256k1Key, err := GenerateSecp256k1PrivKey()
+	require.NoError(t, err)
+	return []PrivKey{ed25519Key, secp256k1Key}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	for _, key := range generateKeys(t) {
+		pubKey := key.PubKey()
+		require.NoError(t, pubKey.Validate())
+
+		signature, err := key.Sign([]byte("message"))
+		require.NoError(t, err)
+		require.Len(t, signature, 64)
+
+		require.True(t, pubKey.Verify([]byte("message"), signature), pubKey.Type)
+		require.False(t, pubKey.Verify([]byte("other message"), signature), pubKey.Type)
+
+		signature[0] ^= 0xff
+		require.False(t, pubKey.Verify([]byte("message"), signature), pubKey.Type)
+	}
+}
+
+func TestSecp256k1HighSRejected(t *testing.T) {
+	key, err := GenerateSecp256k1PrivKey()
+	require.NoError(t, err)
+	signature, err := key.Sign([]byte("message"))
+	require.NoError(t, err)
+
+	// N - S is also a valid ECDSA signature, only the low S form is accepted
+	var s secp256k1.ModNScalar
+	s.SetByteSlice(signature[32:])
+	highS := s.Negate().Bytes()
+	malleated := append(append([]byte(nil), signature[:32]...), highS[:]...)
+
+	require.False(t, key.PubKey().Verify([]byte("message"), malleated))
+}
+
+func TestAddress(t *testing.T) {
+	seed := make([]byte, 32)
+	key, err := NewEd25519PrivKey(seed)
+	require.NoError(t, err)
+
+	// The address is deterministic and differs between keys
+	other, err := NewEd25519PrivKey(append(seed[:31], 1))
+	require.NoError(t, err)
+	require.Equal(t, key.PubKey().Address(), key.PubKey().Address())
+	require.NotEqual(t, key.PubKey().Address(), other.PubKey().Address())
+}
+
+func TestPubKeyEncoding(t *testing.T) {
+	for _, key := range generateKeys(t) {
+		pubKey, err := ParsePubKey(key.PubKey().Bytes())
+		require.NoError(t, err)
+		require.Equal(t, key.PubKey(), pubKey)
+	}
+
+	_, err := ParsePubKey([]byte{0x01, 0x00})
+	require.ErrorContains(t, err, "invalid ed25519 public key size 1")
+
+	_, err = ParsePubKey([]byte{0x09})
+	require.ErrorContains(t, err, "unknown key type tag 0x09")
+
+	invalidPoint := make([]byte, 34)
+	invalidPoint[0], invalidPoint[1] = 0x02, 0x05
+	_, err = ParsePubKey(invalidPoint)
+	require.ErrorContains(t, err, "invalid secp256k1 public key")
+}
+
+func TestVerifySignatures(t *testing.T) {
+	keys := generateKeys(t)
+
+	var signatures []Signature
+	for _, key := range keys {
+		signature, err := Sign(key, []byte("sign bytes"))
+		require.NoError(t, err)
+		signatures = append(signatures, signature)
+	}
+
+	signers, err := VerifySignatures([]byte("sign bytes"), signatures)
+	require.NoError(t, err)
+	require.Len(t, signers, 2)
+	require.Contains(t, signers, keys[0].PubKey().Address())
+	require.Contains(t, signers, keys[1].PubKey().Address())
+
+	_, err = VerifySignatures([]byte("other bytes"), signatures)
+	require.ErrorIs(t, err, ErrUnauthorized)
+	require.ErrorContains(t, err, "signature 0 is invalid")
+}
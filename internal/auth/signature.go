@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnauthorized is returned when a message sender did not sign the transaction
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Signature is the signature of the sign bytes of a transaction by the key
+type Signature struct {
+	PubKey    PubKey `json:"pub_key"`
+	Signature []byte `json:"signature"`
+}
+
+// Sign signs the bytes with the key
+func Sign(key PrivKey, signBytes []byte) (Signature, error) {
+	signature, err := key.Sign(signBytes)
+	if err != nil {
+		return Signature{}, err
+	}
+	return Signature{PubKey: key.PubKey(), Signature: signature}, nil
+}
+
+// VerifySignatures checks every signature is valid for the sign bytes and
+// returns the addresses of the signers
+func VerifySignatures(signBytes []byte, signatures []Signature) (map[string]PubKey, error) {
+	signers := make(map[string]PubKey, len(signatures))
+	for i, signature := range signatures {
+		if err := signature.PubKey.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: signature %d: %w", ErrUnauthorized, i, err)
+		}
+		if !signature.PubKey.Verify(signBytes, signature.Signature) {
+			return nil, fmt.Errorf("%w: signature %d is invalid", ErrUnauthorized, i)
+		}
+		signers[signature.PubKey.Address()] = signature.PubKey
+	}
+	return signers, nil
+}
@@ -0,0 +1,150 @@
+// Package block executes the transactions reaped from the mempool as blocks,
+// every block is saved as a new version of the store.
+package block
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dadamu/contract-wasmvm/internal/indexer"
+	"github.com/dadamu/contract-wasmvm/internal/mempool"
+	"github.com/dadamu/contract-wasmvm/internal/runner"
+	"github.com/dadamu/contract-wasmvm/internal/store"
+)
+
+const (
+	// DefaultMaxBlockTxs is the maximum number of transactions of a block
+	DefaultMaxBlockTxs = 500
+	// DefaultMaxBlockGas is the maximum sum of the gas limits of the
+	// transactions of a block
+	DefaultMaxBlockGas = 100_000_000
+)
+
+// Block is an executed block with the transactions included in it
+type Block struct {
+	Height uint64
+	// AppHash is the root hash of the store once the block is saved
+	AppHash []byte
+	Txs     []*TxResult
+	// Rejected are the transactions removed from the mempool without being
+	// included, with the error of the runner
+	Rejected []*TxResult
+}
+
+// TxResult is the result of a transaction run in the block, the error is set
+// if it failed
+type TxResult struct {
+	Entry  *mempool.Entry
+	Result *runner.TxResult
+	Err    error
+}
+
+type Executor struct {
+	store   *store.Store
+	runner  *runner.TxRunner
+	mempool *mempool.Mempool
+
+	// indexer is nil unless the events of the blocks are indexed
+	indexer *indexer.EventIndexer
+
+	maxBlockTxs int
+	maxBlockGas uint64
+}
+
+type Option func(*Executor)
+
+// WithEventIndexer indexes the events of the included transactions
+func WithEventIndexer(ei *indexer.EventIndexer) Option {
+	return func(e *Executor) {
+		e.indexer = ei
+	}
+}
+
+// WithMaxBlockTxs overrides the maximum number of transactions of a block
+func WithMaxBlockTxs(count int) Option {
+	return func(e *Executor) {
+		e.maxBlockTxs = count
+	}
+}
+
+// WithMaxBlockGas overrides the maximum sum of the gas limits of the
+// transactions of a block
+func WithMaxBlockGas(gas uint64) Option {
+	return func(e *Executor) {
+		e.maxBlockGas = gas
+	}
+}
+
+// NewExecutor creates an executor running the transactions of the mempool with
+// the runner, the runner must run on the cache of the store and verify the
+// signatures so the sequences of the senders are incremented
+func NewExecutor(s *store.Store, r *runner.TxRunner, mp *mempool.Mempool, opts ...Option) *Executor {
+	executor := &Executor{
+		store:       s,
+		runner:      r,
+		mempool:     mp,
+		maxBlockTxs: DefaultMaxBlockTxs,
+		maxBlockGas: DefaultMaxBlockGas,
+	}
+
+	for _, opt := range opts {
+		opt(executor)
+	}
+	return executor
+}
+
+// ExecuteBlock runs a batch of the mempool at the working version of the store
+// and saves the included transactions as a new version. The included
+// transactions and those rejected by the runner are removed from the mempool,
+// except those carrying a future sequence. A failure of the store stops the
+// block, which is not saved.
+func (e *Executor) ExecuteBlock() (block *Block, err error) {
+	cache := e.store.GetCached()
+	defer cache.Rollback()
+
+	// The runner raises the errors of the store as panics
+	defer func() {
+		if p := recover(); p != nil {
+			block, err = nil, fmt.Errorf("failed to execute block: %v", p)
+		}
+	}()
+
+	height := e.store.WorkingVersion()
+	e.runner.SetBlockHeight(height)
+
+	block = &Block{Height: height}
+	var removed [][]byte
+	for _, entry := range e.mempool.Reap(e.maxBlockTxs, e.maxBlockGas, cache.GetSequence) {
+		result, err := e.runner.RunTransaction(entry.Tx)
+		if !result.Included {
+			// A transaction may wait for the previous sequences of its sender
+			if !errors.Is(err, runner.ErrInvalidSequence) {
+				removed = append(removed, entry.Hash)
+				block.Rejected = append(block.Rejected, &TxResult{Entry: entry, Result: result, Err: err})
+			}
+			continue
+		}
+
+		removed = append(removed, entry.Hash)
+		block.Txs = append(block.Txs, &TxResult{Entry: entry, Result: result, Err: err})
+	}
+
+	cache.Commit()
+	hash, err := e.store.SaveVersionWithId(height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save block %d: %w", height, err)
+	}
+	block.AppHash = hash
+
+	e.mempool.Remove(removed...)
+	e.mempool.Recheck(height+1, cache.GetSequence)
+
+	if e.indexer != nil {
+		for i, tx := range block.Txs {
+			if err := e.indexer.IndexEvents(height, uint32(i), tx.Result.Events); err != nil {
+				return nil, fmt.Errorf("failed to index events: %w", err)
+			}
+		}
+	}
+	return block, nil
+}
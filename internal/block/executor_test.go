@@ -0,0 +1,183 @@
+package block
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cosmos/iavl"
+	dbm "github.com/cosmos/iavl/db"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/dadamu/contract-wasmvm/internal/auth"
+	"github.com/dadamu/contract-wasmvm/internal/contract/executor"
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+	"github.com/dadamu/contract-wasmvm/internal/contract/runtime"
+	"github.com/dadamu/contract-wasmvm/internal/indexer"
+	"github.com/dadamu/contract-wasmvm/internal/mempool"
+	"github.com/dadamu/contract-wasmvm/internal/runner"
+	"github.com/dadamu/contract-wasmvm/internal/store"
+	"github.com/dadamu/contract-wasmvm/internal/transaction"
+)
+
+type ExecutorTestSuite struct {
+	suite.Suite
+	store    *store.Store
+	mempool  *mempool.Mempool
+	indexer  *indexer.EventIndexer
+	executor *Executor
+
+	alice    auth.PrivKey
+	contract string
+}
+
+func (s *ExecutorTestSuite) SetupTest() {
+	tree := iavl.NewMutableTree(dbm.NewMemDB(), 100, false, iavl.NewNopLogger())
+	s.store = store.NewStore(tree)
+	s.mempool = mempool.NewMempool(mempool.WithChainId("testnet"))
+	s.indexer = indexer.NewEventIndexer(dbm.NewMemDB())
+
+	r := runner.NewTxRunner(*executor.NewContractExecutor(runtime.NewEngine()), s.store.GetCached(), runner.WithSignatureVerification("testnet"))
+	s.executor = NewExecutor(s.store, r, s.mempool, WithEventIndexer(s.indexer))
+
+	var err error
+	s.alice, err = auth.GenerateEd25519PrivKey()
+	s.Require().NoError(err)
+
+	code, err := os.ReadFile("../runner/testdata/test.wasm")
+	s.Require().NoError(err)
+
+	address := s.alice.PubKey().Address()
+	s.addTx(s.alice, 0, 1,
+		interfaces.DeployContractCodeMessage{Code: code, Sender: address},
+		interfaces.InitializeContractMessage{CodeId: 0, Sender: address},
+	)
+
+	block, err := s.executor.ExecuteBlock()
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(1), block.Height)
+	s.Require().Len(block.Txs, 1)
+	s.Require().NoError(block.Txs[0].Err)
+	s.contract = block.Txs[0].Result.Events[0].ContractId
+}
+
+func TestExecutorTestSuite(t *testing.T) {
+	suite.Run(t, new(ExecutorTestSuite))
+}
+
+// -----------------------------------------------------------------------------
+
+func (s *ExecutorTestSuite) addTx(key auth.PrivKey, sequence, gasPrice uint64, messages ...interfaces.VMMessage) *mempool.Entry {
+	tx := transaction.NewTx(100_000, nil, messages...)
+	tx.ChainId = "testnet"
+	tx.Sequence = sequence
+	tx.GasPrice = gasPrice
+	s.Require().NoError(tx.Sign(key))
+
+	entry, err := s.mempool.AddTx(tx)
+	s.Require().NoError(err)
+	return entry
+}
+
+func (s *ExecutorTestSuite) addOne(key auth.PrivKey, sequence, gasPrice uint64) *mempool.Entry {
+	return s.addTx(key, sequence, gasPrice, interfaces.NewContractMessage(s.contract, "addOne", nil, key.PubKey().Address()))
+}
+
+func (s *ExecutorTestSuite) TestExecuteBlock() {
+	bob, err := auth.GenerateSecp256k1PrivKey()
+	s.Require().NoError(err)
+
+	// Bob pays more, but his second transaction waits for his first one
+	aliceTx := s.addOne(s.alice, 1, 5)
+	bobTxs := []*mempool.Entry{
+		s.addOne(bob, 0, 1),
+		s.addTx(bob, 1, 10, interfaces.NewContractMessage(s.contract, "emitEvent", nil, bob.PubKey().Address())),
+	}
+
+	block, err := s.executor.ExecuteBlock()
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(2), block.Height)
+	s.Require().Len(block.Txs, 3)
+	s.Require().Equal(aliceTx, block.Txs[0].Entry)
+	s.Require().Equal(bobTxs[0], block.Txs[1].Entry)
+	s.Require().Equal(bobTxs[1], block.Txs[2].Entry)
+	s.Require().Zero(s.mempool.Size())
+
+	// The block is saved with its hash
+	versions, err := s.store.Versions()
+	s.Require().NoError(err)
+	s.Require().Equal(block.AppHash, versions[len(versions)-1].Hash)
+	s.Require().Equal(uint64(2), s.store.GetCached().GetSequence(bob.PubKey().Address()))
+
+	// The events are indexed with the position of their transaction
+	result, err := s.indexer.Query(indexer.EventQuery{FromHeight: 2, ToHeight: 2})
+	s.Require().NoError(err)
+	s.Require().Len(result.Events, 1)
+	s.Require().Equal(uint32(2), result.Events[0].TxIndex)
+}
+
+func (s *ExecutorTestSuite) TestFailedTransaction() {
+	// A failed execution is included so the sequence is used
+	s.addTx(s.alice, 1, 1, interfaces.NewContractMessage(s.contract, "unknownMethod", nil, s.alice.PubKey().Address()))
+
+	block, err := s.executor.ExecuteBlock()
+	s.Require().NoError(err)
+	s.Require().Len(block.Txs, 1)
+	s.Require().Error(block.Txs[0].Err)
+	s.Require().Zero(s.mempool.Size())
+	s.Require().Equal(uint64(2), s.store.GetCached().GetSequence(s.alice.PubKey().Address()))
+}
+
+func (s *ExecutorTestSuite) TestPendingSequence() {
+	// A transaction with a future sequence stays in the mempool until the
+	// previous one arrives
+	next := s.addOne(s.alice, 2, 1)
+
+	block, err := s.executor.ExecuteBlock()
+	s.Require().NoError(err)
+	s.Require().Empty(block.Txs)
+	s.Require().Equal(1, s.mempool.Size())
+
+	s.addOne(s.alice, 1, 1)
+	block, err = s.executor.ExecuteBlock()
+	s.Require().NoError(err)
+	s.Require().Len(block.Txs, 2)
+	s.Require().Equal(next, block.Txs[1].Entry)
+	s.Require().Zero(s.mempool.Size())
+}
+
+func (s *ExecutorTestSuite) TestExpiredTransaction() {
+	tx := transaction.NewTx(100_000, nil, interfaces.NewContractMessage(s.contract, "addOne", nil, s.alice.PubKey().Address()))
+	tx.ChainId = "testnet"
+	tx.Sequence = 2
+	tx.TimeoutHeight = 2
+	s.Require().NoError(tx.Sign(s.alice))
+	_, err := s.mempool.AddTx(tx)
+	s.Require().NoError(err)
+
+	// The transaction waits for sequence 1 until it expires
+	block, err := s.executor.ExecuteBlock()
+	s.Require().NoError(err)
+	s.Require().Empty(block.Txs)
+	s.Require().Zero(s.mempool.Size())
+}
+
+func (s *ExecutorTestSuite) TestRejectedTransaction() {
+	// The mempool accepts the transactions of any chain without a chain id
+	mp := mempool.NewMempool()
+	r := runner.NewTxRunner(*executor.NewContractExecutor(runtime.NewEngine()), s.store.GetCached(), runner.WithSignatureVerification("testnet"))
+	blockExecutor := NewExecutor(s.store, r, mp)
+
+	tx := transaction.NewTx(100_000, nil, interfaces.NewContractMessage(s.contract, "addOne", nil, s.alice.PubKey().Address()))
+	tx.ChainId = "mainnet"
+	tx.Sequence = 1
+	s.Require().NoError(tx.Sign(s.alice))
+	_, err := mp.AddTx(tx)
+	s.Require().NoError(err)
+
+	block, err := blockExecutor.ExecuteBlock()
+	s.Require().NoError(err)
+	s.Require().Empty(block.Txs)
+	s.Require().Len(block.Rejected, 1)
+	s.Require().ErrorIs(block.Rejected[0].Err, runner.ErrInvalidChainId)
+	s.Require().Zero(mp.Size())
+}
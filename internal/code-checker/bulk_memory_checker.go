@@ -0,0 +1,43 @@
+package checker
+
+import "fmt"
+
+var bulkMemoryOpcodes = map[Opcode]bool{
+	newPrefixedOpcode(PrefixMisc, 0x08): true, // memory.init
+	newPrefixedOpcode(PrefixMisc, 0x09): true, // data.drop
+	newPrefixedOpcode(PrefixMisc, 0x0A): true, // memory.copy
+	newPrefixedOpcode(PrefixMisc, 0x0B): true, // memory.fill
+	newPrefixedOpcode(PrefixMisc, 0x0C): true, // table.init
+	newPrefixedOpcode(PrefixMisc, 0x0D): true, // elem.drop
+	newPrefixedOpcode(PrefixMisc, 0x0E): true, // table.copy
+}
+
+// checkBulkMemoryOps reports every bulk memory operation and passive segment of a Wasm module
+func checkBulkMemoryOps(module *Module) []Violation {
+	var violations []Violation
+
+	for i, segment := range module.DataSegments {
+		if segment.Flags == 1 {
+			violations = append(violations, NewViolation(
+				RuleBulkMemory, module, nil, segment.Offset, fmt.Sprintf("passive data segment %d", i),
+			))
+		}
+	}
+
+	for i, segment := range module.Elements {
+		// Bit 0 marks passive and declarative segments
+		if segment.Flags&0x01 != 0 {
+			violations = append(violations, NewViolation(
+				RuleBulkMemory, module, nil, segment.Offset, fmt.Sprintf("passive element segment %d", i),
+			))
+		}
+	}
+
+	module.WalkInstructions(func(function *Function, instruction Instruction) {
+		if bulkMemoryOpcodes[instruction.Opcode] {
+			violations = append(violations, NewInstructionViolation(RuleBulkMemory, module, function, instruction))
+		}
+	})
+
+	return violations
+}
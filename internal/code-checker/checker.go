@@ -1,32 +1,57 @@
 package checker
 
+// CheckCode decodes the module and reports every construct rejected by the
+// default policy
+func CheckCode(wasmCode []byte) (*CheckReport, error) {
+	return DefaultPolicy().CheckCode(wasmCode)
+}
+
+// CheckModule reports the constructs of a decoded module rejected by the
+// default policy
+func CheckModule(module *Module) *CheckReport {
+	return DefaultPolicy().Check(module)
+}
+
+// CheckDeployable reports every construct of a decoded module preventing it
+// from being deployed: those rejected by the policy, imports and exports the
+// runtime cannot link and resources exceeding the limits
+func CheckDeployable(module *Module, policy *Policy, hostFunctions []HostFunction, limits CodeLimits) *CheckReport {
+	report := policy.Check(module)
+	report.Merge(CheckConformance(module, hostFunctions))
+	report.Merge(CheckLimits(module, limits))
+	return report
+}
+
 func ContainUndeterminsticOps(wasmCode []byte) (bool, error) {
-	// Check for floating point operations
-	floatingPoint, err := containsFloatingPointOps(wasmCode)
+	report, err := CheckCode(wasmCode)
 	if err != nil {
 		return false, err
 	}
-	if floatingPoint {
-		return true, nil
+	return !report.Passed(), nil
+}
+
+// WalkInstructions calls fn for every instruction of the module, including
+// those of constant expressions for which the function is nil
+func (m *Module) WalkInstructions(fn func(function *Function, instruction Instruction)) {
+	walk := func(function *Function, expr []Instruction) {
+		for _, instruction := range expr {
+			fn(function, instruction)
+		}
 	}
 
-	// Check for SIMD operations
-	simd, err := containsSIMDOps(wasmCode)
-	if err != nil {
-		return false, err
+	for _, global := range m.Globals {
+		walk(nil, global.Init)
 	}
-	if simd {
-		return true, nil
+	for _, segment := range m.Elements {
+		walk(nil, segment.OffsetExpr)
+		for _, expr := range segment.Exprs {
+			walk(nil, expr)
+		}
 	}
-
-	// Check for threading operations
-	threading, err := containsThreadingOps(wasmCode)
-	if err != nil {
-		return false, err
+	for _, segment := range m.DataSegments {
+		walk(nil, segment.OffsetExpr)
 	}
-	if threading {
-		return true, nil
+	for i := range m.Functions {
+		walk(&m.Functions[i], m.Functions[i].Instructions)
 	}
-
-	return false, nil
 }
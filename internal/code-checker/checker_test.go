@@ -0,0 +1,140 @@
+package checker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bytecodealliance/wasmtime-go/v31"
+	"github.com/stretchr/testify/require"
+)
+
+// loadCorpus reads every module of the given testdata directory, compiling the
+// text format ones
+func loadCorpus(t *testing.T, dir string) map[string][]byte {
+	paths, err := filepath.Glob(filepath.Join("testdata", dir, "*"))
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	modules := make(map[string][]byte)
+	for _, path := range paths {
+		code, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		if strings.HasSuffix(path, ".wat") {
+			code, err = wasmtime.Wat2Wasm(string(code))
+			require.NoError(t, err, path)
+		}
+		modules[filepath.Base(path)] = code
+	}
+	return modules
+}
+
+func TestAcceptedCorpus(t *testing.T) {
+	engine := wasmtime.NewEngine()
+	for name, code := range loadCorpus(t, "accept") {
+		t.Run(name, func(t *testing.T) {
+			// The corpus only holds modules valid for the engine
+			_, err := wasmtime.NewModule(engine, code)
+			require.NoError(t, err)
+
+			isUndeterminstic, err := ContainUndeterminsticOps(code)
+			require.NoError(t, err)
+			require.False(t, isUndeterminstic)
+		})
+	}
+}
+
+func TestRejectedCorpus(t *testing.T) {
+	// The rule expected to reject a module is given by its file name prefix
+	rules := map[string]string{
+		"f32_":      RuleFloatingPoint,
+		"f64_":      RuleFloatingPoint,
+		"trunc_":    RuleFloatingPoint,
+		"simd_":     RuleSIMD,
+		"v128_":     RuleSIMD,
+		"atomic_":   RuleThreads,
+		"shared_":   RuleThreads,
+		"imported_": RuleThreads,
+	}
+
+	for name, code := range loadCorpus(t, "reject") {
+		t.Run(name, func(t *testing.T) {
+			isUndeterminstic, err := ContainUndeterminsticOps(code)
+			require.NoError(t, err)
+			require.True(t, isUndeterminstic)
+
+			report, err := CheckCode(code)
+			require.NoError(t, err)
+
+			var expected string
+			for prefix, rule := range rules {
+				if strings.HasPrefix(name, prefix) {
+					expected = rule
+				}
+			}
+			require.NotEmpty(t, expected)
+
+			for _, violation := range report.Violations {
+				require.Equal(t, expected, violation.Rule, violation.String())
+			}
+		})
+	}
+}
+
+func TestCustomSectionBytes(t *testing.T) {
+	code, err := wasmtime.Wat2Wasm(`(module (func (export "run")))`)
+	require.NoError(t, err)
+
+	// Custom section named "x" with opcode-like bytes in its payload
+	code = append(code, 0x00, 0x06, 0x01, 'x', 0x7B, 0xFD, 0xFE, 0x92)
+
+	isUndeterminstic, err := ContainUndeterminsticOps(code)
+	require.NoError(t, err)
+	require.False(t, isUndeterminstic)
+}
+
+func TestMalformedModules(t *testing.T) {
+	header := []byte{0x00, 0x61, 0x73, 0x6D, 0x01, 0x00, 0x00, 0x00}
+	withHeader := func(bz ...byte) []byte {
+		return append(append([]byte{}, header...), bz...)
+	}
+
+	testCases := []struct {
+		name string
+		code []byte
+	}{
+		{"too short", []byte{0x00, 0x61}},
+		{"wrong magic", []byte{0x00, 0x61, 0x73, 0x00, 0x01, 0x00, 0x00, 0x00}},
+		{"wrong version", []byte{0x00, 0x61, 0x73, 0x6D, 0x02, 0x00, 0x00, 0x00}},
+		{"truncated section", withHeader(0x01, 0x05, 0x01)},
+		{"unknown section", withHeader(0x2A, 0x00)},
+		{"section size mismatch", withHeader(0x05, 0x04, 0x01, 0x00, 0x01, 0x00)},
+		{"overlong LEB128", withHeader(0x05, 0x08, 0x01, 0x00, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00)},
+		{"function without code", withHeader(0x01, 0x04, 0x01, 0x60, 0x00, 0x00, 0x03, 0x02, 0x01, 0x00)},
+		{
+			"unknown opcode",
+			withHeader(
+				0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+				0x03, 0x02, 0x01, 0x00,
+				0x0A, 0x05, 0x01, 0x03, 0x00, 0x27, 0x0B,
+			),
+		},
+		{
+			"missing end",
+			withHeader(
+				0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+				0x03, 0x02, 0x01, 0x00,
+				0x0A, 0x05, 0x01, 0x03, 0x00, 0x02, 0x40,
+			),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ContainUndeterminsticOps(tc.code)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package checker
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	RuleImports   = "imports"
+	RuleExports   = "exports"
+	RuleStructure = "structure"
+)
+
+const (
+	MemoryExport    = "memory"
+	AllocatorExport = "__new"
+	InitExport      = "init"
+	StartExport     = "_start"
+
+	// runtimeExportPrefix marks the exports of the AssemblyScript runtime,
+	// e.g. __pin and __collect, which are not entry points
+	runtimeExportPrefix = "__"
+)
+
+var (
+	// EntryPointType is the signature of contract methods, called with the
+	// pointers to the state, the sender and the arguments
+	EntryPointType = FuncType{Params: []ValType{ValTypeI32, ValTypeI32, ValTypeI32}}
+
+	// AllocatorType is the signature of the allocator export taking the size
+	// and the class id of the object
+	AllocatorType = FuncType{Params: []ValType{ValTypeI32, ValTypeI32}, Results: []ValType{ValTypeI32}}
+
+	// StartType is the signature of the exported start function
+	StartType = FuncType{}
+)
+
+// allowedTargetFeatures lists the features a module may declare in its
+// target_features custom section
+var allowedTargetFeatures = map[string]bool{
+	"mutable-globals":        true,
+	"sign-ext":               true,
+	"bulk-memory":            true,
+	"bulk-memory-opt":        true,
+	"nontrapping-fptoint":    true,
+	"multivalue":             true,
+	"reference-types":        true,
+	"call-indirect-overlong": true,
+}
+
+// HostFunction is a function provided by the runtime to contracts
+type HostFunction struct {
+	Module string
+	Name   string
+	Type   FuncType
+}
+
+// Equal returns true if both signatures have the same parameters and results
+func (t FuncType) Equal(other FuncType) bool {
+	return slices.Equal(t.Params, other.Params) && slices.Equal(t.Results, other.Results)
+}
+
+// CheckConformance reports the imports the runtime does not provide and the
+// exports missing from or mismatching the contract interface
+func CheckConformance(module *Module, hostFunctions []HostFunction) *CheckReport {
+	report := &CheckReport{}
+	report.add(checkStructure(module)...)
+	report.add(checkImports(module, hostFunctions)...)
+	report.add(checkExports(module)...)
+	return report
+}
+
+func checkStructure(module *Module) []Violation {
+	var violations []Violation
+
+	for _, section := range module.Sections {
+		switch {
+		case section.ID == SectionStart:
+			violations = append(violations, NewViolation(
+				RuleStructure, module, nil, section.Offset,
+				fmt.Sprintf("start section, export the start function as %s instead", StartExport),
+			))
+		case section.ID == SectionTag:
+			violations = append(violations, NewViolation(
+				RuleStructure, module, nil, section.Offset, "exception handling tag section",
+			))
+		case section.ID == SectionCustom && section.Name == "target_features":
+			violations = append(violations, checkTargetFeatures(module, section)...)
+		}
+	}
+
+	return violations
+}
+
+type targetFeature struct {
+	prefix byte
+	name   string
+	offset int
+}
+
+// readTargetFeatures decodes the target_features custom section, a vector of
+// a prefix byte and a feature name
+func readTargetFeatures(section Section) ([]targetFeature, error) {
+	r := newReader(section.Payload, section.PayloadOffset)
+
+	// Skip the section name
+	_, err := r.readName()
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := r.readCount()
+	if err != nil {
+		return nil, err
+	}
+
+	features := make([]targetFeature, 0, count)
+	for i := uint32(0); i < count; i++ {
+		feature := targetFeature{offset: r.offset()}
+
+		feature.prefix, err = r.readByte()
+		if err != nil {
+			return nil, err
+		}
+
+		feature.name, err = r.readName()
+		if err != nil {
+			return nil, err
+		}
+
+		features = append(features, feature)
+	}
+	return features, nil
+}
+
+func checkTargetFeatures(module *Module, section Section) []Violation {
+	features, err := readTargetFeatures(section)
+	if err != nil {
+		return []Violation{NewViolation(
+			RuleStructure, module, nil, section.Offset, fmt.Sprintf("malformed target_features section: %s", err),
+		)}
+	}
+
+	var violations []Violation
+	for _, feature := range features {
+		// The - prefix marks disallowed features
+		if feature.prefix != '-' && !allowedTargetFeatures[feature.name] {
+			violations = append(violations, NewViolation(
+				RuleStructure, module, nil, feature.offset, fmt.Sprintf("unsupported target feature %s", feature.name),
+			))
+		}
+	}
+	return violations
+}
+
+func checkImports(module *Module, hostFunctions []HostFunction) []Violation {
+	provided := make(map[string]FuncType, len(hostFunctions))
+	for _, function := range hostFunctions {
+		provided[function.Module+"."+function.Name] = function.Type
+	}
+
+	var violations []Violation
+	for _, imp := range module.Imports {
+		if imp.Kind != ExternalFunc {
+			violations = append(violations, NewViolation(
+				RuleImports, module, nil, imp.Offset,
+				fmt.Sprintf("imported %s %s.%s, only functions can be imported", imp.Kind, imp.Module, imp.Name),
+			))
+			continue
+		}
+
+		expected, found := provided[imp.Module+"."+imp.Name]
+		if !found {
+			violations = append(violations, NewViolation(
+				RuleImports, module, nil, imp.Offset,
+				fmt.Sprintf("unknown host function %s.%s", imp.Module, imp.Name),
+			))
+			continue
+		}
+
+		if int(imp.TypeIndex) >= len(module.Types) || !module.Types[imp.TypeIndex].Equal(expected) {
+			violations = append(violations, NewViolation(
+				RuleImports, module, nil, imp.Offset,
+				fmt.Sprintf("host function %s.%s must have signature %s", imp.Module, imp.Name, expected),
+			))
+		}
+	}
+
+	return violations
+}
+
+func checkExports(module *Module) []Violation {
+	var violations []Violation
+
+	exports := make(map[string]Export, len(module.Exports))
+	for _, export := range module.Exports {
+		exports[export.Name] = export
+	}
+
+	memory, found := exports[MemoryExport]
+	if !found || memory.Kind != ExternalMemory {
+		violations = append(violations, NewViolation(
+			RuleExports, module, nil, module.sectionOffset(SectionExport),
+			fmt.Sprintf("missing %s memory export", MemoryExport),
+		))
+	}
+
+	for _, required := range []struct {
+		name     string
+		funcType FuncType
+	}{
+		{AllocatorExport, AllocatorType},
+		{InitExport, EntryPointType},
+	} {
+		if _, found := exports[required.name]; !found {
+			violations = append(violations, NewViolation(
+				RuleExports, module, nil, module.sectionOffset(SectionExport),
+				fmt.Sprintf("missing %s function export with signature %s", required.name, required.funcType),
+			))
+		}
+	}
+
+	for _, export := range module.Exports {
+		var expected FuncType
+		switch {
+		case export.Name == AllocatorExport:
+			expected = AllocatorType
+		case export.Name == StartExport:
+			expected = StartType
+		case strings.HasPrefix(export.Name, runtimeExportPrefix):
+			continue
+		case export.Kind == ExternalFunc:
+			expected = EntryPointType
+		default:
+			continue
+		}
+
+		funcType, found := module.FuncType(export.Index)
+		if export.Kind != ExternalFunc || !found || !funcType.Equal(expected) {
+			violations = append(violations, NewViolation(
+				RuleExports, module, nil, export.Offset,
+				fmt.Sprintf("export %s must be a function with signature %s", export.Name, expected),
+			))
+		}
+	}
+
+	return violations
+}
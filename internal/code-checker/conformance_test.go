@@ -0,0 +1,173 @@
+package checker
+
+import (
+	"os"
+	"testing"
+
+	"github.com/bytecodealliance/wasmtime-go/v31"
+	"github.com/stretchr/testify/require"
+)
+
+var testHostFunctions = []HostFunction{
+	{"runtime", "db.save", FuncType{Params: []ValType{ValTypeI32, ValTypeI32}}},
+	{"runtime", "db.load", FuncType{Params: []ValType{ValTypeI32}, Results: []ValType{ValTypeI32}}},
+	{"runtime", "contract.call", FuncType{Params: []ValType{ValTypeI32, ValTypeI32, ValTypeI32}}},
+	{"runtime", "contract.create", FuncType{Params: []ValType{ValTypeI64, ValTypeI32}, Results: []ValType{ValTypeI32}}},
+	{"runtime", "event.emit", FuncType{Params: []ValType{ValTypeI32, ValTypeI32}}},
+	{"env", "abort", FuncType{Params: []ValType{ValTypeI32, ValTypeI32, ValTypeI32, ValTypeI32}}},
+}
+
+// contractExports are the exports every contract needs
+const contractExports = `
+  (memory (export "memory") 1)
+  (func (export "__new") (param i32 i32) (result i32) (i32.const 1024))
+  (func (export "init") (param i32 i32 i32))
+`
+
+func checkConformance(t *testing.T, wat string) *CheckReport {
+	code, err := wasmtime.Wat2Wasm(wat)
+	require.NoError(t, err)
+
+	module, err := ParseModule(code)
+	require.NoError(t, err)
+
+	return CheckConformance(module, testHostFunctions)
+}
+
+func TestConformanceAssemblyScript(t *testing.T) {
+	code, err := os.ReadFile("testdata/accept/assemblyscript.wasm")
+	require.NoError(t, err)
+
+	module, err := ParseModule(code)
+	require.NoError(t, err)
+
+	report := CheckConformance(module, testHostFunctions)
+	require.True(t, report.Passed(), report.String())
+}
+
+func TestConformance(t *testing.T) {
+	testCases := []struct {
+		name     string
+		wat      string
+		expected []string
+	}{
+		{
+			name: "conforming contract",
+			wat: `(module
+			  (import "runtime" "db.load" (func (param i32) (result i32)))
+			  ` + contractExports + `
+			  (func (export "_start"))
+			  (func (export "__pin") (param i32) (result i32) (local.get 0))
+			  (func (export "run") (param i32 i32 i32))
+			  (global (export "__rtti_base") i32 (i32.const 0)))`,
+		},
+		{
+			name: "unknown host function",
+			wat: `(module
+			  (import "runtime" "db.delete" (func (param i32)))
+			  ` + contractExports + `)`,
+			expected: []string{"imports: unknown host function runtime.db.delete"},
+		},
+		{
+			name: "mismatching host function signature",
+			wat: `(module
+			  (import "runtime" "db.load" (func (param i64) (result i32)))
+			  ` + contractExports + `)`,
+			expected: []string{"imports: host function runtime.db.load must have signature (i32) -> (i32)"},
+		},
+		{
+			name: "imported memory and table",
+			wat: `(module
+			  (import "env" "memory" (memory 1))
+			  (import "env" "table" (table 1 funcref))
+			  (export "memory" (memory 0))
+			  (func (export "__new") (param i32 i32) (result i32) (i32.const 1024))
+			  (func (export "init") (param i32 i32 i32)))`,
+			expected: []string{
+				"imports: imported memory env.memory, only functions can be imported",
+				"imports: imported table env.table, only functions can be imported",
+			},
+		},
+		{
+			name: "start section",
+			wat: `(module
+			  ` + contractExports + `
+			  (func $start)
+			  (start $start))`,
+			expected: []string{"structure: start section, export the start function as _start instead"},
+		},
+		{
+			name: "missing exports",
+			wat:  `(module (func (export "run") (param i32 i32 i32)))`,
+			expected: []string{
+				"exports: missing memory memory export",
+				"exports: missing __new function export with signature (i32, i32) -> (i32)",
+				"exports: missing init function export with signature (i32, i32, i32) -> ()",
+			},
+		},
+		{
+			name: "mismatching export signatures",
+			wat: `(module
+			  (memory (export "memory") 1)
+			  (func (export "__new") (param i32) (result i32) (i32.const 1024))
+			  (func (export "init") (param i32 i32 i32) (result i32) (i32.const 0))
+			  (func (export "_start") (param i32)))`,
+			expected: []string{
+				"exports: export __new must be a function with signature (i32, i32) -> (i32)",
+				"exports: export init must be a function with signature (i32, i32, i32) -> ()",
+				"exports: export _start must be a function with signature () -> ()",
+			},
+		},
+		{
+			name: "memory export of the wrong kind",
+			wat: `(module
+			  (func (export "memory") (param i32 i32 i32))
+			  (func (export "__new") (param i32 i32) (result i32) (i32.const 1024))
+			  (func (export "init") (param i32 i32 i32)))`,
+			expected: []string{"exports: missing memory memory export"},
+		},
+		{
+			name: "exception handling tags",
+			wat: `(module
+			  ` + contractExports + `
+			  (tag $error))`,
+			expected: []string{"structure: exception handling tag section"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			report := checkConformance(t, tc.wat)
+			require.Len(t, report.Violations, len(tc.expected), report.String())
+			for i, expected := range tc.expected {
+				require.Contains(t, report.Violations[i].String(), expected)
+			}
+		})
+	}
+}
+
+func TestConformanceTargetFeatures(t *testing.T) {
+	code, err := wasmtime.Wat2Wasm(`(module ` + contractExports + `)`)
+	require.NoError(t, err)
+
+	// target_features section declaring sign-ext, simd128 and disallowing atomics
+	payload := []byte{0x0F}
+	payload = append(payload, "target_features"...)
+	payload = append(payload, 0x03)
+	payload = append(payload, '+', 0x08)
+	payload = append(payload, "sign-ext"...)
+	payload = append(payload, '+', 0x07)
+	payload = append(payload, "simd128"...)
+	payload = append(payload, '-', 0x07)
+	payload = append(payload, "atomics"...)
+	code = append(code, 0x00, byte(len(payload)))
+	code = append(code, payload...)
+
+	module, err := ParseModule(code)
+	require.NoError(t, err)
+
+	report := CheckConformance(module, testHostFunctions)
+	require.Len(t, report.Violations, 1)
+	require.Equal(t, RuleStructure, report.Violations[0].Rule)
+	require.Equal(t, "unsupported target feature simd128", report.Violations[0].Message)
+}
@@ -0,0 +1,254 @@
+package checker
+
+// BlockTypeEmpty is the block type of blocks without results
+const BlockTypeEmpty int64 = -0x40
+
+type MemArg struct {
+	Align       uint32
+	MemoryIndex uint32
+	Offset      uint64
+}
+
+// Instruction is a decoded instruction with its immediates
+type Instruction struct {
+	Opcode Opcode
+	// Offset is the offset of the instruction in the binary
+	Offset int
+	// Raw is the encoding of the instruction including its immediates
+	Raw []byte
+	// BlockType is either BlockTypeEmpty, a negative value type encoding or a
+	// type index
+	BlockType int64
+	// Indices holds the index immediates, e.g. the function index of call or
+	// the labels of br_table with the default label last
+	Indices []uint32
+	// Types holds the value types of a typed select
+	Types  []ValType
+	MemArg MemArg
+	Value  int64
+}
+
+// BlockValType returns the single result type of a block, if any
+func (i Instruction) BlockValType() (ValType, bool) {
+	if i.BlockType >= 0 || i.BlockType == BlockTypeEmpty {
+		return 0, false
+	}
+	return ValType(byte(i.BlockType & 0x7F)), true
+}
+
+// decodeExpr decodes instructions until the end matching the implicit
+// outermost block, which is included in the result
+func decodeExpr(r *reader) ([]Instruction, error) {
+	var instructions []Instruction
+	depth := 1
+
+	for depth > 0 {
+		instruction, err := decodeInstruction(r)
+		if err != nil {
+			return nil, err
+		}
+
+		switch instruction.Opcode {
+		case OpBlock, OpLoop, OpIf, OpTry:
+			depth++
+		case OpEnd, OpDelegate:
+			// delegate terminates a try block like end does
+			depth--
+		}
+
+		instructions = append(instructions, instruction)
+	}
+	return instructions, nil
+}
+
+// readConstExpr decodes an initializer expression of globals and segments
+func readConstExpr(r *reader) ([]Instruction, error) {
+	return decodeExpr(r)
+}
+
+func decodeInstruction(r *reader) (Instruction, error) {
+	start := r.pos
+	instruction := Instruction{Offset: r.offset()}
+
+	b, err := r.readByte()
+	if err != nil {
+		return instruction, err
+	}
+
+	var kind immediateKind
+	switch b {
+	case PrefixMisc, PrefixSIMD, PrefixAtomics:
+		code, err := r.readU32()
+		if err != nil {
+			return instruction, err
+		}
+		instruction.Opcode = newPrefixedOpcode(b, code)
+
+		var ok bool
+		kind, ok = prefixedImmediate(b, code)
+		if !ok {
+			return instruction, r.errorf("unknown opcode %s", instruction.Opcode)
+		}
+	default:
+		instruction.Opcode = Opcode(b)
+
+		var ok bool
+		kind, ok = singleByteImmediate(b)
+		if !ok {
+			return instruction, r.errorf("unknown opcode 0x%02x", b)
+		}
+	}
+
+	err = readImmediates(r, kind, &instruction)
+	if err != nil {
+		return instruction, err
+	}
+
+	instruction.Raw = r.data[start:r.pos]
+	return instruction, nil
+}
+
+func singleByteImmediate(code byte) (immediateKind, bool) {
+	if code >= 0x28 && code <= 0x3E {
+		return immMemArg, true
+	}
+	if kind, ok := singleByteImmediates[uint32(code)]; ok {
+		return kind, true
+	}
+	_, ok := opcodeNames[uint32(code)]
+	return immNone, ok
+}
+
+func prefixedImmediate(prefix byte, code uint32) (immediateKind, bool) {
+	switch prefix {
+	case PrefixMisc:
+		if _, ok := miscOpcodeNames[code]; !ok {
+			return immNone, false
+		}
+		return miscImmediates[code], true
+	case PrefixSIMD:
+		if _, ok := simdOpcodeNames[code]; !ok {
+			return immNone, false
+		}
+		return simdImmediate(code), true
+	case PrefixAtomics:
+		if _, ok := atomicOpcodeNames[code]; !ok {
+			return immNone, false
+		}
+		return atomicImmediate(code), true
+	}
+	return immNone, false
+}
+
+func readImmediates(r *reader, kind immediateKind, instruction *Instruction) error {
+	var err error
+	switch kind {
+	case immNone:
+	case immBlockType:
+		instruction.BlockType, err = readBlockType(r)
+	case immIndex:
+		err = readIndices(r, instruction, 1)
+	case immTwoIndices:
+		err = readIndices(r, instruction, 2)
+	case immBrTable:
+		var count uint32
+		count, err = r.readCount()
+		if err == nil {
+			// The default label follows the label vector
+			err = readIndices(r, instruction, int(count)+1)
+		}
+	case immSelectTypes:
+		instruction.Types, err = readValTypes(r)
+	case immMemArg:
+		instruction.MemArg, err = readMemArg(r)
+	case immMemArgLane:
+		instruction.MemArg, err = readMemArg(r)
+		if err == nil {
+			_, err = r.readByte()
+		}
+	case immI32:
+		instruction.Value, err = r.readSigned(32)
+	case immI64:
+		instruction.Value, err = r.readSigned(64)
+	case immF32:
+		_, err = r.readBytes(4)
+	case immF64:
+		_, err = r.readBytes(8)
+	case immHeapType:
+		instruction.Value, err = r.readSigned(33)
+	case immLane:
+		_, err = r.readByte()
+	case immBytes16:
+		_, err = r.readBytes(16)
+	case immZeroByte:
+		var b byte
+		b, err = r.readByte()
+		if err == nil && b != 0 {
+			err = r.errorf("expected zero byte")
+		}
+	}
+	return err
+}
+
+func readIndices(r *reader, instruction *Instruction, count int) error {
+	for i := 0; i < count; i++ {
+		index, err := r.readU32()
+		if err != nil {
+			return err
+		}
+		instruction.Indices = append(instruction.Indices, index)
+	}
+	return nil
+}
+
+func readBlockType(r *reader) (int64, error) {
+	b, err := r.peekByte()
+	if err != nil {
+		return 0, err
+	}
+
+	// Empty and value types are single negative bytes, type indices positive
+	// signed 33 bit integers
+	if b == 0x40 {
+		r.pos++
+		return BlockTypeEmpty, nil
+	}
+	if _, ok := valTypeNames[ValType(b)]; ok {
+		r.pos++
+		return int64(b) - 0x80, nil
+	}
+
+	index, err := r.readSigned(33)
+	if err != nil {
+		return 0, err
+	}
+	if index < 0 {
+		return 0, r.errorf("invalid block type")
+	}
+	return index, nil
+}
+
+func readMemArg(r *reader) (MemArg, error) {
+	var memArg MemArg
+
+	align, err := r.readU32()
+	if err != nil {
+		return memArg, err
+	}
+
+	// Bit 6 of the alignment flags an explicit memory index (multi-memory)
+	if align&0x40 != 0 {
+		memArg.MemoryIndex, err = r.readU32()
+		if err != nil {
+			return memArg, err
+		}
+		align &^= 0x40
+	}
+	memArg.Align = align
+
+	memArg.Offset, err = r.readU64()
+	if err != nil {
+		return memArg, err
+	}
+	return memArg, nil
+}
@@ -0,0 +1,177 @@
+package checker
+
+import "fmt"
+
+const RuleLimits = "limits"
+
+// Names of the resource limits reported in violations
+const (
+	LimitCodeSize           = "code_size"
+	LimitFunctions          = "functions"
+	LimitFunctionSize       = "function_size"
+	LimitLocalsPerFunction  = "locals_per_function"
+	LimitGlobals            = "globals"
+	LimitTableSize          = "table_size"
+	LimitDataSegmentSize    = "data_segment_size"
+	LimitInitialMemoryPages = "initial_memory_pages"
+)
+
+// CodeLimits bounds the resources declared by a module so that compiling and
+// instantiating it stays cheap for validators, a zero limit is unlimited
+type CodeLimits struct {
+	// MaxCodeSize is the maximum size of the binary in bytes
+	MaxCodeSize uint64
+	// MaxFunctions is the maximum number of imported and defined functions
+	MaxFunctions uint64
+	// MaxFunctionSize is the maximum size of a function body in bytes
+	MaxFunctionSize uint64
+	// MaxLocalsPerFunction is the maximum number of parameters and locals of a function
+	MaxLocalsPerFunction uint64
+	// MaxGlobals is the maximum number of imported and defined globals
+	MaxGlobals uint64
+	// MaxTableSize is the maximum initial number of elements of a table
+	MaxTableSize uint64
+	// MaxDataSegmentSize is the maximum size of a data segment in bytes
+	MaxDataSegmentSize uint64
+	// MaxInitialMemoryPages is the maximum initial size of a memory in 64KiB pages
+	MaxInitialMemoryPages uint64
+}
+
+func DefaultCodeLimits() CodeLimits {
+	return CodeLimits{
+		MaxCodeSize:           800 * 1024,
+		MaxFunctions:          10_000,
+		MaxFunctionSize:       128 * 1024,
+		MaxLocalsPerFunction:  1024,
+		MaxGlobals:            512,
+		MaxTableSize:          4096,
+		MaxDataSegmentSize:    256 * 1024,
+		MaxInitialMemoryPages: 256,
+	}
+}
+
+// CheckCodeSize reports a binary exceeding the code size limit, it allows to
+// reject oversized code before decoding it
+func CheckCodeSize(size int, limits CodeLimits) *CheckReport {
+	report := &CheckReport{}
+	report.addLimit(nil, limitCheck{limit: LimitCodeSize, measured: uint64(size), maximum: limits.MaxCodeSize})
+	return report
+}
+
+// CheckLimits reports every resource of the module exceeding the limits
+func CheckLimits(module *Module, limits CodeLimits) *CheckReport {
+	report := CheckCodeSize(module.Size, limits)
+
+	functions := uint64(module.NumImportedFuncs()) + uint64(len(module.Functions))
+	report.addLimit(module, limitCheck{
+		limit:    LimitFunctions,
+		measured: functions,
+		maximum:  limits.MaxFunctions,
+		offset:   module.sectionOffset(SectionFunction),
+	})
+
+	for i := range module.Functions {
+		function := &module.Functions[i]
+		report.addLimit(module, limitCheck{
+			limit:    LimitFunctionSize,
+			measured: uint64(function.Size),
+			maximum:  limits.MaxFunctionSize,
+			offset:   function.Offset,
+			function: function,
+		})
+
+		var locals uint64
+		if funcType, found := module.FuncType(function.Index); found {
+			locals = uint64(len(funcType.Params))
+		}
+		for _, local := range function.Locals {
+			locals += uint64(local.Count)
+		}
+		report.addLimit(module, limitCheck{
+			limit:    LimitLocalsPerFunction,
+			measured: locals,
+			maximum:  limits.MaxLocalsPerFunction,
+			offset:   function.Offset,
+			function: function,
+		})
+	}
+
+	globals := uint64(len(module.Globals))
+	var tables []TableType
+	var memories []Limits
+	for _, imp := range module.Imports {
+		switch imp.Kind {
+		case ExternalGlobal:
+			globals++
+		case ExternalTable:
+			tables = append(tables, imp.Table)
+		case ExternalMemory:
+			memories = append(memories, imp.Memory)
+		}
+	}
+	tables = append(tables, module.Tables...)
+	memories = append(memories, module.Memories...)
+
+	report.addLimit(module, limitCheck{
+		limit:    LimitGlobals,
+		measured: globals,
+		maximum:  limits.MaxGlobals,
+		offset:   module.sectionOffset(SectionGlobal),
+	})
+
+	for i, table := range tables {
+		report.addLimit(module, limitCheck{
+			limit:    LimitTableSize,
+			measured: table.Limits.Min,
+			maximum:  limits.MaxTableSize,
+			offset:   module.sectionOffset(SectionTable),
+			subject:  fmt.Sprintf(" for table %d", i),
+		})
+	}
+
+	for i, memory := range memories {
+		report.addLimit(module, limitCheck{
+			limit:    LimitInitialMemoryPages,
+			measured: memory.Min,
+			maximum:  limits.MaxInitialMemoryPages,
+			offset:   module.sectionOffset(SectionMemory),
+			subject:  fmt.Sprintf(" for memory %d", i),
+		})
+	}
+
+	for i, segment := range module.DataSegments {
+		report.addLimit(module, limitCheck{
+			limit:    LimitDataSegmentSize,
+			measured: uint64(len(segment.Data)),
+			maximum:  limits.MaxDataSegmentSize,
+			offset:   segment.Offset,
+			subject:  fmt.Sprintf(" for data segment %d", i),
+		})
+	}
+
+	return report
+}
+
+// limitCheck is a measured resource with the location reported if it exceeds its limit
+type limitCheck struct {
+	limit    string
+	measured uint64
+	maximum  uint64
+	offset   int
+	subject  string
+	function *Function
+}
+
+// addLimit reports the measured value if it exceeds a non zero maximum
+func (r *CheckReport) addLimit(module *Module, check limitCheck) {
+	if check.maximum == 0 || check.measured <= check.maximum {
+		return
+	}
+
+	message := fmt.Sprintf("%s of %d exceeds the maximum of %d%s", check.limit, check.measured, check.maximum, check.subject)
+	violation := NewViolation(RuleLimits, module, check.function, check.offset, message)
+	violation.Limit = check.limit
+	violation.Measured = check.measured
+	violation.Maximum = check.maximum
+	r.add(violation)
+}
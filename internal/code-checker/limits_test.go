@@ -0,0 +1,83 @@
+package checker
+
+import (
+	"os"
+	"testing"
+
+	"github.com/bytecodealliance/wasmtime-go/v31"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultLimitsAssemblyScript(t *testing.T) {
+	code, err := os.ReadFile("testdata/accept/assemblyscript.wasm")
+	require.NoError(t, err)
+
+	module, err := ParseModule(code)
+	require.NoError(t, err)
+
+	report := CheckLimits(module, DefaultCodeLimits())
+	require.True(t, report.Passed(), report.String())
+}
+
+func TestCheckLimits(t *testing.T) {
+	code, err := wasmtime.Wat2Wasm(`
+		(module
+		  (import "env" "counter" (global i32))
+		  (memory 3)
+		  (table 10 funcref)
+		  (global i32 (i32.const 0))
+		  (func $small)
+		  (func $large (param i32 i32) (local i32 i32 i64)
+		    (drop (i32.add (local.get 0) (local.get 1))))
+		  (data (i32.const 0) "0123456789"))
+	`)
+	require.NoError(t, err)
+
+	module, err := ParseModule(code)
+	require.NoError(t, err)
+
+	// Zero limits are unlimited
+	require.True(t, CheckLimits(module, CodeLimits{}).Passed())
+
+	report := CheckLimits(module, CodeLimits{
+		MaxCodeSize:           uint64(len(code)) - 1,
+		MaxFunctions:          1,
+		MaxFunctionSize:       4,
+		MaxLocalsPerFunction:  4,
+		MaxGlobals:            1,
+		MaxTableSize:          8,
+		MaxDataSegmentSize:    5,
+		MaxInitialMemoryPages: 2,
+	})
+
+	measured := make(map[string]uint64)
+	for _, violation := range report.Violations {
+		require.Equal(t, RuleLimits, violation.Rule)
+		measured[violation.Limit] = violation.Measured
+	}
+
+	require.Equal(t, map[string]uint64{
+		LimitCodeSize:           uint64(len(code)),
+		LimitFunctions:          2,
+		LimitFunctionSize:       uint64(module.Functions[1].Size),
+		LimitLocalsPerFunction:  5,
+		LimitGlobals:            2,
+		LimitTableSize:          10,
+		LimitDataSegmentSize:    10,
+		LimitInitialMemoryPages: 3,
+	}, measured)
+
+	require.Contains(t, report.String(), "limits: locals_per_function of 5 exceeds the maximum of 4 in function 1 (large)")
+	require.Contains(t, report.String(), "limits: data_segment_size of 10 exceeds the maximum of 5 for data segment 0")
+}
+
+func TestCheckCodeSize(t *testing.T) {
+	limits := CodeLimits{MaxCodeSize: 100}
+	require.True(t, CheckCodeSize(100, limits).Passed())
+
+	report := CheckCodeSize(101, limits)
+	require.Len(t, report.Violations, 1)
+	require.Equal(t, LimitCodeSize, report.Violations[0].Limit)
+	require.Equal(t, uint64(101), report.Violations[0].Measured)
+	require.Equal(t, uint64(100), report.Violations[0].Maximum)
+}
@@ -0,0 +1,28 @@
+package checker
+
+import "fmt"
+
+// checkMemory64 reports every memory indexed with 64-bit addresses
+func checkMemory64(module *Module) []Violation {
+	var violations []Violation
+
+	for _, imp := range module.Imports {
+		if imp.Kind == ExternalMemory && imp.Memory.Memory64 {
+			violations = append(violations, NewViolation(
+				RuleMemory64, module, nil, imp.Offset,
+				fmt.Sprintf("64-bit imported memory %s.%s", imp.Module, imp.Name),
+			))
+		}
+	}
+
+	for i, memory := range module.Memories {
+		if memory.Memory64 {
+			violations = append(violations, NewViolation(
+				RuleMemory64, module, nil, module.sectionOffset(SectionMemory),
+				fmt.Sprintf("64-bit memory %d", i),
+			))
+		}
+	}
+
+	return violations
+}
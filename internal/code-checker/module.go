@@ -0,0 +1,939 @@
+package checker
+
+import (
+	"bytes"
+	"fmt"
+)
+
+var (
+	wasmMagic   = []byte{0x00, 0x61, 0x73, 0x6D}
+	wasmVersion = []byte{0x01, 0x00, 0x00, 0x00}
+)
+
+type SectionID byte
+
+const (
+	SectionCustom    SectionID = 0
+	SectionType      SectionID = 1
+	SectionImport    SectionID = 2
+	SectionFunction  SectionID = 3
+	SectionTable     SectionID = 4
+	SectionMemory    SectionID = 5
+	SectionGlobal    SectionID = 6
+	SectionExport    SectionID = 7
+	SectionStart     SectionID = 8
+	SectionElement   SectionID = 9
+	SectionCode      SectionID = 10
+	SectionData      SectionID = 11
+	SectionDataCount SectionID = 12
+	SectionTag       SectionID = 13
+)
+
+var sectionNames = map[SectionID]string{
+	SectionCustom:    "custom",
+	SectionType:      "type",
+	SectionImport:    "import",
+	SectionFunction:  "function",
+	SectionTable:     "table",
+	SectionMemory:    "memory",
+	SectionGlobal:    "global",
+	SectionExport:    "export",
+	SectionStart:     "start",
+	SectionElement:   "element",
+	SectionCode:      "code",
+	SectionData:      "data",
+	SectionDataCount: "datacount",
+	SectionTag:       "tag",
+}
+
+func (id SectionID) String() string {
+	if name, ok := sectionNames[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", byte(id))
+}
+
+type ValType byte
+
+const (
+	ValTypeI32       ValType = 0x7F
+	ValTypeI64       ValType = 0x7E
+	ValTypeF32       ValType = 0x7D
+	ValTypeF64       ValType = 0x7C
+	ValTypeV128      ValType = 0x7B
+	ValTypeFuncRef   ValType = 0x70
+	ValTypeExternRef ValType = 0x6F
+)
+
+var valTypeNames = map[ValType]string{
+	ValTypeI32:       "i32",
+	ValTypeI64:       "i64",
+	ValTypeF32:       "f32",
+	ValTypeF64:       "f64",
+	ValTypeV128:      "v128",
+	ValTypeFuncRef:   "funcref",
+	ValTypeExternRef: "externref",
+}
+
+func (t ValType) String() string {
+	if name, ok := valTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%02x", byte(t))
+}
+
+type ExternalKind byte
+
+const (
+	ExternalFunc   ExternalKind = 0
+	ExternalTable  ExternalKind = 1
+	ExternalMemory ExternalKind = 2
+	ExternalGlobal ExternalKind = 3
+	ExternalTag    ExternalKind = 4
+)
+
+var externalKindNames = map[ExternalKind]string{
+	ExternalFunc:   "func",
+	ExternalTable:  "table",
+	ExternalMemory: "memory",
+	ExternalGlobal: "global",
+	ExternalTag:    "tag",
+}
+
+func (k ExternalKind) String() string {
+	if name, ok := externalKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", byte(k))
+}
+
+type FuncType struct {
+	Params  []ValType
+	Results []ValType
+}
+
+func (t FuncType) String() string {
+	return fmt.Sprintf("(%s) -> (%s)", joinValTypes(t.Params), joinValTypes(t.Results))
+}
+
+func joinValTypes(types []ValType) string {
+	var buf bytes.Buffer
+	for i, t := range types {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(t.String())
+	}
+	return buf.String()
+}
+
+type Limits struct {
+	Min      uint64
+	Max      uint64
+	HasMax   bool
+	Shared   bool
+	Memory64 bool
+}
+
+type TableType struct {
+	ElemType ValType
+	Limits   Limits
+}
+
+type GlobalType struct {
+	ValType ValType
+	Mutable bool
+}
+
+type Import struct {
+	Module    string
+	Name      string
+	Kind      ExternalKind
+	TypeIndex uint32
+	Table     TableType
+	Memory    Limits
+	Global    GlobalType
+	Offset    int
+}
+
+type Export struct {
+	Name   string
+	Kind   ExternalKind
+	Index  uint32
+	Offset int
+}
+
+type Global struct {
+	Type   GlobalType
+	Init   []Instruction
+	Offset int
+}
+
+type ElementSegment struct {
+	Flags       uint32
+	TableIndex  uint32
+	OffsetExpr  []Instruction
+	ElemType    ValType
+	FuncIndices []uint32
+	Exprs       [][]Instruction
+	Offset      int
+}
+
+type DataSegment struct {
+	Flags       uint32
+	MemoryIndex uint32
+	OffsetExpr  []Instruction
+	Data        []byte
+	Offset      int
+}
+
+type LocalEntry struct {
+	Count uint32
+	Type  ValType
+}
+
+type Function struct {
+	// Index is the index of the function in the function index space,
+	// including the imported functions
+	Index        uint32
+	TypeIndex    uint32
+	Locals       []LocalEntry
+	Instructions []Instruction
+	Offset       int
+	Size         int
+}
+
+type Section struct {
+	ID   SectionID
+	Name string // name of custom sections
+	// Offset is the offset of the section id byte in the binary
+	Offset  int
+	Payload []byte
+	// PayloadOffset is the offset of the payload in the binary
+	PayloadOffset int
+}
+
+// Module is a decoded WASM binary
+type Module struct {
+	// Size is the size of the binary in bytes
+	Size int
+
+	Sections      []Section
+	Types         []FuncType
+	Imports       []Import
+	Tables        []TableType
+	Memories      []Limits
+	Globals       []Global
+	Exports       []Export
+	Start         *uint32
+	Elements      []ElementSegment
+	DataSegments  []DataSegment
+	DataCount     *uint32
+	Functions     []Function
+	FunctionNames map[uint32]string
+
+	functionTypes []uint32
+}
+
+// ParseModule decodes the sections of a WASM binary and the instructions of
+// every function body
+func ParseModule(wasmCode []byte) (*Module, error) {
+	if len(wasmCode) < 8 {
+		return nil, fmt.Errorf("invalid Wasm binary: too short")
+	}
+
+	if !bytes.Equal(wasmCode[0:4], wasmMagic) {
+		return nil, fmt.Errorf("invalid Wasm binary: wrong magic number")
+	}
+
+	if !bytes.Equal(wasmCode[4:8], wasmVersion) {
+		return nil, fmt.Errorf("invalid Wasm binary: unsupported version")
+	}
+
+	module := &Module{
+		Size:          len(wasmCode),
+		FunctionNames: make(map[uint32]string),
+	}
+
+	r := newReader(wasmCode[8:], 8)
+	for !r.eof() {
+		offset := r.offset()
+		id, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+
+		size, err := r.readU32()
+		if err != nil {
+			return nil, err
+		}
+
+		payloadOffset := r.offset()
+		payload, err := r.readBytes(int(size))
+		if err != nil {
+			return nil, err
+		}
+
+		section := Section{
+			ID:            SectionID(id),
+			Offset:        offset,
+			Payload:       payload,
+			PayloadOffset: payloadOffset,
+		}
+
+		err = module.parseSection(&section)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s section: %w", section.ID, err)
+		}
+		module.Sections = append(module.Sections, section)
+	}
+
+	if len(module.functionTypes) != len(module.Functions) {
+		return nil, fmt.Errorf("function and code section have inconsistent lengths")
+	}
+
+	for i := range module.Functions {
+		module.Functions[i].TypeIndex = module.functionTypes[i]
+	}
+
+	return module, nil
+}
+
+// NumImportedFuncs returns the number of imported functions, which precede the
+// defined functions in the function index space
+func (m *Module) NumImportedFuncs() uint32 {
+	var count uint32
+	for _, imp := range m.Imports {
+		if imp.Kind == ExternalFunc {
+			count++
+		}
+	}
+	return count
+}
+
+// FuncType returns the signature of the function at the given index of the
+// function index space
+func (m *Module) FuncType(index uint32) (FuncType, bool) {
+	var typeIndex uint32
+	imported := m.NumImportedFuncs()
+	if index < imported {
+		var i uint32
+		for _, imp := range m.Imports {
+			if imp.Kind != ExternalFunc {
+				continue
+			}
+			if i == index {
+				typeIndex = imp.TypeIndex
+				break
+			}
+			i++
+		}
+	} else {
+		defined := index - imported
+		if int(defined) >= len(m.Functions) {
+			return FuncType{}, false
+		}
+		typeIndex = m.Functions[defined].TypeIndex
+	}
+
+	if int(typeIndex) >= len(m.Types) {
+		return FuncType{}, false
+	}
+	return m.Types[typeIndex], true
+}
+
+// FuncName returns the export name of the function, or its debug name from the
+// name section, if any
+func (m *Module) FuncName(index uint32) string {
+	for _, export := range m.Exports {
+		if export.Kind == ExternalFunc && export.Index == index {
+			return export.Name
+		}
+	}
+	return m.FunctionNames[index]
+}
+
+// sectionOffset returns the payload offset of the first section with the given
+// id, used to locate declarations which are not located individually
+func (m *Module) sectionOffset(id SectionID) int {
+	for _, section := range m.Sections {
+		if section.ID == id {
+			return section.PayloadOffset
+		}
+	}
+	return 0
+}
+
+func (m *Module) parseSection(section *Section) error {
+	r := newReader(section.Payload, section.PayloadOffset)
+
+	var err error
+	switch section.ID {
+	case SectionCustom:
+		section.Name, err = r.readName()
+		if err != nil {
+			return err
+		}
+		if section.Name == "name" {
+			// The name section is informative only, a malformed one is ignored
+			_ = m.parseNameSection(r)
+		}
+		return nil
+	case SectionType:
+		err = m.parseTypeSection(r)
+	case SectionImport:
+		err = m.parseImportSection(r)
+	case SectionFunction:
+		err = m.parseFunctionSection(r)
+	case SectionTable:
+		err = m.parseTableSection(r)
+	case SectionMemory:
+		err = m.parseMemorySection(r)
+	case SectionGlobal:
+		err = m.parseGlobalSection(r)
+	case SectionExport:
+		err = m.parseExportSection(r)
+	case SectionStart:
+		var start uint32
+		start, err = r.readU32()
+		m.Start = &start
+	case SectionElement:
+		err = m.parseElementSection(r)
+	case SectionCode:
+		err = m.parseCodeSection(r)
+	case SectionData:
+		err = m.parseDataSection(r)
+	case SectionDataCount:
+		var count uint32
+		count, err = r.readU32()
+		m.DataCount = &count
+	case SectionTag:
+		// Tags are kept as raw payload, their use is rejected by the checker
+		return nil
+	default:
+		return fmt.Errorf("unknown section id %d", byte(section.ID))
+	}
+	if err != nil {
+		return err
+	}
+
+	if !r.eof() {
+		return r.errorf("section size mismatch")
+	}
+	return nil
+}
+
+func (m *Module) parseTypeSection(r *reader) error {
+	count, err := r.readCount()
+	if err != nil {
+		return err
+	}
+
+	for i := uint32(0); i < count; i++ {
+		form, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		if form != 0x60 {
+			return r.errorf("unsupported type form 0x%02x", form)
+		}
+
+		params, err := readValTypes(r)
+		if err != nil {
+			return err
+		}
+
+		results, err := readValTypes(r)
+		if err != nil {
+			return err
+		}
+
+		m.Types = append(m.Types, FuncType{Params: params, Results: results})
+	}
+	return nil
+}
+
+func (m *Module) parseImportSection(r *reader) error {
+	count, err := r.readCount()
+	if err != nil {
+		return err
+	}
+
+	for i := uint32(0); i < count; i++ {
+		imp := Import{Offset: r.offset()}
+
+		imp.Module, err = r.readName()
+		if err != nil {
+			return err
+		}
+
+		imp.Name, err = r.readName()
+		if err != nil {
+			return err
+		}
+
+		kind, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		imp.Kind = ExternalKind(kind)
+
+		switch imp.Kind {
+		case ExternalFunc:
+			imp.TypeIndex, err = r.readU32()
+		case ExternalTable:
+			imp.Table, err = readTableType(r)
+		case ExternalMemory:
+			imp.Memory, err = readLimits(r)
+		case ExternalGlobal:
+			imp.Global, err = readGlobalType(r)
+		case ExternalTag:
+			// Attribute byte followed by the tag type index
+			_, err = r.readByte()
+			if err == nil {
+				imp.TypeIndex, err = r.readU32()
+			}
+		default:
+			return r.errorf("unknown import kind %d", kind)
+		}
+		if err != nil {
+			return err
+		}
+
+		m.Imports = append(m.Imports, imp)
+	}
+	return nil
+}
+
+func (m *Module) parseFunctionSection(r *reader) error {
+	count, err := r.readCount()
+	if err != nil {
+		return err
+	}
+
+	for i := uint32(0); i < count; i++ {
+		typeIndex, err := r.readU32()
+		if err != nil {
+			return err
+		}
+		m.functionTypes = append(m.functionTypes, typeIndex)
+	}
+	return nil
+}
+
+func (m *Module) parseTableSection(r *reader) error {
+	count, err := r.readCount()
+	if err != nil {
+		return err
+	}
+
+	for i := uint32(0); i < count; i++ {
+		table, err := readTableType(r)
+		if err != nil {
+			return err
+		}
+		m.Tables = append(m.Tables, table)
+	}
+	return nil
+}
+
+func (m *Module) parseMemorySection(r *reader) error {
+	count, err := r.readCount()
+	if err != nil {
+		return err
+	}
+
+	for i := uint32(0); i < count; i++ {
+		limits, err := readLimits(r)
+		if err != nil {
+			return err
+		}
+		m.Memories = append(m.Memories, limits)
+	}
+	return nil
+}
+
+func (m *Module) parseGlobalSection(r *reader) error {
+	count, err := r.readCount()
+	if err != nil {
+		return err
+	}
+
+	for i := uint32(0); i < count; i++ {
+		global := Global{Offset: r.offset()}
+
+		global.Type, err = readGlobalType(r)
+		if err != nil {
+			return err
+		}
+
+		global.Init, err = readConstExpr(r)
+		if err != nil {
+			return err
+		}
+
+		m.Globals = append(m.Globals, global)
+	}
+	return nil
+}
+
+func (m *Module) parseExportSection(r *reader) error {
+	count, err := r.readCount()
+	if err != nil {
+		return err
+	}
+
+	for i := uint32(0); i < count; i++ {
+		export := Export{Offset: r.offset()}
+
+		export.Name, err = r.readName()
+		if err != nil {
+			return err
+		}
+
+		kind, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		export.Kind = ExternalKind(kind)
+
+		export.Index, err = r.readU32()
+		if err != nil {
+			return err
+		}
+
+		m.Exports = append(m.Exports, export)
+	}
+	return nil
+}
+
+func (m *Module) parseElementSection(r *reader) error {
+	count, err := r.readCount()
+	if err != nil {
+		return err
+	}
+
+	for i := uint32(0); i < count; i++ {
+		segment := ElementSegment{Offset: r.offset(), ElemType: ValTypeFuncRef}
+
+		segment.Flags, err = r.readU32()
+		if err != nil {
+			return err
+		}
+		if segment.Flags > 7 {
+			return r.errorf("unknown element segment flags %d", segment.Flags)
+		}
+
+		// Bit 0 marks passive or declarative segments, bit 1 an explicit table
+		// index for active ones and bit 2 expressions instead of indices
+		active := segment.Flags&0x01 == 0
+		explicitTable := segment.Flags&0x02 != 0
+		usesExprs := segment.Flags&0x04 != 0
+
+		if active && explicitTable {
+			segment.TableIndex, err = r.readU32()
+			if err != nil {
+				return err
+			}
+		}
+
+		if active {
+			segment.OffsetExpr, err = readConstExpr(r)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !active || explicitTable {
+			// Element kind for function indices, reference type for expressions
+			kind, err := r.readByte()
+			if err != nil {
+				return err
+			}
+			if usesExprs {
+				segment.ElemType = ValType(kind)
+			} else if kind != 0x00 {
+				return r.errorf("unknown element kind %d", kind)
+			}
+		}
+
+		length, err := r.readCount()
+		if err != nil {
+			return err
+		}
+
+		for j := uint32(0); j < length; j++ {
+			if usesExprs {
+				expr, err := readConstExpr(r)
+				if err != nil {
+					return err
+				}
+				segment.Exprs = append(segment.Exprs, expr)
+			} else {
+				index, err := r.readU32()
+				if err != nil {
+					return err
+				}
+				segment.FuncIndices = append(segment.FuncIndices, index)
+			}
+		}
+
+		m.Elements = append(m.Elements, segment)
+	}
+	return nil
+}
+
+func (m *Module) parseCodeSection(r *reader) error {
+	count, err := r.readCount()
+	if err != nil {
+		return err
+	}
+
+	imported := m.NumImportedFuncs()
+	for i := uint32(0); i < count; i++ {
+		size, err := r.readU32()
+		if err != nil {
+			return err
+		}
+
+		offset := r.offset()
+		body, err := r.readBytes(int(size))
+		if err != nil {
+			return err
+		}
+
+		function, err := parseFunctionBody(body, offset)
+		if err != nil {
+			return fmt.Errorf("function %d: %w", imported+i, err)
+		}
+		function.Index = imported + i
+
+		m.Functions = append(m.Functions, function)
+	}
+	return nil
+}
+
+func parseFunctionBody(body []byte, offset int) (Function, error) {
+	r := newReader(body, offset)
+	function := Function{
+		Offset: offset,
+		Size:   len(body),
+	}
+
+	count, err := r.readCount()
+	if err != nil {
+		return function, err
+	}
+
+	for i := uint32(0); i < count; i++ {
+		local := LocalEntry{}
+
+		local.Count, err = r.readU32()
+		if err != nil {
+			return function, err
+		}
+
+		local.Type, err = readValType(r)
+		if err != nil {
+			return function, err
+		}
+
+		function.Locals = append(function.Locals, local)
+	}
+
+	function.Instructions, err = decodeExpr(r)
+	if err != nil {
+		return function, err
+	}
+
+	if !r.eof() {
+		return function, r.errorf("trailing bytes after function body")
+	}
+	return function, nil
+}
+
+func (m *Module) parseDataSection(r *reader) error {
+	count, err := r.readCount()
+	if err != nil {
+		return err
+	}
+
+	for i := uint32(0); i < count; i++ {
+		segment := DataSegment{Offset: r.offset()}
+
+		segment.Flags, err = r.readU32()
+		if err != nil {
+			return err
+		}
+
+		switch segment.Flags {
+		case 0:
+			segment.OffsetExpr, err = readConstExpr(r)
+		case 1:
+			// Passive segment without memory index nor offset
+		case 2:
+			segment.MemoryIndex, err = r.readU32()
+			if err == nil {
+				segment.OffsetExpr, err = readConstExpr(r)
+			}
+		default:
+			return r.errorf("unknown data segment flags %d", segment.Flags)
+		}
+		if err != nil {
+			return err
+		}
+
+		length, err := r.readU32()
+		if err != nil {
+			return err
+		}
+
+		segment.Data, err = r.readBytes(int(length))
+		if err != nil {
+			return err
+		}
+
+		m.DataSegments = append(m.DataSegments, segment)
+	}
+	return nil
+}
+
+func (m *Module) parseNameSection(r *reader) error {
+	for !r.eof() {
+		id, err := r.readByte()
+		if err != nil {
+			return err
+		}
+
+		size, err := r.readU32()
+		if err != nil {
+			return err
+		}
+
+		subsection, err := r.readBytes(int(size))
+		if err != nil {
+			return err
+		}
+
+		// Only the function names subsection is used
+		if id != 1 {
+			continue
+		}
+
+		sr := newReader(subsection, 0)
+		count, err := sr.readCount()
+		if err != nil {
+			return err
+		}
+
+		for i := uint32(0); i < count; i++ {
+			index, err := sr.readU32()
+			if err != nil {
+				return err
+			}
+
+			name, err := sr.readName()
+			if err != nil {
+				return err
+			}
+			m.FunctionNames[index] = name
+		}
+	}
+	return nil
+}
+
+func readValType(r *reader) (ValType, error) {
+	b, err := r.readByte()
+	if err != nil {
+		return 0, err
+	}
+
+	t := ValType(b)
+	if _, ok := valTypeNames[t]; !ok {
+		return 0, r.errorf("unknown value type 0x%02x", b)
+	}
+	return t, nil
+}
+
+func readValTypes(r *reader) ([]ValType, error) {
+	count, err := r.readCount()
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]ValType, 0, count)
+	for i := uint32(0); i < count; i++ {
+		t, err := readValType(r)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+func readLimits(r *reader) (Limits, error) {
+	flags, err := r.readByte()
+	if err != nil {
+		return Limits{}, err
+	}
+	if flags > 0x07 {
+		return Limits{}, r.errorf("unknown limits flags 0x%02x", flags)
+	}
+
+	limits := Limits{
+		HasMax:   flags&0x01 != 0,
+		Shared:   flags&0x02 != 0,
+		Memory64: flags&0x04 != 0,
+	}
+
+	// Only 64-bit memories have 64-bit limits
+	bits := uint(32)
+	if limits.Memory64 {
+		bits = 64
+	}
+
+	limits.Min, err = r.readUnsigned(bits)
+	if err != nil {
+		return limits, err
+	}
+
+	if limits.HasMax {
+		limits.Max, err = r.readUnsigned(bits)
+		if err != nil {
+			return limits, err
+		}
+	}
+	return limits, nil
+}
+
+func readTableType(r *reader) (TableType, error) {
+	elemType, err := readValType(r)
+	if err != nil {
+		return TableType{}, err
+	}
+
+	limits, err := readLimits(r)
+	if err != nil {
+		return TableType{}, err
+	}
+
+	return TableType{ElemType: elemType, Limits: limits}, nil
+}
+
+func readGlobalType(r *reader) (GlobalType, error) {
+	valType, err := readValType(r)
+	if err != nil {
+		return GlobalType{}, err
+	}
+
+	mutable, err := r.readByte()
+	if err != nil {
+		return GlobalType{}, err
+	}
+	if mutable > 1 {
+		return GlobalType{}, r.errorf("invalid global mutability %d", mutable)
+	}
+
+	return GlobalType{ValType: valType, Mutable: mutable == 1}, nil
+}
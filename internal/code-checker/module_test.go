@@ -0,0 +1,106 @@
+package checker
+
+import (
+	"testing"
+
+	"github.com/bytecodealliance/wasmtime-go/v31"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseModule(t *testing.T) {
+	code, err := wasmtime.Wat2Wasm(`
+		(module
+		  (import "env" "abort" (func $abort (param i32 i32 i32 i32)))
+		  (memory (export "memory") 1 2)
+		  (global $counter (mut i32) (i32.const 7))
+		  (table 1 funcref)
+		  (elem (i32.const 0) $add)
+		  (func $add (export "add") (param i32 i32) (result i32)
+		    (local i64)
+		    (if (result i32) (local.get 0)
+		      (then (i32.add (local.get 0) (local.get 1)))
+		      (else (call $helper))))
+		  (func $helper (result i32)
+		    (global.get $counter))
+		  (data (i32.const 8) "data"))
+	`)
+	require.NoError(t, err)
+
+	module, err := ParseModule(code)
+	require.NoError(t, err)
+
+	require.Len(t, module.Imports, 1)
+	require.Equal(t, "env", module.Imports[0].Module)
+	require.Equal(t, "abort", module.Imports[0].Name)
+	require.Equal(t, uint32(1), module.NumImportedFuncs())
+
+	require.Equal(t, []Limits{{Min: 1, Max: 2, HasMax: true}}, module.Memories)
+	require.Len(t, module.Globals, 1)
+	require.True(t, module.Globals[0].Type.Mutable)
+	require.Len(t, module.Tables, 1)
+	require.Equal(t, []uint32{1}, module.Elements[0].FuncIndices)
+	require.Equal(t, []byte("data"), module.DataSegments[0].Data)
+
+	// Defined functions follow the imported ones in the index space
+	require.Len(t, module.Functions, 2)
+	add := module.Functions[0]
+	require.Equal(t, uint32(1), add.Index)
+	require.Equal(t, "add", module.FuncName(add.Index))
+	require.Equal(t, "helper", module.FuncName(2))
+	require.Equal(t, []LocalEntry{{Count: 1, Type: ValTypeI64}}, add.Locals)
+
+	addType, found := module.FuncType(add.Index)
+	require.True(t, found)
+	require.Equal(t, "(i32, i32) -> (i32)", addType.String())
+
+	var opcodes []string
+	for _, instruction := range add.Instructions {
+		opcodes = append(opcodes, instruction.Opcode.String())
+	}
+	require.Equal(t, []string{
+		"local.get", "if", "local.get", "local.get", "i32.add", "else", "call", "end", "end",
+	}, opcodes)
+
+	// Offsets point to the instruction bytes in the binary
+	call := add.Instructions[6]
+	require.Equal(t, []uint32{2}, call.Indices)
+	require.Equal(t, call.Raw, code[call.Offset:call.Offset+len(call.Raw)])
+
+	ifInstruction := add.Instructions[1]
+	valType, ok := ifInstruction.BlockValType()
+	require.True(t, ok)
+	require.Equal(t, ValTypeI32, valType)
+}
+
+func TestDecodePrefixedInstructions(t *testing.T) {
+	code, err := wasmtime.Wat2Wasm(`
+		(module
+		  (memory 1 1 shared)
+		  (func (result i32)
+		    (drop (i8x16.shuffle 0 1 2 3 4 5 6 7 8 9 10 11 12 13 14 15
+		      (v128.load offset=16 (i32.const 0)) (v128.const i64x2 0 0)))
+		    (drop (v128.load8_lane 3 (i32.const 0) (v128.const i64x2 0 0)))
+		    (atomic.fence)
+		    (i32.atomic.rmw8.cmpxchg_u (i32.const 0) (i32.const 1) (i32.const 2))))
+	`)
+	require.NoError(t, err)
+
+	module, err := ParseModule(code)
+	require.NoError(t, err)
+	require.True(t, module.Memories[0].Shared)
+
+	var opcodes []string
+	for _, instruction := range module.Functions[0].Instructions {
+		opcodes = append(opcodes, instruction.Opcode.String())
+	}
+	require.Equal(t, []string{
+		"i32.const", "v128.load", "v128.const", "i8x16.shuffle", "drop",
+		"i32.const", "v128.const", "v128.load8_lane", "drop",
+		"atomic.fence",
+		"i32.const", "i32.const", "i32.const", "i32.atomic.rmw8.cmpxchg_u",
+		"end",
+	}, opcodes)
+
+	load := module.Functions[0].Instructions[1]
+	require.Equal(t, uint64(16), load.MemArg.Offset)
+}
@@ -0,0 +1,30 @@
+package checker
+
+import "fmt"
+
+// checkMultiValue reports every signature with more than one result and every
+// block typed by a signature
+func checkMultiValue(module *Module) []Violation {
+	var violations []Violation
+
+	for i, funcType := range module.Types {
+		if len(funcType.Results) > 1 {
+			violations = append(violations, NewViolation(
+				RuleMultiValue, module, nil, module.sectionOffset(SectionType),
+				fmt.Sprintf("multiple results in signature of type %d %s", i, funcType),
+			))
+		}
+	}
+
+	module.WalkInstructions(func(function *Function, instruction Instruction) {
+		switch instruction.Opcode {
+		case OpBlock, OpLoop, OpIf, OpTry:
+			// Blocks typed by a signature may have parameters and several results
+			if instruction.BlockType >= 0 {
+				violations = append(violations, NewInstructionViolation(RuleMultiValue, module, function, instruction))
+			}
+		}
+	})
+
+	return violations
+}
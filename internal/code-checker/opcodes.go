@@ -0,0 +1,357 @@
+package checker
+
+import "fmt"
+
+// Opcode identifies an instruction, prefixed instructions keep the prefix byte
+// in the upper bits and the LEB128 encoded sub opcode in the lower bits.
+type Opcode uint32
+
+const (
+	PrefixMisc    byte = 0xFC
+	PrefixSIMD    byte = 0xFD
+	PrefixAtomics byte = 0xFE
+)
+
+func newPrefixedOpcode(prefix byte, code uint32) Opcode {
+	return Opcode(prefix)<<16 | Opcode(code)
+}
+
+const (
+	OpUnreachable        Opcode = 0x00
+	OpNop                Opcode = 0x01
+	OpBlock              Opcode = 0x02
+	OpLoop               Opcode = 0x03
+	OpIf                 Opcode = 0x04
+	OpElse               Opcode = 0x05
+	OpTry                Opcode = 0x06
+	OpCatch              Opcode = 0x07
+	OpThrow              Opcode = 0x08
+	OpRethrow            Opcode = 0x09
+	OpThrowRef           Opcode = 0x0A
+	OpEnd                Opcode = 0x0B
+	OpBr                 Opcode = 0x0C
+	OpBrIf               Opcode = 0x0D
+	OpBrTable            Opcode = 0x0E
+	OpReturn             Opcode = 0x0F
+	OpCall               Opcode = 0x10
+	OpCallIndirect       Opcode = 0x11
+	OpReturnCall         Opcode = 0x12
+	OpReturnCallIndirect Opcode = 0x13
+	OpCallRef            Opcode = 0x14
+	OpReturnCallRef      Opcode = 0x15
+	OpDelegate           Opcode = 0x18
+	OpCatchAll           Opcode = 0x19
+	OpDrop               Opcode = 0x1A
+	OpSelect             Opcode = 0x1B
+	OpSelectTyped        Opcode = 0x1C
+	OpLocalGet           Opcode = 0x20
+	OpLocalSet           Opcode = 0x21
+	OpLocalTee           Opcode = 0x22
+	OpGlobalGet          Opcode = 0x23
+	OpGlobalSet          Opcode = 0x24
+	OpTableGet           Opcode = 0x25
+	OpTableSet           Opcode = 0x26
+	OpMemorySize         Opcode = 0x3F
+	OpMemoryGrow         Opcode = 0x40
+	OpI32Const           Opcode = 0x41
+	OpI64Const           Opcode = 0x42
+	OpF32Const           Opcode = 0x43
+	OpF64Const           Opcode = 0x44
+	OpRefNull            Opcode = 0xD0
+	OpRefIsNull          Opcode = 0xD1
+	OpRefFunc            Opcode = 0xD2
+	OpRefAsNonNull       Opcode = 0xD3
+	OpBrOnNull           Opcode = 0xD4
+	OpBrOnNonNull        Opcode = 0xD6
+)
+
+// Prefix returns the prefix byte of the opcode, or 0 for single byte opcodes
+func (o Opcode) Prefix() byte {
+	return byte(o >> 16)
+}
+
+// Code returns the opcode without its prefix
+func (o Opcode) Code() uint32 {
+	return uint32(o & 0xFFFF)
+}
+
+func (o Opcode) String() string {
+	var name string
+	switch o.Prefix() {
+	case 0:
+		name = opcodeNames[o.Code()]
+	case PrefixMisc:
+		name = miscOpcodeNames[o.Code()]
+	case PrefixSIMD:
+		name = simdOpcodeNames[o.Code()]
+	case PrefixAtomics:
+		name = atomicOpcodeNames[o.Code()]
+	}
+
+	if name != "" {
+		return name
+	}
+	if o.Prefix() != 0 {
+		return fmt.Sprintf("0x%02x 0x%02x", o.Prefix(), o.Code())
+	}
+	return fmt.Sprintf("0x%02x", o.Code())
+}
+
+// immediateKind describes the immediates following an opcode in the binary
+type immediateKind int
+
+const (
+	immNone immediateKind = iota
+	immBlockType
+	immIndex       // a single u32 index: function, type, local, global, table, label...
+	immTwoIndices  // two u32 indices, e.g. call_indirect type and table
+	immBrTable     // a vector of labels followed by the default label
+	immSelectTypes // a vector of value types
+	immMemArg      // alignment, optional memory index, offset
+	immMemArgLane  // a memory argument followed by a lane index
+	immI32
+	immI64
+	immF32
+	immF64
+	immHeapType
+	immLane
+	immBytes16 // v128.const and i8x16.shuffle
+	immZeroByte
+)
+
+// singleByteImmediates lists the single byte opcodes carrying immediates, the
+// remaining valid opcodes are listed in opcodeNames
+var singleByteImmediates = map[uint32]immediateKind{
+	0x02: immBlockType,
+	0x03: immBlockType,
+	0x04: immBlockType,
+	0x06: immBlockType,
+	0x07: immIndex,
+	0x08: immIndex,
+	0x09: immIndex,
+	0x0C: immIndex,
+	0x0D: immIndex,
+	0x0E: immBrTable,
+	0x10: immIndex,
+	0x11: immTwoIndices,
+	0x12: immIndex,
+	0x13: immTwoIndices,
+	0x14: immIndex,
+	0x15: immIndex,
+	0x18: immIndex,
+	0x1C: immSelectTypes,
+	0x20: immIndex,
+	0x21: immIndex,
+	0x22: immIndex,
+	0x23: immIndex,
+	0x24: immIndex,
+	0x25: immIndex,
+	0x26: immIndex,
+	0x3F: immIndex,
+	0x40: immIndex,
+	0x41: immI32,
+	0x42: immI64,
+	0x43: immF32,
+	0x44: immF64,
+	0xD0: immHeapType,
+	0xD2: immIndex,
+	0xD4: immIndex,
+	0xD6: immIndex,
+}
+
+var opcodeNames = map[uint32]string{
+	0x00: "unreachable", 0x01: "nop", 0x02: "block", 0x03: "loop", 0x04: "if", 0x05: "else",
+	0x06: "try", 0x07: "catch", 0x08: "throw", 0x09: "rethrow", 0x0A: "throw_ref", 0x0B: "end",
+	0x0C: "br", 0x0D: "br_if", 0x0E: "br_table", 0x0F: "return", 0x10: "call", 0x11: "call_indirect",
+	0x12: "return_call", 0x13: "return_call_indirect", 0x14: "call_ref", 0x15: "return_call_ref",
+	0x18: "delegate", 0x19: "catch_all", 0x1A: "drop", 0x1B: "select", 0x1C: "select",
+	0x20: "local.get", 0x21: "local.set", 0x22: "local.tee", 0x23: "global.get", 0x24: "global.set",
+	0x25: "table.get", 0x26: "table.set",
+	0x28: "i32.load", 0x29: "i64.load", 0x2A: "f32.load", 0x2B: "f64.load",
+	0x2C: "i32.load8_s", 0x2D: "i32.load8_u", 0x2E: "i32.load16_s", 0x2F: "i32.load16_u",
+	0x30: "i64.load8_s", 0x31: "i64.load8_u", 0x32: "i64.load16_s", 0x33: "i64.load16_u",
+	0x34: "i64.load32_s", 0x35: "i64.load32_u", 0x36: "i32.store", 0x37: "i64.store",
+	0x38: "f32.store", 0x39: "f64.store", 0x3A: "i32.store8", 0x3B: "i32.store16",
+	0x3C: "i64.store8", 0x3D: "i64.store16", 0x3E: "i64.store32",
+	0x3F: "memory.size", 0x40: "memory.grow",
+	0x41: "i32.const", 0x42: "i64.const", 0x43: "f32.const", 0x44: "f64.const",
+	0x45: "i32.eqz", 0x46: "i32.eq", 0x47: "i32.ne", 0x48: "i32.lt_s", 0x49: "i32.lt_u",
+	0x4A: "i32.gt_s", 0x4B: "i32.gt_u", 0x4C: "i32.le_s", 0x4D: "i32.le_u", 0x4E: "i32.ge_s", 0x4F: "i32.ge_u",
+	0x50: "i64.eqz", 0x51: "i64.eq", 0x52: "i64.ne", 0x53: "i64.lt_s", 0x54: "i64.lt_u",
+	0x55: "i64.gt_s", 0x56: "i64.gt_u", 0x57: "i64.le_s", 0x58: "i64.le_u", 0x59: "i64.ge_s", 0x5A: "i64.ge_u",
+	0x5B: "f32.eq", 0x5C: "f32.ne", 0x5D: "f32.lt", 0x5E: "f32.gt", 0x5F: "f32.le", 0x60: "f32.ge",
+	0x61: "f64.eq", 0x62: "f64.ne", 0x63: "f64.lt", 0x64: "f64.gt", 0x65: "f64.le", 0x66: "f64.ge",
+	0x67: "i32.clz", 0x68: "i32.ctz", 0x69: "i32.popcnt", 0x6A: "i32.add", 0x6B: "i32.sub",
+	0x6C: "i32.mul", 0x6D: "i32.div_s", 0x6E: "i32.div_u", 0x6F: "i32.rem_s", 0x70: "i32.rem_u",
+	0x71: "i32.and", 0x72: "i32.or", 0x73: "i32.xor", 0x74: "i32.shl", 0x75: "i32.shr_s",
+	0x76: "i32.shr_u", 0x77: "i32.rotl", 0x78: "i32.rotr",
+	0x79: "i64.clz", 0x7A: "i64.ctz", 0x7B: "i64.popcnt", 0x7C: "i64.add", 0x7D: "i64.sub",
+	0x7E: "i64.mul", 0x7F: "i64.div_s", 0x80: "i64.div_u", 0x81: "i64.rem_s", 0x82: "i64.rem_u",
+	0x83: "i64.and", 0x84: "i64.or", 0x85: "i64.xor", 0x86: "i64.shl", 0x87: "i64.shr_s",
+	0x88: "i64.shr_u", 0x89: "i64.rotl", 0x8A: "i64.rotr",
+	0x8B: "f32.abs", 0x8C: "f32.neg", 0x8D: "f32.ceil", 0x8E: "f32.floor", 0x8F: "f32.trunc",
+	0x90: "f32.nearest", 0x91: "f32.sqrt", 0x92: "f32.add", 0x93: "f32.sub", 0x94: "f32.mul",
+	0x95: "f32.div", 0x96: "f32.min", 0x97: "f32.max", 0x98: "f32.copysign",
+	0x99: "f64.abs", 0x9A: "f64.neg", 0x9B: "f64.ceil", 0x9C: "f64.floor", 0x9D: "f64.trunc",
+	0x9E: "f64.nearest", 0x9F: "f64.sqrt", 0xA0: "f64.add", 0xA1: "f64.sub", 0xA2: "f64.mul",
+	0xA3: "f64.div", 0xA4: "f64.min", 0xA5: "f64.max", 0xA6: "f64.copysign",
+	0xA7: "i32.wrap_i64", 0xA8: "i32.trunc_f32_s", 0xA9: "i32.trunc_f32_u", 0xAA: "i32.trunc_f64_s",
+	0xAB: "i32.trunc_f64_u", 0xAC: "i64.extend_i32_s", 0xAD: "i64.extend_i32_u", 0xAE: "i64.trunc_f32_s",
+	0xAF: "i64.trunc_f32_u", 0xB0: "i64.trunc_f64_s", 0xB1: "i64.trunc_f64_u",
+	0xB2: "f32.convert_i32_s", 0xB3: "f32.convert_i32_u", 0xB4: "f32.convert_i64_s",
+	0xB5: "f32.convert_i64_u", 0xB6: "f32.demote_f64", 0xB7: "f64.convert_i32_s",
+	0xB8: "f64.convert_i32_u", 0xB9: "f64.convert_i64_s", 0xBA: "f64.convert_i64_u",
+	0xBB: "f64.promote_f32", 0xBC: "i32.reinterpret_f32", 0xBD: "i64.reinterpret_f64",
+	0xBE: "f32.reinterpret_i32", 0xBF: "f64.reinterpret_i64",
+	0xC0: "i32.extend8_s", 0xC1: "i32.extend16_s", 0xC2: "i64.extend8_s", 0xC3: "i64.extend16_s",
+	0xC4: "i64.extend32_s",
+	0xD0: "ref.null", 0xD1: "ref.is_null", 0xD2: "ref.func", 0xD3: "ref.as_non_null",
+	0xD4: "br_on_null", 0xD6: "br_on_non_null",
+}
+
+var miscImmediates = map[uint32]immediateKind{
+	0x08: immTwoIndices, // memory.init
+	0x09: immIndex,      // data.drop
+	0x0A: immTwoIndices, // memory.copy
+	0x0B: immIndex,      // memory.fill
+	0x0C: immTwoIndices, // table.init
+	0x0D: immIndex,      // elem.drop
+	0x0E: immTwoIndices, // table.copy
+	0x0F: immIndex,      // table.grow
+	0x10: immIndex,      // table.size
+	0x11: immIndex,      // table.fill
+}
+
+var miscOpcodeNames = map[uint32]string{
+	0x00: "i32.trunc_sat_f32_s", 0x01: "i32.trunc_sat_f32_u", 0x02: "i32.trunc_sat_f64_s",
+	0x03: "i32.trunc_sat_f64_u", 0x04: "i64.trunc_sat_f32_s", 0x05: "i64.trunc_sat_f32_u",
+	0x06: "i64.trunc_sat_f64_s", 0x07: "i64.trunc_sat_f64_u",
+	0x08: "memory.init", 0x09: "data.drop", 0x0A: "memory.copy", 0x0B: "memory.fill",
+	0x0C: "table.init", 0x0D: "elem.drop", 0x0E: "table.copy", 0x0F: "table.grow",
+	0x10: "table.size", 0x11: "table.fill",
+}
+
+func simdImmediate(code uint32) immediateKind {
+	switch {
+	case code <= 0x0B, code == 0x5C, code == 0x5D:
+		return immMemArg
+	case code == 0x0C, code == 0x0D:
+		return immBytes16
+	case code >= 0x15 && code <= 0x22:
+		return immLane
+	case code >= 0x54 && code <= 0x5B:
+		return immMemArgLane
+	}
+	return immNone
+}
+
+var simdOpcodeNames = map[uint32]string{
+	0x00: "v128.load", 0x01: "v128.load8x8_s", 0x02: "v128.load8x8_u", 0x03: "v128.load16x4_s",
+	0x04: "v128.load16x4_u", 0x05: "v128.load32x2_s", 0x06: "v128.load32x2_u", 0x07: "v128.load8_splat",
+	0x08: "v128.load16_splat", 0x09: "v128.load32_splat", 0x0A: "v128.load64_splat", 0x0B: "v128.store",
+	0x0C: "v128.const", 0x0D: "i8x16.shuffle", 0x0E: "i8x16.swizzle", 0x0F: "i8x16.splat",
+	0x10: "i16x8.splat", 0x11: "i32x4.splat", 0x12: "i64x2.splat", 0x13: "f32x4.splat", 0x14: "f64x2.splat",
+	0x15: "i8x16.extract_lane_s", 0x16: "i8x16.extract_lane_u", 0x17: "i8x16.replace_lane",
+	0x18: "i16x8.extract_lane_s", 0x19: "i16x8.extract_lane_u", 0x1A: "i16x8.replace_lane",
+	0x1B: "i32x4.extract_lane", 0x1C: "i32x4.replace_lane", 0x1D: "i64x2.extract_lane",
+	0x1E: "i64x2.replace_lane", 0x1F: "f32x4.extract_lane", 0x20: "f32x4.replace_lane",
+	0x21: "f64x2.extract_lane", 0x22: "f64x2.replace_lane",
+	0x23: "i8x16.eq", 0x24: "i8x16.ne", 0x25: "i8x16.lt_s", 0x26: "i8x16.lt_u", 0x27: "i8x16.gt_s",
+	0x28: "i8x16.gt_u", 0x29: "i8x16.le_s", 0x2A: "i8x16.le_u", 0x2B: "i8x16.ge_s", 0x2C: "i8x16.ge_u",
+	0x2D: "i16x8.eq", 0x2E: "i16x8.ne", 0x2F: "i16x8.lt_s", 0x30: "i16x8.lt_u", 0x31: "i16x8.gt_s",
+	0x32: "i16x8.gt_u", 0x33: "i16x8.le_s", 0x34: "i16x8.le_u", 0x35: "i16x8.ge_s", 0x36: "i16x8.ge_u",
+	0x37: "i32x4.eq", 0x38: "i32x4.ne", 0x39: "i32x4.lt_s", 0x3A: "i32x4.lt_u", 0x3B: "i32x4.gt_s",
+	0x3C: "i32x4.gt_u", 0x3D: "i32x4.le_s", 0x3E: "i32x4.le_u", 0x3F: "i32x4.ge_s", 0x40: "i32x4.ge_u",
+	0x41: "f32x4.eq", 0x42: "f32x4.ne", 0x43: "f32x4.lt", 0x44: "f32x4.gt", 0x45: "f32x4.le", 0x46: "f32x4.ge",
+	0x47: "f64x2.eq", 0x48: "f64x2.ne", 0x49: "f64x2.lt", 0x4A: "f64x2.gt", 0x4B: "f64x2.le", 0x4C: "f64x2.ge",
+	0x4D: "v128.not", 0x4E: "v128.and", 0x4F: "v128.andnot", 0x50: "v128.or", 0x51: "v128.xor",
+	0x52: "v128.bitselect", 0x53: "v128.any_true",
+	0x54: "v128.load8_lane", 0x55: "v128.load16_lane", 0x56: "v128.load32_lane", 0x57: "v128.load64_lane",
+	0x58: "v128.store8_lane", 0x59: "v128.store16_lane", 0x5A: "v128.store32_lane", 0x5B: "v128.store64_lane",
+	0x5C: "v128.load32_zero", 0x5D: "v128.load64_zero",
+	0x5E: "f32x4.demote_f64x2_zero", 0x5F: "f64x2.promote_low_f32x4",
+	0x60: "i8x16.abs", 0x61: "i8x16.neg", 0x62: "i8x16.popcnt", 0x63: "i8x16.all_true", 0x64: "i8x16.bitmask",
+	0x65: "i8x16.narrow_i16x8_s", 0x66: "i8x16.narrow_i16x8_u",
+	0x67: "f32x4.ceil", 0x68: "f32x4.floor", 0x69: "f32x4.trunc", 0x6A: "f32x4.nearest",
+	0x6B: "i8x16.shl", 0x6C: "i8x16.shr_s", 0x6D: "i8x16.shr_u", 0x6E: "i8x16.add", 0x6F: "i8x16.add_sat_s",
+	0x70: "i8x16.add_sat_u", 0x71: "i8x16.sub", 0x72: "i8x16.sub_sat_s", 0x73: "i8x16.sub_sat_u",
+	0x74: "f64x2.ceil", 0x75: "f64x2.floor", 0x76: "i8x16.min_s", 0x77: "i8x16.min_u", 0x78: "i8x16.max_s",
+	0x79: "i8x16.max_u", 0x7A: "f64x2.trunc", 0x7B: "i8x16.avgr_u",
+	0x7C: "i16x8.extadd_pairwise_i8x16_s", 0x7D: "i16x8.extadd_pairwise_i8x16_u",
+	0x7E: "i32x4.extadd_pairwise_i16x8_s", 0x7F: "i32x4.extadd_pairwise_i16x8_u",
+	0x80: "i16x8.abs", 0x81: "i16x8.neg", 0x82: "i16x8.q15mulr_sat_s", 0x83: "i16x8.all_true",
+	0x84: "i16x8.bitmask", 0x85: "i16x8.narrow_i32x4_s", 0x86: "i16x8.narrow_i32x4_u",
+	0x87: "i16x8.extend_low_i8x16_s", 0x88: "i16x8.extend_high_i8x16_s",
+	0x89: "i16x8.extend_low_i8x16_u", 0x8A: "i16x8.extend_high_i8x16_u",
+	0x8B: "i16x8.shl", 0x8C: "i16x8.shr_s", 0x8D: "i16x8.shr_u", 0x8E: "i16x8.add", 0x8F: "i16x8.add_sat_s",
+	0x90: "i16x8.add_sat_u", 0x91: "i16x8.sub", 0x92: "i16x8.sub_sat_s", 0x93: "i16x8.sub_sat_u",
+	0x94: "f64x2.nearest", 0x95: "i16x8.mul", 0x96: "i16x8.min_s", 0x97: "i16x8.min_u",
+	0x98: "i16x8.max_s", 0x99: "i16x8.max_u", 0x9B: "i16x8.avgr_u",
+	0x9C: "i16x8.extmul_low_i8x16_s", 0x9D: "i16x8.extmul_high_i8x16_s",
+	0x9E: "i16x8.extmul_low_i8x16_u", 0x9F: "i16x8.extmul_high_i8x16_u",
+	0xA0: "i32x4.abs", 0xA1: "i32x4.neg", 0xA3: "i32x4.all_true", 0xA4: "i32x4.bitmask",
+	0xA7: "i32x4.extend_low_i16x8_s", 0xA8: "i32x4.extend_high_i16x8_s",
+	0xA9: "i32x4.extend_low_i16x8_u", 0xAA: "i32x4.extend_high_i16x8_u",
+	0xAB: "i32x4.shl", 0xAC: "i32x4.shr_s", 0xAD: "i32x4.shr_u", 0xAE: "i32x4.add", 0xB1: "i32x4.sub",
+	0xB5: "i32x4.mul", 0xB6: "i32x4.min_s", 0xB7: "i32x4.min_u", 0xB8: "i32x4.max_s", 0xB9: "i32x4.max_u",
+	0xBA: "i32x4.dot_i16x8_s",
+	0xBC: "i32x4.extmul_low_i16x8_s", 0xBD: "i32x4.extmul_high_i16x8_s",
+	0xBE: "i32x4.extmul_low_i16x8_u", 0xBF: "i32x4.extmul_high_i16x8_u",
+	0xC0: "i64x2.abs", 0xC1: "i64x2.neg", 0xC3: "i64x2.all_true", 0xC4: "i64x2.bitmask",
+	0xC7: "i64x2.extend_low_i32x4_s", 0xC8: "i64x2.extend_high_i32x4_s",
+	0xC9: "i64x2.extend_low_i32x4_u", 0xCA: "i64x2.extend_high_i32x4_u",
+	0xCB: "i64x2.shl", 0xCC: "i64x2.shr_s", 0xCD: "i64x2.shr_u", 0xCE: "i64x2.add", 0xD1: "i64x2.sub",
+	0xD5: "i64x2.mul", 0xD6: "i64x2.eq", 0xD7: "i64x2.ne", 0xD8: "i64x2.lt_s", 0xD9: "i64x2.gt_s",
+	0xDA: "i64x2.le_s", 0xDB: "i64x2.ge_s",
+	0xDC: "i64x2.extmul_low_i32x4_s", 0xDD: "i64x2.extmul_high_i32x4_s",
+	0xDE: "i64x2.extmul_low_i32x4_u", 0xDF: "i64x2.extmul_high_i32x4_u",
+	0xE0: "f32x4.abs", 0xE1: "f32x4.neg", 0xE3: "f32x4.sqrt", 0xE4: "f32x4.add", 0xE5: "f32x4.sub",
+	0xE6: "f32x4.mul", 0xE7: "f32x4.div", 0xE8: "f32x4.min", 0xE9: "f32x4.max", 0xEA: "f32x4.pmin",
+	0xEB: "f32x4.pmax",
+	0xEC: "f64x2.abs", 0xED: "f64x2.neg", 0xEF: "f64x2.sqrt", 0xF0: "f64x2.add", 0xF1: "f64x2.sub",
+	0xF2: "f64x2.mul", 0xF3: "f64x2.div", 0xF4: "f64x2.min", 0xF5: "f64x2.max", 0xF6: "f64x2.pmin",
+	0xF7: "f64x2.pmax",
+	0xF8: "i32x4.trunc_sat_f32x4_s", 0xF9: "i32x4.trunc_sat_f32x4_u",
+	0xFA: "f32x4.convert_i32x4_s", 0xFB: "f32x4.convert_i32x4_u",
+	0xFC: "i32x4.trunc_sat_f64x2_s_zero", 0xFD: "i32x4.trunc_sat_f64x2_u_zero",
+	0xFE: "f64x2.convert_low_i32x4_s", 0xFF: "f64x2.convert_low_i32x4_u",
+	0x100: "i8x16.relaxed_swizzle", 0x101: "i32x4.relaxed_trunc_f32x4_s", 0x102: "i32x4.relaxed_trunc_f32x4_u",
+	0x103: "i32x4.relaxed_trunc_f64x2_s_zero", 0x104: "i32x4.relaxed_trunc_f64x2_u_zero",
+	0x105: "f32x4.relaxed_madd", 0x106: "f32x4.relaxed_nmadd", 0x107: "f64x2.relaxed_madd",
+	0x108: "f64x2.relaxed_nmadd", 0x109: "i8x16.relaxed_laneselect", 0x10A: "i16x8.relaxed_laneselect",
+	0x10B: "i32x4.relaxed_laneselect", 0x10C: "i64x2.relaxed_laneselect",
+	0x10D: "f32x4.relaxed_min", 0x10E: "f32x4.relaxed_max", 0x10F: "f64x2.relaxed_min",
+	0x110: "f64x2.relaxed_max", 0x111: "i16x8.relaxed_q15mulr_s",
+	0x112: "i16x8.relaxed_dot_i8x16_i7x16_s", 0x113: "i32x4.relaxed_dot_i8x16_i7x16_add_s",
+}
+
+func atomicImmediate(code uint32) immediateKind {
+	if code == 0x03 {
+		return immZeroByte
+	}
+	return immMemArg
+}
+
+var atomicOpcodeNames = newAtomicOpcodeNames()
+
+func newAtomicOpcodeNames() map[uint32]string {
+	names := map[uint32]string{
+		0x00: "memory.atomic.notify", 0x01: "memory.atomic.wait32", 0x02: "memory.atomic.wait64",
+		0x03: "atomic.fence",
+		0x10: "i32.atomic.load", 0x11: "i64.atomic.load", 0x12: "i32.atomic.load8_u",
+		0x13: "i32.atomic.load16_u", 0x14: "i64.atomic.load8_u", 0x15: "i64.atomic.load16_u",
+		0x16: "i64.atomic.load32_u", 0x17: "i32.atomic.store", 0x18: "i64.atomic.store",
+		0x19: "i32.atomic.store8", 0x1A: "i32.atomic.store16", 0x1B: "i64.atomic.store8",
+		0x1C: "i64.atomic.store16", 0x1D: "i64.atomic.store32",
+	}
+
+	// Read-modify-write operations come in groups of seven widths
+	widths := []string{"i32.atomic.rmw.%s", "i64.atomic.rmw.%s", "i32.atomic.rmw8.%s_u", "i32.atomic.rmw16.%s_u",
+		"i64.atomic.rmw8.%s_u", "i64.atomic.rmw16.%s_u", "i64.atomic.rmw32.%s_u"}
+	for i, op := range []string{"add", "sub", "and", "or", "xor", "xchg", "cmpxchg"} {
+		for j, width := range widths {
+			names[uint32(0x1E+i*len(widths)+j)] = fmt.Sprintf(width, op)
+		}
+	}
+	return names
+}
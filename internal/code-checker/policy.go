@@ -0,0 +1,117 @@
+package checker
+
+import "fmt"
+
+const (
+	ProfileStrict  = "strict"
+	ProfileDefault = "default"
+	ProfileFloats  = "floats"
+	ProfileDev     = "dev"
+)
+
+// Policy is a named set of rules a module must pass to be deployed
+type Policy struct {
+	Name  string
+	Rules []Rule
+}
+
+func NewPolicy(name string, rules ...Rule) *Policy {
+	return &Policy{
+		Name:  name,
+		Rules: rules,
+	}
+}
+
+// With returns a copy of the policy extended with the given rules
+func (p *Policy) With(rules ...Rule) *Policy {
+	extended := make([]Rule, 0, len(p.Rules)+len(rules))
+	extended = append(extended, p.Rules...)
+	extended = append(extended, rules...)
+	return NewPolicy(p.Name, extended...)
+}
+
+// Check reports the violations of every rule of the policy, in rule order
+func (p *Policy) Check(module *Module) *CheckReport {
+	report := &CheckReport{}
+	for _, rule := range p.Rules {
+		report.add(rule.Check(module)...)
+	}
+	return report
+}
+
+// CheckCode decodes the module and checks it against the policy
+func (p *Policy) CheckCode(wasmCode []byte) (*CheckReport, error) {
+	module, err := ParseModule(wasmCode)
+	if err != nil {
+		return nil, err
+	}
+	return p.Check(module), nil
+}
+
+// StrictPolicy only allows the WASM 1.0 features besides floats, plus the
+// sign extension instructions. The saturating conversions operate on floats,
+// so they are rejected like the other floating point instructions.
+func StrictPolicy() *Policy {
+	return NewPolicy(
+		ProfileStrict,
+		FloatingPointRule(),
+		SIMDRule(),
+		ThreadsRule(),
+		BulkMemoryRule(),
+		ReferenceTypesRule(),
+		MultiValueRule(),
+		Memory64Rule(),
+		TailCallsRule(),
+	)
+}
+
+// DefaultPolicy rejects the undeterministic features and those the runtime
+// does not support, it accepts the output of the AssemblyScript compiler
+func DefaultPolicy() *Policy {
+	return NewPolicy(
+		ProfileDefault,
+		FloatingPointRule(),
+		SIMDRule(),
+		ThreadsRule(),
+		Memory64Rule(),
+		TailCallsRule(),
+	)
+}
+
+// FloatsPolicy is the default policy allowing floating point operations, the
+// code must be executed by engines canonicalizing NaN values, such as those
+// created by runtime.NewEngine, for the results to be deterministic
+func FloatsPolicy() *Policy {
+	return NewPolicy(
+		ProfileFloats,
+		SIMDRule(),
+		ThreadsRule(),
+		Memory64Rule(),
+		TailCallsRule(),
+	)
+}
+
+// DevPolicy only rejects the features the runtime can not execute, it is meant
+// for local development and must not be used by validators
+func DevPolicy() *Policy {
+	return NewPolicy(
+		ProfileDev,
+		ThreadsRule(),
+		Memory64Rule(),
+	)
+}
+
+// Profile returns the policy of the named profile
+func Profile(name string) (*Policy, error) {
+	switch name {
+	case ProfileStrict:
+		return StrictPolicy(), nil
+	case ProfileDefault:
+		return DefaultPolicy(), nil
+	case ProfileFloats:
+		return FloatsPolicy(), nil
+	case ProfileDev:
+		return DevPolicy(), nil
+	}
+	return nil, fmt.Errorf("unknown code check profile %s", name)
+}
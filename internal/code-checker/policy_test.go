@@ -0,0 +1,164 @@
+package checker
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/bytecodealliance/wasmtime-go/v31"
+	"github.com/stretchr/testify/require"
+)
+
+func parseWat(t *testing.T, wat string) *Module {
+	code, err := wasmtime.Wat2Wasm(wat)
+	require.NoError(t, err)
+
+	module, err := ParseModule(code)
+	require.NoError(t, err)
+	return module
+}
+
+func TestFeatureRules(t *testing.T) {
+	testCases := []struct {
+		rule     Rule
+		accepted string
+		rejected string
+		expected []string
+	}{
+		{
+			rule:     BulkMemoryRule(),
+			accepted: `(module (memory 1) (data (i32.const 0) "data"))`,
+			rejected: `(module
+			  (memory 1)
+			  (data $d "data")
+			  (func
+			    (memory.init $d (i32.const 0) (i32.const 0) (i32.const 4))
+			    (memory.copy (i32.const 8) (i32.const 0) (i32.const 4))))`,
+			expected: []string{"passive data segment 0", "instruction memory.init", "instruction memory.copy"},
+		},
+		{
+			rule:     ReferenceTypesRule(),
+			accepted: `(module (table 1 funcref) (elem (i32.const 0) $f) (func $f))`,
+			rejected: `(module
+			  (table 1 funcref)
+			  (table 1 externref)
+			  (func (param externref) (result i32)
+			    (ref.is_null (local.get 0))))`,
+			expected: []string{"reference in signature of type 0 (externref) -> (i32)", "2 tables", "instruction ref.is_null"},
+		},
+		{
+			rule:     MultiValueRule(),
+			accepted: `(module (func (result i32) (block (result i32) (i32.const 1))))`,
+			rejected: `(module
+			  (func (result i32 i32)
+			    (block (result i32 i32) (i32.const 1) (i32.const 2))))`,
+			expected: []string{"multiple results in signature of type 0 () -> (i32, i32)", "instruction block"},
+		},
+		{
+			rule:     Memory64Rule(),
+			accepted: `(module (memory 1))`,
+			rejected: `(module (memory i64 1))`,
+			expected: []string{"64-bit memory 0"},
+		},
+		{
+			rule:     TailCallsRule(),
+			accepted: `(module (func $f (call $f)))`,
+			rejected: `(module (func $f (return_call $f)))`,
+			expected: []string{"instruction return_call"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.rule.Name(), func(t *testing.T) {
+			require.Empty(t, tc.rule.Check(parseWat(t, tc.accepted)))
+
+			violations := tc.rule.Check(parseWat(t, tc.rejected))
+			require.Len(t, violations, len(tc.expected))
+			for i, expected := range tc.expected {
+				require.Equal(t, tc.rule.Name(), violations[i].Rule)
+				require.Equal(t, expected, violations[i].Message)
+			}
+		})
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	code, err := os.ReadFile("testdata/accept/assemblyscript.wasm")
+	require.NoError(t, err)
+
+	module, err := ParseModule(code)
+	require.NoError(t, err)
+
+	// AssemblyScript output uses memory.fill from the bulk memory proposal
+	strict, err := Profile(ProfileStrict)
+	require.NoError(t, err)
+	report := strict.Check(module)
+	require.False(t, report.Passed())
+	for _, violation := range report.Violations {
+		require.Equal(t, RuleBulkMemory, violation.Rule)
+	}
+
+	// Saturating conversions are floating point instructions
+	truncSat := parseWat(t, `(module (func (result i32) (i32.trunc_sat_f32_s (f32.const 1))))`)
+	var messages []string
+	for _, violation := range strict.Check(truncSat).Violations {
+		require.Equal(t, RuleFloatingPoint, violation.Rule)
+		messages = append(messages, violation.Message)
+	}
+	require.Contains(t, messages, "instruction i32.trunc_sat_f32_s")
+
+	defaultPolicy, err := Profile(ProfileDefault)
+	require.NoError(t, err)
+	require.True(t, defaultPolicy.Check(module).Passed())
+
+	// Floats are only allowed by the dev profile
+	floats := parseWat(t, `(module (func (result f32) (f32.add (f32.const 1) (f32.const 2))))`)
+	require.False(t, defaultPolicy.Check(floats).Passed())
+
+	dev, err := Profile(ProfileDev)
+	require.NoError(t, err)
+	require.True(t, dev.Check(floats).Passed())
+
+	_, err = Profile("unknown")
+	require.Error(t, err)
+}
+
+func TestCustomRule(t *testing.T) {
+	// Reject modules exporting more than one function
+	maxExports := NewRule("max_exports", func(module *Module) []Violation {
+		if len(module.Exports) <= 1 {
+			return nil
+		}
+		return []Violation{NewViolation(
+			"max_exports", module, nil, module.Exports[1].Offset,
+			fmt.Sprintf("%d exports", len(module.Exports)),
+		)}
+	})
+
+	// Reject calls to the first function
+	noRecursion := NewRule("no_call_0", func(module *Module) []Violation {
+		var violations []Violation
+		module.WalkInstructions(func(function *Function, instruction Instruction) {
+			if instruction.Opcode == OpCall && instruction.Indices[0] == 0 {
+				violations = append(violations, NewInstructionViolation("no_call_0", module, function, instruction))
+			}
+		})
+		return violations
+	})
+
+	policy := DefaultPolicy().With(maxExports, noRecursion)
+	require.Equal(t, ProfileDefault, policy.Name)
+	require.Len(t, policy.Rules, len(DefaultPolicy().Rules)+2)
+
+	module := parseWat(t, `
+		(module
+		  (func $a (export "a") (call $a))
+		  (func (export "b")))
+	`)
+
+	report := policy.Check(module)
+	require.Len(t, report.Violations, 2)
+	require.Equal(t, "max_exports: 2 exports at offset 0x"+fmt.Sprintf("%x", module.Exports[1].Offset), report.Violations[0].String())
+	require.Equal(t, "no_call_0", report.Violations[1].Rule)
+	require.Equal(t, "a", report.Violations[1].FunctionName)
+}
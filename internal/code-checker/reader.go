@@ -0,0 +1,157 @@
+package checker
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+var errUnexpectedEnd = errors.New("unexpected end of data")
+
+// reader decodes the primitive values of the WASM binary format, base is the
+// absolute offset of data in the binary so that positions can be reported.
+type reader struct {
+	data []byte
+	pos  int
+	base int
+}
+
+func newReader(data []byte, base int) *reader {
+	return &reader{
+		data: data,
+		base: base,
+	}
+}
+
+func (r *reader) offset() int {
+	return r.base + r.pos
+}
+
+func (r *reader) eof() bool {
+	return r.pos >= len(r.data)
+}
+
+func (r *reader) remaining() int {
+	return len(r.data) - r.pos
+}
+
+func (r *reader) readByte() (byte, error) {
+	if r.eof() {
+		return 0, r.errorf("%w", errUnexpectedEnd)
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *reader) peekByte() (byte, error) {
+	if r.eof() {
+		return 0, r.errorf("%w", errUnexpectedEnd)
+	}
+	return r.data[r.pos], nil
+}
+
+func (r *reader) readBytes(n int) ([]byte, error) {
+	if n < 0 || n > r.remaining() {
+		return nil, r.errorf("%w", errUnexpectedEnd)
+	}
+	bz := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return bz, nil
+}
+
+// readUnsigned reads an unsigned LEB128 integer of at most the given bits
+func (r *reader) readUnsigned(bits uint) (uint64, error) {
+	var result uint64
+	var shift uint
+
+	for {
+		b, err := r.readByte()
+		if err != nil {
+			return 0, err
+		}
+
+		if shift+7 > bits && b>>(bits-shift) != 0 {
+			return 0, r.errorf("integer representation too long")
+		}
+
+		result |= uint64(b&0x7F) << shift
+		shift += 7
+
+		if b&0x80 == 0 {
+			return result, nil
+		}
+	}
+}
+
+// readSigned reads a signed LEB128 integer of at most the given bits
+func (r *reader) readSigned(bits uint) (int64, error) {
+	var result int64
+	var shift uint
+
+	for {
+		b, err := r.readByte()
+		if err != nil {
+			return 0, err
+		}
+
+		if shift >= bits {
+			return 0, r.errorf("integer representation too long")
+		}
+
+		result |= int64(b&0x7F) << shift
+		shift += 7
+
+		if b&0x80 == 0 {
+			// Sign extend the result if the sign bit of the last byte is set
+			if shift < 64 && b&0x40 != 0 {
+				result |= -1 << shift
+			}
+			return result, nil
+		}
+	}
+}
+
+func (r *reader) readU32() (uint32, error) {
+	value, err := r.readUnsigned(32)
+	return uint32(value), err
+}
+
+func (r *reader) readU64() (uint64, error) {
+	return r.readUnsigned(64)
+}
+
+// readCount reads a vector length, which can not exceed the remaining bytes
+// since every element takes at least one byte
+func (r *reader) readCount() (uint32, error) {
+	count, err := r.readU32()
+	if err != nil {
+		return 0, err
+	}
+
+	if int(count) > r.remaining() {
+		return 0, r.errorf("vector length %d exceeds the remaining data", count)
+	}
+	return count, nil
+}
+
+func (r *reader) readName() (string, error) {
+	length, err := r.readU32()
+	if err != nil {
+		return "", err
+	}
+
+	bz, err := r.readBytes(int(length))
+	if err != nil {
+		return "", err
+	}
+
+	if !utf8.Valid(bz) {
+		return "", r.errorf("invalid UTF-8 name")
+	}
+	return string(bz), nil
+}
+
+func (r *reader) errorf(format string, args ...any) error {
+	return fmt.Errorf("offset 0x%x: %w", r.offset(), fmt.Errorf(format, args...))
+}
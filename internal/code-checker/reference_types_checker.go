@@ -0,0 +1,109 @@
+package checker
+
+import "fmt"
+
+var referenceTypesOpcodes = map[Opcode]bool{
+	OpSelectTyped:                       true,
+	OpTableGet:                          true,
+	OpTableSet:                          true,
+	OpRefNull:                           true,
+	OpRefIsNull:                         true,
+	OpRefFunc:                           true,
+	OpRefAsNonNull:                      true,
+	OpBrOnNull:                          true,
+	OpBrOnNonNull:                       true,
+	OpCallRef:                           true,
+	OpReturnCallRef:                     true,
+	newPrefixedOpcode(PrefixMisc, 0x0F): true, // table.grow
+	newPrefixedOpcode(PrefixMisc, 0x10): true, // table.size
+	newPrefixedOpcode(PrefixMisc, 0x11): true, // table.fill
+}
+
+// checkReferenceTypes reports every reference instruction, reference value
+// and additional table of a Wasm module
+func checkReferenceTypes(module *Module) []Violation {
+	var violations []Violation
+
+	for i, funcType := range module.Types {
+		if containsReference(funcType.Params) || containsReference(funcType.Results) {
+			violations = append(violations, NewViolation(
+				RuleReferenceTypes, module, nil, module.sectionOffset(SectionType),
+				fmt.Sprintf("reference in signature of type %d %s", i, funcType),
+			))
+		}
+	}
+
+	tables := len(module.Tables)
+	for _, imp := range module.Imports {
+		if imp.Kind == ExternalTable {
+			tables++
+		}
+		if imp.Kind == ExternalGlobal && isReference(imp.Global.ValType) {
+			violations = append(violations, NewViolation(
+				RuleReferenceTypes, module, nil, imp.Offset,
+				fmt.Sprintf("reference imported global %s.%s", imp.Module, imp.Name),
+			))
+		}
+	}
+
+	if tables > 1 {
+		violations = append(violations, NewViolation(
+			RuleReferenceTypes, module, nil, module.sectionOffset(SectionTable),
+			fmt.Sprintf("%d tables", tables),
+		))
+	}
+
+	for i, global := range module.Globals {
+		if isReference(global.Type.ValType) {
+			violations = append(violations, NewViolation(
+				RuleReferenceTypes, module, nil, global.Offset, fmt.Sprintf("reference global %d", i),
+			))
+		}
+	}
+
+	for i := range module.Functions {
+		function := &module.Functions[i]
+		for _, local := range function.Locals {
+			if isReference(local.Type) {
+				violations = append(violations, NewViolation(
+					RuleReferenceTypes, module, function, function.Offset, "reference local",
+				))
+				break
+			}
+		}
+	}
+
+	module.WalkInstructions(func(function *Function, instruction Instruction) {
+		// ref.func is allowed in element segments, which predate reference types
+		if function == nil && instruction.Opcode == OpRefFunc {
+			return
+		}
+
+		if referenceTypesOpcodes[instruction.Opcode] || usesTableIndex(instruction) {
+			violations = append(violations, NewInstructionViolation(RuleReferenceTypes, module, function, instruction))
+		}
+	})
+
+	return violations
+}
+
+// usesTableIndex returns true for indirect calls to tables other than the first
+func usesTableIndex(instruction Instruction) bool {
+	if instruction.Opcode != OpCallIndirect && instruction.Opcode != OpReturnCallIndirect {
+		return false
+	}
+	return instruction.Indices[1] != 0
+}
+
+func isReference(t ValType) bool {
+	return t == ValTypeFuncRef || t == ValTypeExternRef
+}
+
+func containsReference(types []ValType) bool {
+	for _, t := range types {
+		if isReference(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package checker
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	RuleFloatingPoint = "floating_point"
+	RuleSIMD          = "simd"
+	RuleThreads       = "threads"
+
+	RuleBulkMemory     = "bulk_memory"
+	RuleReferenceTypes = "reference_types"
+	RuleMultiValue     = "multi_value"
+	RuleMemory64       = "memory64"
+	RuleTailCalls      = "tail_calls"
+)
+
+// maxReportedViolations bounds the violations listed in error messages
+const maxReportedViolations = 10
+
+// Violation is a single construct of a module rejected by a rule
+type Violation struct {
+	Rule string `json:"rule"`
+	// Opcode is the name of the offending instruction, empty for declarations
+	Opcode string `json:"opcode,omitempty"`
+	// FunctionIndex is the index of the function containing the violation, if any
+	FunctionIndex *uint32 `json:"function_index,omitempty"`
+	// FunctionName is the export or debug name of the function, if any
+	FunctionName string `json:"function_name,omitempty"`
+	// Offset is the offset of the offending bytes in the binary
+	Offset  int    `json:"offset"`
+	Message string `json:"message"`
+
+	// Limit is the name of the exceeded resource limit, with the measured
+	// value and the maximum allowed
+	Limit    string `json:"limit,omitempty"`
+	Measured uint64 `json:"measured,omitempty"`
+	Maximum  uint64 `json:"maximum,omitempty"`
+}
+
+func (v Violation) String() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s: %s", v.Rule, v.Message)
+
+	if v.FunctionIndex != nil {
+		fmt.Fprintf(&builder, " in function %d", *v.FunctionIndex)
+		if v.FunctionName != "" {
+			fmt.Fprintf(&builder, " (%s)", v.FunctionName)
+		}
+	}
+
+	fmt.Fprintf(&builder, " at offset 0x%x", v.Offset)
+	return builder.String()
+}
+
+// CheckReport lists the violations found in a module
+type CheckReport struct {
+	Violations []Violation `json:"violations"`
+}
+
+// Passed returns true if the module has no violation
+func (r *CheckReport) Passed() bool {
+	return len(r.Violations) == 0
+}
+
+// Err returns a CheckError describing the violations, or nil if the module passed
+func (r *CheckReport) Err() error {
+	if r.Passed() {
+		return nil
+	}
+	return &CheckError{Report: r}
+}
+
+func (r *CheckReport) String() string {
+	if r.Passed() {
+		return "no violations"
+	}
+
+	lines := make([]string, 0, len(r.Violations))
+	for _, violation := range r.Violations {
+		lines = append(lines, violation.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Merge appends the violations of another report
+func (r *CheckReport) Merge(other *CheckReport) {
+	r.add(other.Violations...)
+}
+
+func (r *CheckReport) add(violations ...Violation) {
+	r.Violations = append(r.Violations, violations...)
+}
+
+// CheckError is returned for modules with violations
+type CheckError struct {
+	Report *CheckReport
+}
+
+func (e *CheckError) Error() string {
+	violations := e.Report.Violations
+
+	descriptions := make([]string, 0, maxReportedViolations)
+	for i, violation := range violations {
+		if i == maxReportedViolations {
+			descriptions = append(descriptions, fmt.Sprintf("and %d more", len(violations)-i))
+			break
+		}
+		descriptions = append(descriptions, violation.String())
+	}
+
+	return fmt.Sprintf("code contains unsupported operations: %s", strings.Join(descriptions, "; "))
+}
+
+// NewInstructionViolation reports an instruction, function is nil for
+// instructions of constant expressions
+func NewInstructionViolation(rule string, module *Module, function *Function, instruction Instruction) Violation {
+	violation := NewViolation(rule, module, function, instruction.Offset, fmt.Sprintf("instruction %s", instruction.Opcode))
+	violation.Opcode = instruction.Opcode.String()
+	return violation
+}
+
+// NewViolation reports a construct at the given offset, function is nil for
+// constructs outside of function bodies
+func NewViolation(rule string, module *Module, function *Function, offset int, message string) Violation {
+	violation := Violation{
+		Rule:    rule,
+		Offset:  offset,
+		Message: message,
+	}
+
+	if function != nil {
+		index := function.Index
+		violation.FunctionIndex = &index
+		violation.FunctionName = module.FuncName(index)
+	}
+	return violation
+}
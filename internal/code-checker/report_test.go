@@ -0,0 +1,84 @@
+package checker
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bytecodealliance/wasmtime-go/v31"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckReportLocations(t *testing.T) {
+	code, err := wasmtime.Wat2Wasm(`
+		(module
+		  (memory 1 1 shared)
+		  (func $helper (param v128))
+		  (func (export "compute") (param i32) (result i32)
+		    (i32.trunc_f64_s (f64.add (f64.const 1) (f64.convert_i32_s (local.get 0)))))
+		  (func $fence
+		    (atomic.fence)))
+	`)
+	require.NoError(t, err)
+
+	report, err := CheckCode(code)
+	require.NoError(t, err)
+	require.False(t, report.Passed())
+
+	var floats []Violation
+	for _, violation := range report.Violations {
+		if violation.Rule == RuleFloatingPoint {
+			floats = append(floats, violation)
+		}
+	}
+
+	// f64.const is only data movement and not reported
+	require.Len(t, floats, 3)
+	require.Equal(t, "f64.convert_i32_s", floats[0].Opcode)
+	require.Equal(t, "f64.add", floats[1].Opcode)
+	require.Equal(t, "i32.trunc_f64_s", floats[2].Opcode)
+
+	convert := floats[0]
+	require.Equal(t, uint32(1), *convert.FunctionIndex)
+	require.Equal(t, "compute", convert.FunctionName)
+	require.Equal(t, byte(0xB7), code[convert.Offset])
+
+	// Debug names are used for functions which are not exported
+	var fence Violation
+	for _, violation := range report.Violations {
+		if violation.Opcode == "atomic.fence" {
+			fence = violation
+		}
+	}
+	require.Equal(t, RuleThreads, fence.Rule)
+	require.Equal(t, uint32(2), *fence.FunctionIndex)
+	require.Equal(t, "fence", fence.FunctionName)
+	require.Equal(t, []byte{0xFE, 0x03}, code[fence.Offset:fence.Offset+2])
+
+	require.Contains(t, report.String(), "simd: v128 in signature of type 0 (v128) -> ()")
+	require.Contains(t, report.String(), "threads: shared memory 0")
+	require.Contains(t, report.String(), "floating_point: instruction f64.add in function 1 (compute) at offset")
+
+	bz, err := json.Marshal(report)
+	require.NoError(t, err)
+	require.Contains(t, string(bz), `"opcode":"f64.add","function_index":1,"function_name":"compute"`)
+}
+
+func TestCheckReportError(t *testing.T) {
+	report := &CheckReport{}
+	require.NoError(t, report.Err())
+
+	for i := 0; i < maxReportedViolations+3; i++ {
+		report.add(Violation{Rule: RuleFloatingPoint, Opcode: "f32.add", Message: "instruction f32.add"})
+	}
+
+	err := report.Err()
+	require.Error(t, err)
+	require.True(t, strings.HasPrefix(err.Error(), "code contains unsupported operations: "))
+	require.True(t, strings.HasSuffix(err.Error(), "; and 3 more"))
+
+	var checkErr *CheckError
+	require.True(t, errors.As(err, &checkErr))
+	require.Len(t, checkErr.Report.Violations, maxReportedViolations+3)
+}
@@ -0,0 +1,69 @@
+package checker
+
+// Rule reports the constructs of a module it does not allow
+type Rule interface {
+	Name() string
+	Check(module *Module) []Violation
+}
+
+type ruleFunc struct {
+	name  string
+	check func(module *Module) []Violation
+}
+
+// NewRule creates a rule from a check function, the violations it returns
+// should use the rule name
+func NewRule(name string, check func(module *Module) []Violation) Rule {
+	return ruleFunc{
+		name:  name,
+		check: check,
+	}
+}
+
+func (r ruleFunc) Name() string {
+	return r.name
+}
+
+func (r ruleFunc) Check(module *Module) []Violation {
+	return r.check(module)
+}
+
+// FloatingPointRule rejects f32 and f64 arithmetic, comparisons and conversions
+func FloatingPointRule() Rule {
+	return NewRule(RuleFloatingPoint, checkFloatingPointOps)
+}
+
+// SIMDRule rejects SIMD instructions and v128 values
+func SIMDRule() Rule {
+	return NewRule(RuleSIMD, checkSIMDOps)
+}
+
+// ThreadsRule rejects atomic instructions and shared memories
+func ThreadsRule() Rule {
+	return NewRule(RuleThreads, checkThreadingOps)
+}
+
+// BulkMemoryRule rejects bulk memory instructions and passive segments
+func BulkMemoryRule() Rule {
+	return NewRule(RuleBulkMemory, checkBulkMemoryOps)
+}
+
+// ReferenceTypesRule rejects reference instructions and values, and multiple tables
+func ReferenceTypesRule() Rule {
+	return NewRule(RuleReferenceTypes, checkReferenceTypes)
+}
+
+// MultiValueRule rejects functions and blocks with more than one result
+func MultiValueRule() Rule {
+	return NewRule(RuleMultiValue, checkMultiValue)
+}
+
+// Memory64Rule rejects memories indexed with 64-bit addresses
+func Memory64Rule() Rule {
+	return NewRule(RuleMemory64, checkMemory64)
+}
+
+// TailCallsRule rejects tail call instructions
+func TailCallsRule() Rule {
+	return NewRule(RuleTailCalls, checkTailCalls)
+}
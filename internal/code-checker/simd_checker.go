@@ -1,72 +1,74 @@
 package checker
 
-import (
-	"bytes"
-	"fmt"
-	"io"
-)
+import "fmt"
 
-// containsSIMDOps checks if a Wasm binary contains any SIMD operations
-func containsSIMDOps(wasmCode []byte) (bool, error) {
-	// Check Wasm magic number
-	if len(wasmCode) < 8 {
-		return false, fmt.Errorf("invalid Wasm binary: too short")
-	}
+// checkSIMDOps reports every SIMD operation of a Wasm module and every
+// declaration of values of the v128 type
+func checkSIMDOps(module *Module) []Violation {
+	var violations []Violation
 
-	magic := wasmCode[0:4]
-	if !bytes.Equal(magic, []byte{0x00, 0x61, 0x73, 0x6D}) {
-		return false, fmt.Errorf("invalid Wasm binary: wrong magic number")
+	// Check if v128 type is used in the types, imported globals and globals
+	for i, funcType := range module.Types {
+		if containsV128(funcType.Params) || containsV128(funcType.Results) {
+			violations = append(violations, NewViolation(
+				RuleSIMD, module, nil, module.sectionOffset(SectionType),
+				fmt.Sprintf("v128 in signature of type %d %s", i, funcType),
+			))
+		}
 	}
 
-	// Check for SIMD in the type section
-	// First, look for the type section (section ID = 1)
-	reader := bytes.NewReader(wasmCode)
-
-	// Skip magic number and version
-	_, err := reader.Seek(8, io.SeekStart)
-	if err != nil {
-		return false, err
+	for _, imp := range module.Imports {
+		if imp.Kind == ExternalGlobal && imp.Global.ValType == ValTypeV128 {
+			violations = append(violations, NewViolation(
+				RuleSIMD, module, nil, imp.Offset,
+				fmt.Sprintf("v128 imported global %s.%s", imp.Module, imp.Name),
+			))
+		}
 	}
 
-	// Scan for SIMD opcodes
-	// SIMD instructions use the 0xFD prefix
-	buf := make([]byte, 1024)
-	for {
-		n, err := reader.Read(buf)
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return false, err
+	for i, global := range module.Globals {
+		if global.Type.ValType == ValTypeV128 {
+			violations = append(violations, NewViolation(
+				RuleSIMD, module, nil, global.Offset,
+				fmt.Sprintf("v128 global %d", i),
+			))
 		}
+	}
 
-		// Look for the SIMD prefix (0xFD)
-		for i := 0; i < n; i++ {
-			if buf[i] == 0xFD {
-				return true, nil
+	for i := range module.Functions {
+		function := &module.Functions[i]
+		for _, local := range function.Locals {
+			if local.Type == ValTypeV128 {
+				violations = append(violations, NewViolation(
+					RuleSIMD, module, function, function.Offset, "v128 local",
+				))
+				break
 			}
 		}
 	}
 
-	// Check if v128 type is used in the types or function signatures
-	// Vector type v128 is represented by the value 0x7B in the binary format
-	reader.Seek(8, io.SeekStart) // Reset to beginning after magic+version
-
-	for {
-		n, err := reader.Read(buf)
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return false, err
+	// SIMD instructions use the 0xFD prefix
+	module.WalkInstructions(func(function *Function, instruction Instruction) {
+		if instruction.Opcode.Prefix() == PrefixSIMD || usesV128(instruction) {
+			violations = append(violations, NewInstructionViolation(RuleSIMD, module, function, instruction))
 		}
+	})
 
-		for i := 0; i < n; i++ {
-			if buf[i] == 0x7B { // v128 type identifier
-				return true, nil
-			}
-		}
+	return violations
+}
+
+func usesV128(instruction Instruction) bool {
+	if valType, ok := instruction.BlockValType(); ok && valType == ValTypeV128 {
+		return true
 	}
+	return containsV128(instruction.Types)
+}
 
-	return false, nil
+func containsV128(types []ValType) bool {
+	for _, t := range types {
+		if t == ValTypeV128 {
+			return true
+		}
+	}
+	return false
 }
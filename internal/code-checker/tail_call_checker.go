@@ -0,0 +1,15 @@
+package checker
+
+// checkTailCalls reports every tail call instruction of a Wasm module
+func checkTailCalls(module *Module) []Violation {
+	var violations []Violation
+
+	module.WalkInstructions(func(function *Function, instruction Instruction) {
+		switch instruction.Opcode {
+		case OpReturnCall, OpReturnCallIndirect, OpReturnCallRef:
+			violations = append(violations, NewInstructionViolation(RuleTailCalls, module, function, instruction))
+		}
+	})
+
+	return violations
+}
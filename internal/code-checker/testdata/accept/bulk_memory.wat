@@ -0,0 +1,9 @@
+;; Prefixed 0xfc instructions other than saturating truncations
+(module
+  (memory 1)
+  (data $d "\fd\fe")
+  (func (export "copy")
+    (memory.init $d (i32.const 0) (i32.const 0) (i32.const 2))
+    (memory.copy (i32.const 8) (i32.const 0) (i32.const 2))
+    (memory.fill (i32.const 16) (i32.const 0xfe) (i32.const 4))
+    (data.drop $d)))
@@ -0,0 +1,7 @@
+;; Data segments full of bytes matching float, SIMD and atomic opcodes
+(module
+  (memory (export "memory") 1)
+  (data (i32.const 0) "\7b\7b\fd\fd\fe\fe\00\8b\8c\92\a0\b2\bb")
+  (data (i32.const 16) "{\"key\":\"value\"}")
+  (func (export "read") (result i32)
+    (i32.load8_u (i32.const 2))))
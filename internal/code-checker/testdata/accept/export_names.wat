@@ -0,0 +1,5 @@
+;; Names are UTF-8 strings and may contain any byte
+(module
+  (func $first (export "{ý þ û}") (result i32)
+    (i32.const 0))
+  (func $second (export "\7b\7d") (drop (call $first))))
@@ -0,0 +1,10 @@
+;; Floats are only copied around, never computed with
+(module
+  (memory 1)
+  (global $f (mut f64) (f64.const 1.5))
+  (func (export "copy") (param f32) (result i64)
+    (local f64)
+    (f32.store (i32.const 0) (local.get 0))
+    (f64.store (i32.const 8) (global.get $f))
+    (local.set 1 (f64.load (i32.const 8)))
+    (i64.reinterpret_f64 (local.get 1))))
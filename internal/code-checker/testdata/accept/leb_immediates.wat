@@ -0,0 +1,17 @@
+;; Immediates whose LEB128 encoding contains opcode-like bytes
+(module
+  (memory (export "memory") 1)
+  (global $g (mut i32) (i32.const 123))
+  (func (export "consts") (result i64)
+    (drop (i32.const 123))       ;; 0xfb 0x00
+    (drop (i32.const -3))        ;; 0x7d
+    (drop (i32.const 0x3ffe))    ;; 0xfe 0xff 0x00
+    (drop (i32.const -133))      ;; 0xfb 0x7e
+    (drop (i32.load offset=253 (i32.const 0)))
+    (i64.store offset=0x7b (i32.const 0) (i64.const 0xfdfdfdfd))
+    (i64.const -0x44444445))
+  (func (export "labels") (param i32) (result i32)
+    (block $a
+      (block $b
+        (br_table $a $b $a (local.get 0))))
+    (global.get $g)))
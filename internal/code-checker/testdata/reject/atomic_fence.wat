@@ -0,0 +1,3 @@
+(module
+  (func (export "fence")
+    (atomic.fence)))
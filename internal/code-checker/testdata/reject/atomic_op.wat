@@ -0,0 +1,4 @@
+(module
+  (memory 1)
+  (func (export "add") (result i32)
+    (i32.atomic.rmw.add (i32.const 0) (i32.const 1))))
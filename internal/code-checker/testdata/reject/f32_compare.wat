@@ -0,0 +1,3 @@
+(module
+  (func (export "compare") (param f32) (result i32)
+    (f32.lt (local.get 0) (f32.const 0))))
@@ -0,0 +1,3 @@
+(module
+  (func (export "convert") (param i64) (result i64)
+    (i64.reinterpret_f64 (f64.convert_i64_s (local.get 0)))))
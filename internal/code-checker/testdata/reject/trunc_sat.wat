@@ -0,0 +1,3 @@
+(module
+  (func (export "trunc") (param f64) (result i32)
+    (i32.trunc_sat_f64_s (local.get 0))))
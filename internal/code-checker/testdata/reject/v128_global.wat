@@ -0,0 +1,2 @@
+(module
+  (global $g (mut v128) (v128.const i64x2 0 0)))
@@ -0,0 +1,4 @@
+(module
+  (func (export "unused") (result i32)
+    (local v128)
+    (i32.const 0)))
@@ -0,0 +1,3 @@
+(module
+  (func (export "identity") (param v128) (result v128)
+    (local.get 0)))
@@ -6,16 +6,27 @@ import (
 
 type CallbackQueue struct {
 	container []interfaces.ContractMessage
+	depths    []int
 	head      int
 }
 
 func NewCallbackQueue() *CallbackQueue {
 	return &CallbackQueue{
 		container: make([]interfaces.ContractMessage, 0),
+		depths:    make([]int, 0),
 	}
 }
+
+// Enqueue adds the message one level deeper than the last dequeued message,
+// messages enqueued before any dequeue are at depth 0.
 func (q *CallbackQueue) Enqueue(msg interfaces.ContractMessage) {
+	depth := 0
+	if q.head > 0 {
+		depth = q.depths[q.head-1] + 1
+	}
+
 	q.container = append(q.container, msg)
+	q.depths = append(q.depths, depth)
 }
 
 func (q *CallbackQueue) Dequeue() (interfaces.ContractMessage, bool) {
@@ -28,6 +39,14 @@ func (q *CallbackQueue) Dequeue() (interfaces.ContractMessage, bool) {
 	return msg, true
 }
 
+// Depth returns the call depth of the last dequeued message
+func (q *CallbackQueue) Depth() int {
+	if q.head == 0 {
+		return 0
+	}
+	return q.depths[q.head-1]
+}
+
 func (q *CallbackQueue) IsEmpty() bool {
 	return q.head >= len(q.container)
 }
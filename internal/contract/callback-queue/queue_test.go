@@ -0,0 +1,40 @@
+package callbackqueue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+)
+
+func TestQueueDepth(t *testing.T) {
+	queue := NewCallbackQueue()
+	queue.Enqueue(interfaces.NewContractMessage("root", "method", nil, "sender"))
+
+	msg, found := queue.Dequeue()
+	require.True(t, found)
+	require.Equal(t, "root", msg.Contract)
+	require.Equal(t, 0, queue.Depth())
+
+	// Messages enqueued while running the root message are one level deeper
+	queue.Enqueue(interfaces.NewContractMessage("child1", "method", nil, "root"))
+	queue.Enqueue(interfaces.NewContractMessage("child2", "method", nil, "root"))
+
+	msg, _ = queue.Dequeue()
+	require.Equal(t, "child1", msg.Contract)
+	require.Equal(t, 1, queue.Depth())
+
+	queue.Enqueue(interfaces.NewContractMessage("grandchild", "method", nil, "child1"))
+
+	msg, _ = queue.Dequeue()
+	require.Equal(t, "child2", msg.Contract)
+	require.Equal(t, 1, queue.Depth())
+
+	msg, _ = queue.Dequeue()
+	require.Equal(t, "grandchild", msg.Contract)
+	require.Equal(t, 2, queue.Depth())
+
+	_, found = queue.Dequeue()
+	require.False(t, found)
+}
@@ -4,24 +4,68 @@ import (
 	"crypto/sha256"
 	"encoding/binary"
 	"fmt"
+	"time"
 
 	"github.com/btcsuite/btcutil/base58"
 	"github.com/bytecodealliance/wasmtime-go/v31"
 	callbackqueue "github.com/dadamu/contract-wasmvm/internal/contract/callback-queue"
+	"github.com/dadamu/contract-wasmvm/internal/contract/gas"
 	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
 	"github.com/dadamu/contract-wasmvm/internal/contract/runtime"
 )
 
 type ContractExecutor struct {
-	engine *wasmtime.Engine
+	engine    *wasmtime.Engine
+	gasConfig gas.Config
+
+	// tracer is nil unless tracing is enabled
+	tracer interfaces.Tracer
+
+	// timeout is zero unless executions are interrupted after it
+	timeout time.Duration
+}
+
+type Option func(*ContractExecutor)
+
+// WithGasConfig overrides the gas costs charged by the host functions, which
+// are none by default, e.g. with gas.PricedConfig
+func WithGasConfig(config gas.Config) Option {
+	return func(ce *ContractExecutor) {
+		ce.gasConfig = config
+	}
+}
+
+// WithTracer enables tracing of the contract executions
+func WithTracer(tracer interfaces.Tracer) Option {
+	return func(ce *ContractExecutor) {
+		ce.tracer = tracer
+	}
+}
+
+// WithTimeout interrupts the execution of a message and the contract calls it
+// triggered with runtime.ErrExecutionTimeout once the timeout elapsed.
+// The engine must be created by runtime.NewInterruptibleEngine with a running
+// runtime.EpochTicker. It is meant for queries and simulations, never for
+// consensus execution since the result depends on the speed of the host.
+func WithTimeout(timeout time.Duration) Option {
+	return func(ce *ContractExecutor) {
+		ce.timeout = timeout
+	}
 }
 
 func NewContractExecutor(
 	engine *wasmtime.Engine,
+	opts ...Option,
 ) *ContractExecutor {
-	return &ContractExecutor{
-		engine: engine,
+	executor := &ContractExecutor{
+		engine:    engine,
+		gasConfig: gas.DefaultConfig(),
+	}
+
+	for _, opt := range opts {
+		opt(executor)
 	}
+	return executor
 }
 
 func generateContractId(
@@ -38,19 +82,18 @@ func generateContractId(
 func (ce *ContractExecutor) InitializeContract(
 	repository interfaces.IContractRepository,
 	state []byte,
-	codeId uint64,
-	args []byte,
+	msg interfaces.InitializeContractMessage,
 	gasLimit uint64,
-) (uint64, *callbackqueue.CallbackQueue, []interfaces.ResultEvent, error) {
+) (*gas.MessageReport, *callbackqueue.CallbackQueue, []interfaces.ResultEvent, error) {
 	// Get the total contract amount as salt
 	amount := repository.GetTotalContractAmount()
 	salt := make([]byte, 8)
 	binary.LittleEndian.PutUint64(salt, amount)
 
-	contractId := generateContractId(state, codeId, salt)
-	err := repository.CreateConctract(codeId, contractId)
+	contractId := generateContractId(state, msg.CodeId, salt)
+	err := repository.CreateConctract(msg.CodeId, contractId)
 	if err != nil {
-		return 0, nil, nil, err
+		return gas.NewMessageReport(), nil, nil, err
 	}
 
 	return ce.RunContract(
@@ -59,38 +102,61 @@ func (ce *ContractExecutor) InitializeContract(
 		interfaces.ContractMessage{
 			Contract: contractId,
 			Method:   "init",
-			Args:     args,
+			Args:     msg.Args,
+			Sender:   msg.Sender,
+			Funds:    msg.Funds,
 		},
 		gasLimit,
 	)
 }
 
+// RunContract runs the contract message and all the contract calls triggered by it.
+// The returned report records the gas consumed even if the execution failed,
+// so the remaining gas is the gas limit minus the report total.
 func (ce *ContractExecutor) RunContract(
 	repository interfaces.IContractRepository,
 	state []byte,
 	msg interfaces.ContractMessage,
 	gasLimit uint64,
-) (uint64, *callbackqueue.CallbackQueue, []interfaces.ResultEvent, error) {
+) (*gas.MessageReport, *callbackqueue.CallbackQueue, []interfaces.ResultEvent, error) {
 	callbackQueue := callbackqueue.NewCallbackQueue()
 	resultEvents := []interfaces.ResultEvent{}
+	report := gas.NewMessageReport()
+
+	// The timeout applies to the message with all the contract calls it triggers
+	var deadline time.Time
+	if ce.timeout > 0 {
+		deadline = time.Now().Add(ce.timeout)
+	}
 
 	// Enqueue the initial contract call
 	// This is the first contract call that will be executed
 	callbackQueue.Enqueue(msg)
 
-	for msg, found := callbackQueue.Dequeue(); found; {
+	for msg, found := callbackQueue.Dequeue(); found; msg, found = callbackQueue.Dequeue() {
+
+		depth := callbackQueue.Depth()
+		if ce.tracer != nil {
+			ce.tracer.OnMessageStart(msg, depth, gasLimit)
+		}
 
 		// Run the contract with the current gas limit
-		remaining, err := ce.runMessage(callbackQueue, &resultEvents, repository, state, msg, gasLimit)
+		usage, err := ce.runMessage(callbackQueue, &resultEvents, repository, state, msg, gasLimit, deadline)
+		report.AddContract(msg.Contract, usage)
+
+		if ce.tracer != nil {
+			ce.tracer.OnMessageEnd(msg, depth, usage.Total, err)
+		}
+
 		if err != nil {
-			return 0, callbackQueue, resultEvents, err
+			return report, callbackQueue, resultEvents, err
 		}
 
 		// Update the gas limit for the next contract call
-		gasLimit = remaining
+		gasLimit -= usage.Total
 	}
 
-	return gasLimit, callbackQueue, resultEvents, nil
+	return report, callbackQueue, resultEvents, nil
 }
 
 func (ce *ContractExecutor) runMessage(
@@ -100,34 +166,68 @@ func (ce *ContractExecutor) runMessage(
 	state []byte,
 	msg interfaces.ContractMessage,
 	gasLimit uint64,
-) (uint64, error) {
+	deadline time.Time,
+) (*gas.Usage, error) {
 	// Load the contract code from the repository
 	module, err := ce.loadContract(repository, msg.Contract)
 	if err != nil {
-		return 0, err
+		return gas.NewUsage(), err
 	}
 
 	if msg.Method == "init" {
 		err := repository.TryInitializeContract(msg.Contract)
 		if err != nil {
-			return 0, fmt.Errorf("failed to initialize contract: %w", err)
+			return gas.NewUsage(), fmt.Errorf("failed to initialize contract: %w", err)
 		}
 
-		*resultEvents = append(*resultEvents, interfaces.ResultEvent{
+		initializedEvent := interfaces.ResultEvent{
 			ContractId: msg.Contract,
 			Event:      "initialized",
-			Data:       "true",
-		})
+			// The data attribute is kept for the clients of the former event
+			Attributes: []interfaces.EventAttribute{
+				interfaces.NewEventAttribute("data", []byte("true")),
+				interfaces.NewEventAttribute("sender", []byte(msg.Sender)),
+			},
+			Depth: callbackQueue.Depth(),
+		}
+		if ce.tracer != nil {
+			ce.tracer.OnEvent(initializedEvent)
+		}
+
+		*resultEvents = append(*resultEvents, initializedEvent)
+	}
+
+	// Transfer the attached funds to the contract before execution
+	if len(msg.Funds) > 0 {
+		err := repository.TransferCoins(msg.Sender, msg.Contract, msg.Funds)
+		if err != nil {
+			return gas.NewUsage(), fmt.Errorf("failed to transfer funds: %w", err)
+		}
 	}
 
 	// Execute the contract
-	runtime := runtime.NewRuntimeFromModule(ce.engine, callbackQueue, resultEvents, repository, module, state, msg.Contract, gasLimit)
-	remaining, err := runtime.Run(msg)
+	var opts []runtime.Option
+	if !deadline.IsZero() {
+		opts = append(opts, runtime.WithDeadline(deadline))
+	}
+
+	runtime, err := runtime.NewRuntimeFromModule(ce.engine, callbackQueue, resultEvents, repository, module, state, msg.Contract, gasLimit, ce.gasConfig, opts...)
+	if err != nil {
+		if ce.tracer != nil {
+			ce.tracer.OnTrap(msg.Contract, err)
+		}
+		return runtime.GasUsage(), err
+	}
+	if ce.tracer != nil {
+		runtime.SetTracer(ce.tracer)
+	}
+
+	_, err = runtime.Run(msg)
 	if err != nil {
-		return 0, fmt.Errorf("failed to run contract: %w", err)
+		return runtime.GasUsage(), fmt.Errorf("failed to run contract: %w", err)
 	}
 
-	return remaining, nil
+	return runtime.GasUsage(), nil
 }
 
 func (ce *ContractExecutor) loadContract(repository interfaces.IContractRepository, contractId string) (*wasmtime.Module, error) {
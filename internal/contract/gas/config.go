@@ -0,0 +1,68 @@
+package gas
+
+import "errors"
+
+// ErrOutOfGas is raised when the gas limit is not enough to pay for an operation.
+var ErrOutOfGas = errors.New("out of gas")
+
+type Category string
+
+const (
+	CategoryCompute       Category = "compute"
+	CategoryInstantiation Category = "instantiation"
+	CategoryStorageRead   Category = "storage_read"
+	CategoryStorageWrite  Category = "storage_write"
+	CategoryEvents        Category = "events"
+)
+
+// Config defines the gas costs charged by the host functions on top of the
+// fuel consumed by the WASM instructions.
+type Config struct {
+	InstantiationCost uint64
+
+	StorageReadCostFlat    uint64
+	StorageReadCostPerByte uint64
+
+	StorageWriteCostFlat    uint64
+	StorageWriteCostPerByte uint64
+
+	EventCostFlat    uint64
+	EventCostPerByte uint64
+}
+
+// DefaultConfig charges nothing on top of the fuel, so the gas used by the
+// existing contracts is unchanged. Chains opt into the host function costs
+// with PricedConfig.
+func DefaultConfig() Config {
+	return Config{}
+}
+
+// PricedConfig charges the host functions by the bytes they read, write and
+// emit. It changes the gas used by the existing contracts, so it must only be
+// enabled by a chain upgrade.
+func PricedConfig() Config {
+	return Config{
+		InstantiationCost: 1000,
+
+		StorageReadCostFlat:    1000,
+		StorageReadCostPerByte: 3,
+
+		StorageWriteCostFlat:    2000,
+		StorageWriteCostPerByte: 30,
+
+		EventCostFlat:    500,
+		EventCostPerByte: 10,
+	}
+}
+
+func (c Config) StorageReadCost(size int) uint64 {
+	return c.StorageReadCostFlat + c.StorageReadCostPerByte*uint64(size)
+}
+
+func (c Config) StorageWriteCost(size int) uint64 {
+	return c.StorageWriteCostFlat + c.StorageWriteCostPerByte*uint64(size)
+}
+
+func (c Config) EventCost(size int) uint64 {
+	return c.EventCostFlat + c.EventCostPerByte*uint64(size)
+}
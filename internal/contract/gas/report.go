@@ -0,0 +1,91 @@
+package gas
+
+// Usage is the gas consumed broken down by category.
+type Usage struct {
+	Total      uint64              `json:"total"`
+	Categories map[Category]uint64 `json:"categories"`
+}
+
+func NewUsage() *Usage {
+	return &Usage{
+		Categories: make(map[Category]uint64),
+	}
+}
+
+func (u *Usage) Add(category Category, amount uint64) {
+	u.Total += amount
+	u.Categories[category] += amount
+}
+
+func (u *Usage) Merge(other *Usage) {
+	for category, amount := range other.Categories {
+		u.Add(category, amount)
+	}
+}
+
+// ----------------------------------------------------------------------------
+
+// MessageReport is the gas consumed by a single transaction message,
+// including all the contract calls triggered by it.
+type MessageReport struct {
+	Usage
+	Contracts map[string]*Usage `json:"contracts"`
+}
+
+func NewMessageReport() *MessageReport {
+	return &MessageReport{
+		Usage:     *NewUsage(),
+		Contracts: make(map[string]*Usage),
+	}
+}
+
+func (mr *MessageReport) AddContract(contractId string, usage *Usage) {
+	mr.Merge(usage)
+
+	if _, ok := mr.Contracts[contractId]; !ok {
+		mr.Contracts[contractId] = NewUsage()
+	}
+	mr.Contracts[contractId].Merge(usage)
+}
+
+// ----------------------------------------------------------------------------
+
+// Report is the gas consumed by a transaction, it is built from the reports
+// of the transaction messages in execution order.
+type Report struct {
+	Limit     uint64 `json:"limit"`
+	Remaining uint64 `json:"remaining"`
+
+	Usage
+	Contracts map[string]*Usage `json:"contracts"`
+	Messages  []*MessageReport  `json:"messages"`
+}
+
+func NewReport(limit uint64) *Report {
+	return &Report{
+		Limit:     limit,
+		Remaining: limit,
+
+		Usage:     *NewUsage(),
+		Contracts: make(map[string]*Usage),
+		Messages:  make([]*MessageReport, 0),
+	}
+}
+
+func (r *Report) AddMessage(report *MessageReport) {
+	r.Messages = append(r.Messages, report)
+	r.Merge(&report.Usage)
+
+	for contractId, usage := range report.Contracts {
+		if _, ok := r.Contracts[contractId]; !ok {
+			r.Contracts[contractId] = NewUsage()
+		}
+		r.Contracts[contractId].Merge(usage)
+	}
+
+	if report.Total > r.Remaining {
+		r.Remaining = 0
+		return
+	}
+	r.Remaining -= report.Total
+}
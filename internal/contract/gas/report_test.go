@@ -0,0 +1,47 @@
+package gas
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportAddMessage(t *testing.T) {
+	first := NewMessageReport()
+	firstUsage := NewUsage()
+	firstUsage.Add(CategoryCompute, 100)
+	firstUsage.Add(CategoryStorageWrite, 50)
+	first.AddContract("contract1", firstUsage)
+
+	second := NewMessageReport()
+	secondUsage := NewUsage()
+	secondUsage.Add(CategoryCompute, 30)
+	secondUsage.Add(CategoryEvents, 20)
+	second.AddContract("contract1", secondUsage)
+	second.AddContract("contract2", secondUsage)
+
+	report := NewReport(1_000)
+	report.AddMessage(first)
+	report.AddMessage(second)
+
+	require.Equal(t, uint64(250), report.Total)
+	require.Equal(t, uint64(750), report.Remaining)
+	require.Len(t, report.Messages, 2)
+
+	require.Equal(t, uint64(160), report.Categories[CategoryCompute])
+	require.Equal(t, uint64(50), report.Categories[CategoryStorageWrite])
+	require.Equal(t, uint64(40), report.Categories[CategoryEvents])
+
+	require.Equal(t, uint64(200), report.Contracts["contract1"].Total)
+	require.Equal(t, uint64(50), report.Contracts["contract2"].Total)
+}
+
+func TestReportRemainingSaturates(t *testing.T) {
+	message := NewMessageReport()
+	message.Add(CategoryCompute, 200)
+
+	report := NewReport(100)
+	report.AddMessage(message)
+
+	require.Equal(t, uint64(0), report.Remaining)
+}
@@ -0,0 +1,14 @@
+package interfaces
+
+// Coin is an amount of a single native token denomination.
+type Coin struct {
+	Denom  string
+	Amount uint64
+}
+
+func NewCoin(denom string, amount uint64) Coin {
+	return Coin{
+		Denom:  denom,
+		Amount: amount,
+	}
+}
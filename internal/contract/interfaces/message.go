@@ -1,5 +1,11 @@
 package interfaces
 
+import (
+	"fmt"
+
+	"github.com/dadamu/contract-wasmvm/internal/auth"
+)
+
 type VMMessage interface {
 	IsVMMessage()
 }
@@ -18,6 +24,9 @@ type ContractMessage struct {
 	Method   string
 	Args     []byte
 	Sender   string
+
+	// Funds are transferred from the sender to the contract before execution
+	Funds []Coin
 }
 
 func (cm ContractMessage) IsVMMessage() {}
@@ -33,10 +42,27 @@ type InitializeContractMessage struct {
 	CodeId uint64
 	Args   []byte
 	Sender string
+
+	// Funds are transferred from the sender to the new contract before execution
+	Funds []Coin
 }
 
 func (icm InitializeContractMessage) IsVMMessage() {}
 
+// MessageSender returns the sender of the message, it is empty for unknown
+// message types
+func MessageSender(msg VMMessage) string {
+	switch msg := msg.(type) {
+	case DeployContractCodeMessage:
+		return msg.Sender
+	case InitializeContractMessage:
+		return msg.Sender
+	case ContractMessage:
+		return msg.Sender
+	}
+	return ""
+}
+
 // ----------------------------------------------------------------------------
 
 type Transaction interface {
@@ -44,3 +70,47 @@ type Transaction interface {
 	GetState() []byte
 	GetMessages() []VMMessage
 }
+
+// SignedTransaction is a transaction carrying the signatures of its senders
+type SignedTransaction interface {
+	Transaction
+
+	// GetSignBytes returns the bytes signed by the signers
+	GetSignBytes() ([]byte, error)
+	GetSignatures() []auth.Signature
+
+	GetChainId() string
+	// GetSequence returns the expected sequence of the first signer
+	GetSequence() uint64
+	// GetTimeoutHeight returns the last height the transaction can be
+	// included at, zero if it does not expire
+	GetTimeoutHeight() uint64
+}
+
+// VerifySignatures checks the signatures are valid for the sign bytes and every
+// message sender signed, it returns the address of the first signer whose
+// sequence the transaction carries
+func VerifySignatures(tx SignedTransaction) (string, error) {
+	signatures := tx.GetSignatures()
+	if len(signatures) == 0 {
+		return "", fmt.Errorf("%w: transaction has no signatures", auth.ErrUnauthorized)
+	}
+
+	signBytes, err := tx.GetSignBytes()
+	if err != nil {
+		return "", err
+	}
+
+	signers, err := auth.VerifySignatures(signBytes, signatures)
+	if err != nil {
+		return "", err
+	}
+
+	for msgIndex, msg := range tx.GetMessages() {
+		sender := MessageSender(msg)
+		if _, ok := signers[sender]; !ok {
+			return "", fmt.Errorf("%w: sender %s of message %d did not sign the transaction", auth.ErrUnauthorized, sender, msgIndex)
+		}
+	}
+	return signatures[0].PubKey.Address(), nil
+}
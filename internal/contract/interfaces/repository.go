@@ -1,5 +1,7 @@
 package interfaces
 
+import "fmt"
+
 type IContractRepository interface {
 	// SaveEntity saves the entity with the given key and data in the contract namespace.
 	SaveEntity(contractId string, key string, data []byte)
@@ -22,4 +24,22 @@ type IContractRepository interface {
 
 	// GetTotalContractAmount retrieves the total amount of the contract.
 	GetTotalContractAmount() uint64
+
+	// GetBalance retrieves the balance of the denom held by the address.
+	GetBalance(address string, denom string) uint64
+
+	// TransferCoins moves the coins from one address to another.
+	// It returns an error if the sender does not have enough balance.
+	TransferCoins(from string, to string, coins []Coin) error
+}
+
+// StoreError is raised as a panic by the repository when its underlying store
+// fails, such as an I/O error. It is not caused by the contract, so it must not
+// be recovered as a failed execution.
+type StoreError struct {
+	Value any
+}
+
+func (e StoreError) Error() string {
+	return fmt.Sprintf("store error: %v", e.Value)
 }
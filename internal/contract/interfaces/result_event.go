@@ -1,7 +1,67 @@
 package interfaces
 
+import "fmt"
+
+const (
+	MaxEventNameSize           = 128
+	MaxEventAttributes         = 64
+	MaxEventAttributeKeySize   = 128
+	MaxEventAttributeValueSize = 4096
+)
+
+type EventAttribute struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+func NewEventAttribute(key string, value []byte) EventAttribute {
+	return EventAttribute{
+		Key:   key,
+		Value: value,
+	}
+}
+
 type ResultEvent struct {
-	ContractId string
-	Event      string
-	Data       string
+	ContractId string           `json:"contract_id"`
+	Event      string           `json:"event"`
+	Attributes []EventAttribute `json:"attributes"`
+
+	// MsgIndex is the index of the transaction message which emitted the event
+	MsgIndex int `json:"msg_index"`
+	// Depth is the call depth of the contract call which emitted the event,
+	// the contract called by the transaction message is at depth 0
+	Depth int `json:"depth"`
+}
+
+// GetAttribute returns the value of the first attribute with the given key
+func (e ResultEvent) GetAttribute(key string) ([]byte, bool) {
+	for _, attribute := range e.Attributes {
+		if attribute.Key == key {
+			return attribute.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Validate checks the event against the attribute count and size limits
+func (e ResultEvent) Validate() error {
+	if len(e.Event) == 0 || len(e.Event) > MaxEventNameSize {
+		return fmt.Errorf("invalid event name size: %d", len(e.Event))
+	}
+
+	if len(e.Attributes) > MaxEventAttributes {
+		return fmt.Errorf("too many event attributes: %d > %d", len(e.Attributes), MaxEventAttributes)
+	}
+
+	for _, attribute := range e.Attributes {
+		if len(attribute.Key) == 0 || len(attribute.Key) > MaxEventAttributeKeySize {
+			return fmt.Errorf("invalid event attribute key size: %d", len(attribute.Key))
+		}
+
+		if len(attribute.Value) > MaxEventAttributeValueSize {
+			return fmt.Errorf("event attribute %s value too large: %d > %d", attribute.Key, len(attribute.Value), MaxEventAttributeValueSize)
+		}
+	}
+
+	return nil
 }
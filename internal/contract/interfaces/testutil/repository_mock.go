@@ -12,6 +12,7 @@ package testutil
 import (
 	reflect "reflect"
 
+	interfaces "github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -53,6 +54,20 @@ func (mr *MockIContractRepositoryMockRecorder) CreateConctract(codeId, contractI
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateConctract", reflect.TypeOf((*MockIContractRepository)(nil).CreateConctract), codeId, contractId)
 }
 
+// GetBalance mocks base method.
+func (m *MockIContractRepository) GetBalance(address, denom string) uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBalance", address, denom)
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// GetBalance indicates an expected call of GetBalance.
+func (mr *MockIContractRepositoryMockRecorder) GetBalance(address, denom any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalance", reflect.TypeOf((*MockIContractRepository)(nil).GetBalance), address, denom)
+}
+
 // GetContractCodeByContract mocks base method.
 func (m *MockIContractRepository) GetContractCodeByContract(contractId string) ([]byte, error) {
 	m.ctrl.T.Helper()
@@ -123,6 +138,20 @@ func (mr *MockIContractRepositoryMockRecorder) SaveEntity(contractId, key, data
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveEntity", reflect.TypeOf((*MockIContractRepository)(nil).SaveEntity), contractId, key, data)
 }
 
+// TransferCoins mocks base method.
+func (m *MockIContractRepository) TransferCoins(from, to string, coins []interfaces.Coin) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferCoins", from, to, coins)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TransferCoins indicates an expected call of TransferCoins.
+func (mr *MockIContractRepositoryMockRecorder) TransferCoins(from, to, coins any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferCoins", reflect.TypeOf((*MockIContractRepository)(nil).TransferCoins), from, to, coins)
+}
+
 // TryInitializeContract mocks base method.
 func (m *MockIContractRepository) TryInitializeContract(contractId string) error {
 	m.ctrl.T.Helper()
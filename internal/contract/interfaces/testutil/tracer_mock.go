@@ -0,0 +1,125 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/contract/interfaces/tracer.go
+//
+// Generated by this command:
+//
+//	mockgen -source ./internal/contract/interfaces/tracer.go -package testutil -destination ./internal/contract/interfaces/testutil/tracer_mock.go
+//
+
+// Package testutil is a generated GoMock package.
+package testutil
+
+import (
+	reflect "reflect"
+
+	interfaces "github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockTracer is a mock of Tracer interface.
+type MockTracer struct {
+	ctrl     *gomock.Controller
+	recorder *MockTracerMockRecorder
+	isgomock struct{}
+}
+
+// MockTracerMockRecorder is the mock recorder for MockTracer.
+type MockTracerMockRecorder struct {
+	mock *MockTracer
+}
+
+// NewMockTracer creates a new mock instance.
+func NewMockTracer(ctrl *gomock.Controller) *MockTracer {
+	mock := &MockTracer{ctrl: ctrl}
+	mock.recorder = &MockTracerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTracer) EXPECT() *MockTracerMockRecorder {
+	return m.recorder
+}
+
+// OnEvent mocks base method.
+func (m *MockTracer) OnEvent(event interfaces.ResultEvent) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnEvent", event)
+}
+
+// OnEvent indicates an expected call of OnEvent.
+func (mr *MockTracerMockRecorder) OnEvent(event any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnEvent", reflect.TypeOf((*MockTracer)(nil).OnEvent), event)
+}
+
+// OnHostCall mocks base method.
+func (m *MockTracer) OnHostCall(contractId, name string, args []any, gas uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnHostCall", contractId, name, args, gas)
+}
+
+// OnHostCall indicates an expected call of OnHostCall.
+func (mr *MockTracerMockRecorder) OnHostCall(contractId, name, args, gas any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnHostCall", reflect.TypeOf((*MockTracer)(nil).OnHostCall), contractId, name, args, gas)
+}
+
+// OnMessageEnd mocks base method.
+func (m *MockTracer) OnMessageEnd(msg interfaces.ContractMessage, depth int, gasUsed uint64, err error) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnMessageEnd", msg, depth, gasUsed, err)
+}
+
+// OnMessageEnd indicates an expected call of OnMessageEnd.
+func (mr *MockTracerMockRecorder) OnMessageEnd(msg, depth, gasUsed, err any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnMessageEnd", reflect.TypeOf((*MockTracer)(nil).OnMessageEnd), msg, depth, gasUsed, err)
+}
+
+// OnMessageStart mocks base method.
+func (m *MockTracer) OnMessageStart(msg interfaces.ContractMessage, depth int, gasLimit uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnMessageStart", msg, depth, gasLimit)
+}
+
+// OnMessageStart indicates an expected call of OnMessageStart.
+func (mr *MockTracerMockRecorder) OnMessageStart(msg, depth, gasLimit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnMessageStart", reflect.TypeOf((*MockTracer)(nil).OnMessageStart), msg, depth, gasLimit)
+}
+
+// OnStorageRead mocks base method.
+func (m *MockTracer) OnStorageRead(contractId, key string, value []byte) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnStorageRead", contractId, key, value)
+}
+
+// OnStorageRead indicates an expected call of OnStorageRead.
+func (mr *MockTracerMockRecorder) OnStorageRead(contractId, key, value any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnStorageRead", reflect.TypeOf((*MockTracer)(nil).OnStorageRead), contractId, key, value)
+}
+
+// OnStorageWrite mocks base method.
+func (m *MockTracer) OnStorageWrite(contractId, key string, value []byte) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnStorageWrite", contractId, key, value)
+}
+
+// OnStorageWrite indicates an expected call of OnStorageWrite.
+func (mr *MockTracerMockRecorder) OnStorageWrite(contractId, key, value any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnStorageWrite", reflect.TypeOf((*MockTracer)(nil).OnStorageWrite), contractId, key, value)
+}
+
+// OnTrap mocks base method.
+func (m *MockTracer) OnTrap(contractId string, err error) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnTrap", contractId, err)
+}
+
+// OnTrap indicates an expected call of OnTrap.
+func (mr *MockTracerMockRecorder) OnTrap(contractId, err any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnTrap", reflect.TypeOf((*MockTracer)(nil).OnTrap), contractId, err)
+}
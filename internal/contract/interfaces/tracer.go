@@ -0,0 +1,26 @@
+package interfaces
+
+// Tracer receives the execution steps of the contract calls, it is only
+// invoked when configured in the executor.
+type Tracer interface {
+	// OnMessageStart is called before the contract message is executed.
+	OnMessageStart(msg ContractMessage, depth int, gasLimit uint64)
+
+	// OnMessageEnd is called after the contract message is executed, err is nil on success.
+	OnMessageEnd(msg ContractMessage, depth int, gasUsed uint64, err error)
+
+	// OnHostCall is called when the contract calls a host function with the gas charged by it.
+	OnHostCall(contractId string, name string, args []any, gas uint64)
+
+	// OnStorageRead is called when the contract loads an entity.
+	OnStorageRead(contractId string, key string, value []byte)
+
+	// OnStorageWrite is called when the contract saves an entity.
+	OnStorageWrite(contractId string, key string, value []byte)
+
+	// OnEvent is called when an event is emitted.
+	OnEvent(event ResultEvent)
+
+	// OnTrap is called when the contract execution traps or panics.
+	OnTrap(contractId string, err error)
+}
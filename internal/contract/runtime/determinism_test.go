@@ -0,0 +1,120 @@
+package runtime
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/bytecodealliance/wasmtime-go/v31"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	checker "github.com/dadamu/contract-wasmvm/internal/code-checker"
+	callbackqueue "github.com/dadamu/contract-wasmvm/internal/contract/callback-queue"
+	"github.com/dadamu/contract-wasmvm/internal/contract/gas"
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces/testutil"
+)
+
+const (
+	canonicalNaN32 = 0x7FC00000
+	canonicalNaN64 = 0x7FF8000000000000
+)
+
+// runFloats runs the float-heavy contract and returns the bits of its results
+func runFloats(t *testing.T, config *wasmtime.Config) []uint64 {
+	wat, err := os.ReadFile("testdata/floats.wat")
+	require.NoError(t, err)
+
+	wasm, err := wasmtime.Wat2Wasm(string(wat))
+	require.NoError(t, err)
+
+	engine := wasmtime.NewEngineWithConfig(config)
+	module, err := wasmtime.NewModule(engine, wasm)
+	require.NoError(t, err)
+
+	var saved []byte
+	repository := testutil.NewMockIContractRepository(gomock.NewController(t))
+	repository.EXPECT().SaveEntity("contractId", "floats", gomock.Any()).Do(
+		func(_ string, _ string, value []byte) { saved = value },
+	)
+
+	runtime, err := NewRuntimeFromModule(
+		engine,
+		callbackqueue.NewCallbackQueue(),
+		&[]interfaces.ResultEvent{},
+		repository,
+		module,
+
+		[]byte("state"),
+		"contractId",
+		10_000_000,
+		gas.DefaultConfig(),
+	)
+	require.NoError(t, err)
+
+	_, err = runtime.Run(interfaces.NewContractMessage("contractId", "compute", []byte{}, "sender"))
+	require.NoError(t, err)
+
+	results := make([]uint64, len(saved)/8)
+	for i := range results {
+		results[i] = binary.LittleEndian.Uint64(saved[i*8:])
+	}
+	return results
+}
+
+func TestFloatsPassFloatsProfile(t *testing.T) {
+	wat, err := os.ReadFile("testdata/floats.wat")
+	require.NoError(t, err)
+
+	wasm, err := wasmtime.Wat2Wasm(string(wat))
+	require.NoError(t, err)
+
+	report, err := checker.FloatsPolicy().CheckCode(wasm)
+	require.NoError(t, err)
+	require.True(t, report.Passed(), report.String())
+
+	report, err = checker.DefaultPolicy().CheckCode(wasm)
+	require.NoError(t, err)
+	require.False(t, report.Passed())
+}
+
+func TestFloatDeterminismAcrossOptLevels(t *testing.T) {
+	var expected []uint64
+	for _, level := range []wasmtime.OptLevel{wasmtime.OptLevelNone, wasmtime.OptLevelSpeed, wasmtime.OptLevelSpeedAndSize} {
+		config := NewEngineConfig()
+		config.SetCraneliftOptLevel(level)
+
+		results := runFloats(t, config)
+		if expected == nil {
+			expected = results
+			continue
+		}
+		require.Equal(t, expected, results, "optimization level %d", level)
+	}
+
+	// Every NaN is canonical whatever its operands
+	require.Len(t, expected, 12)
+	require.Equal(t, uint64(canonicalNaN64), expected[0])
+	require.Equal(t, uint64(canonicalNaN64), expected[1])
+	require.Equal(t, uint64(canonicalNaN32), expected[2])
+	require.Equal(t, uint64(canonicalNaN64), expected[3])
+	require.Equal(t, uint64(canonicalNaN32), expected[4])
+	require.Equal(t, uint64(canonicalNaN64), expected[5])
+	require.Equal(t, uint64(canonicalNaN64), expected[6])
+
+	// Other results follow IEEE 754, operands are variables since constant
+	// expressions are evaluated exactly by Go
+	a, b := 0.1, 0.2
+	require.Equal(t, math.Float64bits(2), expected[7])
+	require.Equal(t, math.Float64bits(a+b), expected[8])
+	require.Equal(t, uint64(math.Float32bits(float32(math.Sqrt(2)))), expected[9])
+	require.Equal(t, uint64(0), expected[11])
+
+	var harmonic float64
+	for i := 1; i <= 1000; i++ {
+		harmonic += 1 / float64(i)
+	}
+	require.Equal(t, math.Float64bits(harmonic), expected[10])
+}
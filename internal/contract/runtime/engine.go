@@ -0,0 +1,42 @@
+package runtime
+
+import "github.com/bytecodealliance/wasmtime-go/v31"
+
+// MaxWasmStack is the size in bytes of the native stack available to contracts
+const MaxWasmStack = 4 << 20
+
+// NewEngineConfig returns the engine configuration contracts must be executed
+// with so that every node computes the same results and gas
+func NewEngineConfig() *wasmtime.Config {
+	config := wasmtime.NewConfig()
+	config.SetConsumeFuel(true)
+
+	// Float operations producing NaN return the canonical NaN instead of a
+	// payload depending on the host CPU, it does not affect float-free code
+	config.SetCraneliftNanCanonicalization(true)
+
+	// Relaxed SIMD results depend on the host CPU unless deterministic
+	config.SetWasmRelaxedSIMD(false)
+	config.SetWasmRelaxedSIMDDeterministic(true)
+
+	config.SetWasmThreads(false)
+
+	// The native stack is fixed rather than depending on the host, recursion is
+	// limited deterministically by the stack height limit of deployed code
+	// well before it is exhausted
+	config.SetMaxWasmStack(MaxWasmStack)
+	return config
+}
+
+// NewEngine creates an engine with the configuration of NewEngineConfig
+func NewEngine() *wasmtime.Engine {
+	return wasmtime.NewEngineWithConfig(NewEngineConfig())
+}
+
+// NewMeteredEngine creates an engine for code instrumented with gas metering,
+// fuel is disabled since the code charges its gas itself
+func NewMeteredEngine() *wasmtime.Engine {
+	config := NewEngineConfig()
+	config.SetConsumeFuel(false)
+	return wasmtime.NewEngineWithConfig(config)
+}
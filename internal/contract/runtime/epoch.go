@@ -0,0 +1,72 @@
+package runtime
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bytecodealliance/wasmtime-go/v31"
+)
+
+// EpochInterval is the period at which EpochTicker increments the engine epoch,
+// it is the precision of execution deadlines
+const EpochInterval = 10 * time.Millisecond
+
+// noDeadlineTicks is the epoch deadline of executions without a deadline
+const noDeadlineTicks = uint64(1) << 32
+
+// ErrExecutionTimeout is raised when the execution is interrupted at its deadline
+var ErrExecutionTimeout = errors.New("execution timeout")
+
+// NewInterruptibleEngine creates an engine whose executions can be interrupted
+// at a deadline, its epoch must be incremented by an EpochTicker.
+// It must not be used for consensus execution since whether a deadline is
+// reached depends on the speed of the host.
+func NewInterruptibleEngine() *wasmtime.Engine {
+	config := NewEngineConfig()
+	config.SetEpochInterruption(true)
+	return wasmtime.NewEngineWithConfig(config)
+}
+
+// EpochTicker increments the epoch of an engine every EpochInterval in the
+// background until it is stopped
+type EpochTicker struct {
+	done chan struct{}
+	once sync.Once
+}
+
+func NewEpochTicker(engine *wasmtime.Engine) *EpochTicker {
+	ticker := &EpochTicker{done: make(chan struct{})}
+
+	go func() {
+		t := time.NewTicker(EpochInterval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-t.C:
+				engine.IncrementEpoch()
+			case <-ticker.done:
+				return
+			}
+		}
+	}()
+
+	return ticker
+}
+
+// Stop stops incrementing the epoch, executions with a deadline are no longer
+// interrupted
+func (t *EpochTicker) Stop() {
+	t.once.Do(func() { close(t.done) })
+}
+
+// deadlineTicks returns the number of epoch increments until the deadline,
+// at least one so that an expired deadline interrupts at the next tick
+func deadlineTicks(deadline time.Time) uint64 {
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return 1
+	}
+	return uint64((remaining + EpochInterval - 1) / EpochInterval)
+}
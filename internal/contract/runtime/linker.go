@@ -3,59 +3,128 @@ package runtime
 import (
 	"crypto/sha256"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"math"
+	"reflect"
 
 	"github.com/btcsuite/btcutil/base58"
 	"github.com/bytecodealliance/wasmtime-go/v31"
 
+	checker "github.com/dadamu/contract-wasmvm/internal/code-checker"
+	"github.com/dadamu/contract-wasmvm/internal/contract/gas"
 	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+	metering "github.com/dadamu/contract-wasmvm/internal/gas-metering"
 )
 
+type hostFunction struct {
+	module string
+	name   string
+	fn     any
+}
+
+// hostFunctions lists the functions provided to the WASM code
+func (e *Runtime) hostFunctions() []hostFunction {
+	return []hostFunction{
+		{"runtime", "db.save", e.saveEntry()},
+		{"runtime", "db.load", e.loadEntry()},
+		{"runtime", "contract.call", e.callEntry()},
+		{"runtime", "contract.create", e.createContractEntry()},
+		{"runtime", "event.emit", e.emitEventEntry()},
+		{"runtime", "event.emit_attrs", e.emitEventAttrsEntry()},
+		{"runtime", "bank.balance", e.balanceEntry()},
+		{"runtime", "bank.transfer", e.transferEntry()},
+		{"env", "abort", e.abortEntry()},
+	}
+}
+
 func (e *Runtime) prepareLinker() *wasmtime.Linker {
 	linker := wasmtime.NewLinker(e.engine)
 
-	// Add db.save function
-	if err := linker.DefineFunc(e.store, "runtime", "db.save", e.saveEntry()); err != nil {
-		panic(err)
+	for _, function := range e.hostFunctions() {
+		if err := linker.DefineFunc(e.store, function.module, function.name, function.fn); err != nil {
+			panic(err)
+		}
 	}
 
-	// Add db.load function
-	if err := linker.DefineFunc(e.store, "runtime", "db.load", e.loadEntry()); err != nil {
+	// The gas and stack overflow functions are only imported by the
+	// instrumentation passes, they are not part of the host functions contracts
+	// may import
+	if err := linker.DefineFunc(e.store, metering.GasModule, metering.GasFunction, e.gasEntry()); err != nil {
 		panic(err)
 	}
-
-	// Add contract.call function
-	if err := linker.DefineFunc(e.store, "runtime", "contract.call", e.callEntry()); err != nil {
+	if err := linker.DefineFunc(e.store, metering.StackModule, metering.StackOverflowFunction, e.stackOverflowEntry()); err != nil {
 		panic(err)
 	}
 
-	// Add contract.create function
-	if err := linker.DefineFunc(e.store, "runtime", "contract.create", e.createContractEntry()); err != nil {
-		panic(err)
+	return linker
+}
+
+// HostFunctions returns the signatures of the functions provided by the linker,
+// so that imports can be validated before the code is stored
+func HostFunctions() []checker.HostFunction {
+	var e Runtime
+	hostFunctions := e.hostFunctions()
+
+	functions := make([]checker.HostFunction, 0, len(hostFunctions))
+	for _, function := range hostFunctions {
+		functions = append(functions, checker.HostFunction{
+			Module: function.module,
+			Name:   function.name,
+			Type:   funcTypeOf(function.fn),
+		})
 	}
+	return functions
+}
 
-	// Add event.emit function
-	if err := linker.DefineFunc(e.store, "runtime", "event.emit", e.emitEventEntry()); err != nil {
-		panic(err)
+// funcTypeOf derives the WASM signature of a host function the same way the
+// linker does, the leading caller parameter is not part of the signature
+func funcTypeOf(fn any) checker.FuncType {
+	fnType := reflect.TypeOf(fn)
+
+	var funcType checker.FuncType
+	for i := 0; i < fnType.NumIn(); i++ {
+		if fnType.In(i) == reflect.TypeOf(&wasmtime.Caller{}) {
+			continue
+		}
+		funcType.Params = append(funcType.Params, valTypeOf(fnType.In(i)))
 	}
 
-	// Add env.abort function
-	if err := linker.DefineFunc(e.store, "env", "abort", e.abortEntry()); err != nil {
-		panic(err)
+	for i := 0; i < fnType.NumOut(); i++ {
+		funcType.Results = append(funcType.Results, valTypeOf(fnType.Out(i)))
 	}
+	return funcType
+}
 
-	return linker
+func valTypeOf(t reflect.Type) checker.ValType {
+	switch t.Kind() {
+	case reflect.Int32:
+		return checker.ValTypeI32
+	case reflect.Int64:
+		return checker.ValTypeI64
+	case reflect.Float32:
+		return checker.ValTypeF32
+	case reflect.Float64:
+		return checker.ValTypeF64
+	}
+	panic(fmt.Sprintf("unsupported host function type %s", t))
 }
 
 // `db.save` function that will be called from the WASM code
 func (e *Runtime) saveEntry() func(caller *wasmtime.Caller, idPtr int32, dataPtr int32) {
 	return func(caller *wasmtime.Caller, idPtr int32, dataPtr int32) {
-		// TODO: consume fuel
-
 		// Read the id string
 		id := readUTF16EncodedString(readBytes(caller, idPtr))
 
 		var dataBz = readBytes(caller, dataPtr)
+		cost := e.gasConfig.StorageWriteCost(len(id) + len(dataBz))
+		e.consumeGas(gas.CategoryStorageWrite, cost)
+
+		if e.tracer != nil {
+			e.tracer.OnHostCall(e.contractId, "db.save", []any{id, dataBz}, cost)
+			e.tracer.OnStorageWrite(e.contractId, id, dataBz)
+		}
+
 		e.repository.SaveEntity(e.contractId, id, dataBz)
 	}
 }
@@ -63,12 +132,18 @@ func (e *Runtime) saveEntry() func(caller *wasmtime.Caller, idPtr int32, dataPtr
 // `db.load` function that will be called from the WASM code
 func (e *Runtime) loadEntry() func(caller *wasmtime.Caller, idPtr int32) int32 {
 	return func(caller *wasmtime.Caller, idPtr int32) int32 {
-		// TODO: consume fuel
-
 		// Read the id string
 		id := readUTF16EncodedString(readBytes(caller, idPtr))
 
 		loaded := e.repository.LoadEntity(e.contractId, id)
+		cost := e.gasConfig.StorageReadCost(len(id) + len(loaded))
+		e.consumeGas(gas.CategoryStorageRead, cost)
+
+		if e.tracer != nil {
+			e.tracer.OnHostCall(e.contractId, "db.load", []any{id}, cost)
+			e.tracer.OnStorageRead(e.contractId, id, loaded)
+		}
+
 		return writeBytes(caller, loaded)
 	}
 }
@@ -83,6 +158,10 @@ func (e *Runtime) callEntry() func(caller *wasmtime.Caller, contractIdPtr int32,
 		method := readUTF16EncodedString(readBytes(caller, methodPtr))
 		args := readBytes(caller, argsPtr)
 
+		if e.tracer != nil {
+			e.tracer.OnHostCall(e.contractId, "contract.call", []any{contractId, method, args}, 0)
+		}
+
 		// Call the contract method with the arguments
 		e.callbackQueue.Enqueue(
 			interfaces.NewContractMessage(
@@ -97,7 +176,8 @@ func (e *Runtime) callEntry() func(caller *wasmtime.Caller, contractIdPtr int32,
 
 func (e *Runtime) createContractEntry() func(caller *wasmtime.Caller, codeId int64, initArgsPtr int32) int32 {
 	return func(caller *wasmtime.Caller, codeId int64, initArgsPtr int32) int32 {
-		// TODO: consume fuel
+		cost := e.gasConfig.StorageWriteCost(0)
+		e.consumeGas(gas.CategoryStorageWrite, cost)
 
 		// Get the total contract amount as salt
 		amount := e.repository.GetTotalContractAmount()
@@ -112,6 +192,10 @@ func (e *Runtime) createContractEntry() func(caller *wasmtime.Caller, codeId int
 
 		initArgs := readBytes(caller, initArgsPtr)
 
+		if e.tracer != nil {
+			e.tracer.OnHostCall(e.contractId, "contract.create", []any{codeId, initArgs}, cost)
+		}
+
 		e.callbackQueue.Enqueue(
 			interfaces.NewContractMessage(
 				contractId,
@@ -127,27 +211,139 @@ func (e *Runtime) createContractEntry() func(caller *wasmtime.Caller, codeId int
 	}
 }
 
+// `event.emit` function that will be called from the WASM code,
+// the data string is recorded as the single "data" attribute of the event
 func (e *Runtime) emitEventEntry() func(caller *wasmtime.Caller, eventPtr int32, dataPtr int32) {
 	return func(caller *wasmtime.Caller, eventPtr int32, dataPtr int32) {
-		// TODO: Consume fuel
-
 		event := readUTF16EncodedString(readBytes(caller, eventPtr))
 		data := readUTF16EncodedString(readBytes(caller, dataPtr))
 
-		*e.resultEvents = append(*e.resultEvents, interfaces.ResultEvent{
-			ContractId: e.contractId,
-			Event:      event,
-			Data:       data,
+		e.emitEvent("event.emit", event, []interfaces.EventAttribute{
+			interfaces.NewEventAttribute("data", []byte(data)),
 		})
 	}
 }
 
+// `event.emit_attrs` function that will be called from the WASM code
+func (e *Runtime) emitEventAttrsEntry() func(caller *wasmtime.Caller, eventPtr int32, attrsPtr int32) {
+	return func(caller *wasmtime.Caller, eventPtr int32, attrsPtr int32) {
+		event := readUTF16EncodedString(readBytes(caller, eventPtr))
+
+		attributes, err := decodeEventAttributes(readBytes(caller, attrsPtr))
+		if err != nil {
+			panic(err)
+		}
+
+		// The limits only apply to the events with attributes, event.emit
+		// keeps accepting any name and data for the existing contracts
+		if err := (interfaces.ResultEvent{Event: event, Attributes: attributes}).Validate(); err != nil {
+			panic(err)
+		}
+
+		e.emitEvent("event.emit_attrs", event, attributes)
+	}
+}
+
+func (e *Runtime) emitEvent(hostFunction string, event string, attributes []interfaces.EventAttribute) {
+	resultEvent := interfaces.ResultEvent{
+		ContractId: e.contractId,
+		Event:      event,
+		Attributes: attributes,
+		Depth:      e.callbackQueue.Depth(),
+	}
+
+	size := len(event)
+	for _, attribute := range attributes {
+		size += len(attribute.Key) + len(attribute.Value)
+	}
+	cost := e.gasConfig.EventCost(size)
+	e.consumeGas(gas.CategoryEvents, cost)
+
+	if e.tracer != nil {
+		e.tracer.OnHostCall(e.contractId, hostFunction, []any{event, attributes}, cost)
+		e.tracer.OnEvent(resultEvent)
+	}
+
+	*e.resultEvents = append(*e.resultEvents, resultEvent)
+}
+
+// `bank.balance` function that will be called from the WASM code
+func (e *Runtime) balanceEntry() func(caller *wasmtime.Caller, addressPtr int32, denomPtr int32) int64 {
+	return func(caller *wasmtime.Caller, addressPtr int32, denomPtr int32) int64 {
+		cost := e.gasConfig.StorageReadCost(0)
+		e.consumeGas(gas.CategoryStorageRead, cost)
+
+		address := readUTF16EncodedString(readBytes(caller, addressPtr))
+		denom := readUTF16EncodedString(readBytes(caller, denomPtr))
+
+		if e.tracer != nil {
+			e.tracer.OnHostCall(e.contractId, "bank.balance", []any{address, denom}, cost)
+		}
+
+		// The amounts are signed for the contracts, as for bank.transfer, so the
+		// balances above the largest amount are saturated instead of wrapping
+		balance := e.repository.GetBalance(address, denom)
+		return int64(min(balance, math.MaxInt64))
+	}
+}
+
+// ErrInvalidAmount is raised when a contract transfers a non-positive amount
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// `bank.transfer` function that will be called from the WASM code
+func (e *Runtime) transferEntry() func(caller *wasmtime.Caller, toPtr int32, denomPtr int32, amount int64) {
+	return func(caller *wasmtime.Caller, toPtr int32, denomPtr int32, amount int64) {
+		cost := e.gasConfig.StorageWriteCost(0)
+		e.consumeGas(gas.CategoryStorageWrite, cost)
+
+		to := readUTF16EncodedString(readBytes(caller, toPtr))
+		denom := readUTF16EncodedString(readBytes(caller, denomPtr))
+
+		if e.tracer != nil {
+			e.tracer.OnHostCall(e.contractId, "bank.transfer", []any{to, denom, amount}, cost)
+		}
+
+		// The amount is signed in the ABI, a negative one would wrap around
+		if amount <= 0 {
+			panic(fmt.Errorf("%w: %d %s", ErrInvalidAmount, amount, denom))
+		}
+
+		// Transfer the coins from the contract itself to the recipient
+		err := e.repository.TransferCoins(
+			e.contractId,
+			to,
+			[]interfaces.Coin{interfaces.NewCoin(denom, uint64(amount))},
+		)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// `runtime.gas` function called by instrumented code with the cost of each block
+func (e *Runtime) gasEntry() func(amount int64) {
+	return func(amount int64) {
+		e.consumeGas(gas.CategoryCompute, uint64(amount))
+	}
+}
+
+// `runtime.stack_overflow` function called by code exceeding its stack height limit
+func (e *Runtime) stackOverflowEntry() func() {
+	return func() {
+		panic(ErrStackOverflow)
+	}
+}
+
 // `env.abort` function that will be called from the WASM code
 func (e *Runtime) abortEntry() func(caller *wasmtime.Caller, arg1, arg2, arg3, arg4 int32) {
 	return func(caller *wasmtime.Caller, msgPtr, filePtr, line, column int32) {
 		msg := readUTF16EncodedString(readBytes(caller, msgPtr))
 		file := readUTF16EncodedString(readBytes(caller, filePtr))
 
+		if e.tracer != nil {
+			e.tracer.OnHostCall(e.contractId, "abort", []any{msg, file, line, column}, 0)
+		}
+
 		panic(fmt.Errorf("WASM called abort msg: %s, file: %s, line: %d, column: %d", msg, file, line, column))
 	}
 }
@@ -182,6 +378,57 @@ func writeBytes(caller *wasmtime.Caller, data []byte) int32 {
 	return offset
 }
 
+// decodeEventAttributes decodes the attributes passed to `event.emit_attrs`.
+// The layout is a little-endian u32 attribute count followed by, for each
+// attribute, a u32 key length, the UTF-8 key, a u32 value length and the value.
+func decodeEventAttributes(bz []byte) ([]interfaces.EventAttribute, error) {
+	readChunk := func() ([]byte, error) {
+		if len(bz) < 4 {
+			return nil, fmt.Errorf("invalid event attributes: unexpected end of data")
+		}
+		length := binary.LittleEndian.Uint32(bz[:4])
+		bz = bz[4:]
+
+		if uint64(len(bz)) < uint64(length) {
+			return nil, fmt.Errorf("invalid event attributes: unexpected end of data")
+		}
+		chunk := bz[:length]
+		bz = bz[length:]
+		return chunk, nil
+	}
+
+	if len(bz) < 4 {
+		return nil, fmt.Errorf("invalid event attributes: missing attribute count")
+	}
+	count := binary.LittleEndian.Uint32(bz[:4])
+	bz = bz[4:]
+
+	if count > interfaces.MaxEventAttributes {
+		return nil, fmt.Errorf("too many event attributes: %d > %d", count, interfaces.MaxEventAttributes)
+	}
+
+	attributes := make([]interfaces.EventAttribute, 0, count)
+	for i := uint32(0); i < count; i++ {
+		key, err := readChunk()
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := readChunk()
+		if err != nil {
+			return nil, err
+		}
+
+		attributes = append(attributes, interfaces.NewEventAttribute(string(key), value))
+	}
+
+	if len(bz) != 0 {
+		return nil, fmt.Errorf("invalid event attributes: %d trailing bytes", len(bz))
+	}
+
+	return attributes, nil
+}
+
 func readUTF16EncodedString(bz []byte) string {
 	var decoded []byte
 	for i := 0; i < len(bz); i += 2 {
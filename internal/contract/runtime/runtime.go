@@ -1,14 +1,31 @@
 package runtime
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"time"
 
 	"github.com/bytecodealliance/wasmtime-go/v31"
 
 	callbackqueue "github.com/dadamu/contract-wasmvm/internal/contract/callback-queue"
+	"github.com/dadamu/contract-wasmvm/internal/contract/gas"
 	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+	metering "github.com/dadamu/contract-wasmvm/internal/gas-metering"
 )
 
+// StartFunction is the export called once after instantiation, it is produced
+// by building AssemblyScript contracts with `--exportStart _start`
+const StartFunction = "_start"
+
+// ErrStackOverflow is raised when the contract exceeds the stack height limit
+// of its code or, as a fallback, the stack size of the engine
+var ErrStackOverflow = errors.New("stack overflow")
+
+// ErrUnmeteredCode is raised when code without gas metering runs on an engine
+// which does not consume fuel, it would run without a gas limit
+var ErrUnmeteredCode = errors.New("code is not metered")
+
 type Runtime struct {
 	callbackQueue *callbackqueue.CallbackQueue
 	resultEvents  *[]interfaces.ResultEvent
@@ -20,6 +37,31 @@ type Runtime struct {
 	state      []byte
 	contractId string
 	repository interfaces.IContractRepository
+
+	gasConfig gas.Config
+	gasUsage  *gas.Usage
+
+	// metered is set for code instrumented with gas metering, which charges
+	// gasRemaining through the gas host function instead of the store fuel
+	metered      bool
+	gasRemaining uint64
+
+	// tracer is nil unless tracing is enabled
+	tracer interfaces.Tracer
+
+	// deadline is zero unless the execution is interrupted at a deadline
+	deadline time.Time
+}
+
+type Option func(*Runtime)
+
+// WithDeadline interrupts the instantiation and execution with
+// ErrExecutionTimeout at the deadline, it requires an engine created by
+// NewInterruptibleEngine whose epoch is incremented by an EpochTicker
+func WithDeadline(deadline time.Time) Option {
+	return func(e *Runtime) {
+		e.deadline = deadline
+	}
 }
 
 func NewRuntimeFromModule(
@@ -32,7 +74,9 @@ func NewRuntimeFromModule(
 	state []byte,
 	contractId string,
 	gasLimit uint64,
-) *Runtime {
+	gasConfig gas.Config,
+	opts ...Option,
+) (*Runtime, error) {
 	runtime := &Runtime{
 		engine: engine,
 
@@ -42,38 +86,149 @@ func NewRuntimeFromModule(
 
 		state:      state,
 		contractId: contractId,
+
+		gasConfig: gasConfig,
+		gasUsage:  gas.NewUsage(),
+	}
+
+	for _, opt := range opts {
+		opt(runtime)
 	}
 
-	instance := runtime.newInstanceFromModule(module, gasLimit)
-	if instance == nil {
-		panic("failed to create instance")
+	// The runtime is returned with the error so the gas consumed by the start
+	// function is still available
+	instance, err := runtime.newInstanceFromModule(module, gasLimit)
+	if err != nil {
+		return runtime, fmt.Errorf("failed to instantiate contract: %w", err)
 	}
 
 	runtime.instance = instance
-	return runtime
+	return runtime, nil
 }
 
-func (e *Runtime) newInstanceFromModule(module *wasmtime.Module, gasLimit uint64) *wasmtime.Instance {
+func (e *Runtime) newInstanceFromModule(module *wasmtime.Module, gasLimit uint64) (instance *wasmtime.Instance, err error) {
 	// Create a new isolated store for the contract runtime
 	store := wasmtime.NewStore(e.engine)
-	store.SetFuel(gasLimit)
 	e.store = store
+
+	// The deadline only matters for engines with epoch interruption enabled
+	if e.deadline.IsZero() {
+		store.SetEpochDeadline(noDeadlineTicks)
+	} else {
+		store.SetEpochDeadline(deadlineTicks(e.deadline))
+	}
+
+	e.metered = isInstrumented(module)
+	if e.metered {
+		// The fuel is left unlimited if the engine consumes fuel anyway
+		e.gasRemaining = gasLimit
+		store.SetFuel(math.MaxUint64)
+	} else if err := store.SetFuel(gasLimit); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnmeteredCode, err)
+	}
 	linker := e.prepareLinker()
 
-	instance, err := linker.Instantiate(store, module)
+	defer func() {
+		// The host functions called by the start function, such as the gas
+		// host function, raise their errors as panics
+		if r := recover(); r != nil {
+			instance, err = nil, recoveredError(r)
+		}
+
+		// Record the fuel consumed by the start function of the module, even
+		// if it trapped
+		if remaining, ok := e.remainingGas(); ok && !e.metered {
+			e.gasUsage.Add(gas.CategoryInstantiation, gasLimit-remaining)
+		}
+	}()
+
+	instance, err = linker.Instantiate(store, module)
 	if err != nil {
-		panic(err)
+		return nil, trapError(err)
+	}
+
+	// Contracts initialize their globals in an exported start function since
+	// start sections are rejected at deploy time
+	if start := instance.GetFunc(store, StartFunction); start != nil {
+		if _, err := start.Call(store); err != nil {
+			return nil, trapError(err)
+		}
 	}
-	return instance
+	return instance, nil
+}
+
+// recoveredError converts a recovered panic into an error, the errors of the
+// store are raised again since they are not caused by the contract
+func recoveredError(r any) error {
+	if _, ok := r.(interfaces.StoreError); ok {
+		panic(r)
+	}
+
+	if err, ok := r.(error); ok {
+		return fmt.Errorf("panic: %w", err)
+	}
+	return fmt.Errorf("panic: %v", r)
+}
+
+// trapError wraps the traps with a dedicated error, such as a stack overflow
+// or an interruption at the deadline
+func trapError(err error) error {
+	var trap *wasmtime.Trap
+	if errors.As(err, &trap) && trap.Code() != nil {
+		switch *trap.Code() {
+		case wasmtime.StackOverflow:
+			return fmt.Errorf("%w: %w", ErrStackOverflow, err)
+		case wasmtime.Interrupt:
+			return fmt.Errorf("%w: %w", ErrExecutionTimeout, err)
+		}
+	}
+	return err
+}
+
+// isInstrumented reports whether the module charges its own gas by calling the
+// gas host function
+func isInstrumented(module *wasmtime.Module) bool {
+	for _, imp := range module.Imports() {
+		if imp.Module() == metering.GasModule && imp.Name() != nil && *imp.Name() == metering.GasFunction {
+			return true
+		}
+	}
+	return false
+}
+
+// SetTracer enables tracing of the host calls, storage access and traps
+func (e *Runtime) SetTracer(tracer interfaces.Tracer) {
+	e.tracer = tracer
+}
+
+// GasUsage returns the gas consumed by the runtime so far, it is available
+// even if the execution failed.
+func (e *Runtime) GasUsage() *gas.Usage {
+	return e.gasUsage
 }
 
 func (e *Runtime) Run(msg interfaces.ContractMessage) (remainingGas uint64, err error) {
+	startFuel, fuelEnabled := e.remainingGas()
+	hostGas := e.gasUsage.Total
 	defer func() {
 		if r := recover(); r != nil {
-			err = fmt.Errorf("panic: %v", r)
+			err = recoveredError(r)
+		}
+
+		// Attribute the fuel not charged by host functions to the WASM instructions,
+		// metered code charges them through the gas host function instead
+		if fuelEnabled && !e.metered {
+			endFuel, _ := e.remainingGas()
+			e.gasUsage.Add(gas.CategoryCompute, startFuel-endFuel-(e.gasUsage.Total-hostGas))
+		}
+
+		if err != nil && e.tracer != nil {
+			e.tracer.OnTrap(e.contractId, err)
 		}
 	}()
 
+	e.consumeGas(gas.CategoryInstantiation, e.gasConfig.InstantiationCost)
+
 	run := e.instance.GetFunc(e.store, msg.Method)
 	if run == nil {
 		return 0, fmt.Errorf("function %s not found in instance", msg.Method)
@@ -86,12 +241,12 @@ func (e *Runtime) Run(msg interfaces.ContractMessage) (remainingGas uint64, err
 	// Call the run function with the pointer to the golobal state and args
 	_, err = run.Call(e.store, statePtr, senderPtr, argsPtr)
 	if err != nil {
-		return 0, err
+		return 0, trapError(err)
 	}
 
-	// Get the remaining fuel
-	// Ignore error that fuel is not configured for the store
-	remainingGas, _ = e.store.GetFuel()
+	// Get the remaining gas
+	// Ignore that fuel is not configured for the store
+	remainingGas, _ = e.remainingGas()
 
 	return remainingGas, nil
 }
@@ -111,3 +266,41 @@ func (e *Runtime) writeBytesByInstance(message []byte) int32 {
 	copy(memory[offset:offset+int32(len(message))], message)
 	return offset
 }
+
+// remainingGas returns the gas left for the execution, it is false if neither
+// fuel nor gas metering is enabled
+func (e *Runtime) remainingGas() (uint64, bool) {
+	if e.metered {
+		return e.gasRemaining, true
+	}
+
+	fuel, err := e.store.GetFuel()
+	return fuel, err == nil
+}
+
+func (e *Runtime) setRemainingGas(amount uint64) {
+	if e.metered {
+		e.gasRemaining = amount
+		return
+	}
+	e.store.SetFuel(amount)
+}
+
+// consumeGas charges the amount from the remaining gas and records it in the category,
+// it panics with gas.ErrOutOfGas if the remaining gas is not enough.
+func (e *Runtime) consumeGas(category gas.Category, amount uint64) {
+	remaining, ok := e.remainingGas()
+	if !ok {
+		// Fuel is not configured for the store, nothing to charge
+		return
+	}
+
+	if remaining < amount {
+		e.setRemainingGas(0)
+		e.gasUsage.Add(category, remaining)
+		panic(gas.ErrOutOfGas)
+	}
+
+	e.setRemainingGas(remaining - amount)
+	e.gasUsage.Add(category, amount)
+}
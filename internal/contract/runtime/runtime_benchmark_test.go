@@ -8,6 +8,7 @@ import (
 
 	"github.com/bytecodealliance/wasmtime-go/v31"
 	callbackqueue "github.com/dadamu/contract-wasmvm/internal/contract/callback-queue"
+	"github.com/dadamu/contract-wasmvm/internal/contract/gas"
 	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
 	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces/testutil"
 )
@@ -30,7 +31,7 @@ func BenchmarkRuntimeAddOne(b *testing.B) {
 	}
 
 	repository := testutil.NewMockIContractRepository(ctrl)
-	runtime := NewRuntimeFromModule(
+	runtime, err := NewRuntimeFromModule(
 		engine,
 		callbackqueue.NewCallbackQueue(),
 		&[]interfaces.ResultEvent{},
@@ -40,7 +41,11 @@ func BenchmarkRuntimeAddOne(b *testing.B) {
 		[]byte("state"),
 		"contractId",
 		100_000,
+		gas.DefaultConfig(),
 	)
+	if err != nil {
+		b.Fatalf("failed to create runtime: %v", err)
+	}
 
 	// Setup the mock repository
 	loadedValue := []byte{1, 0, 0, 0}
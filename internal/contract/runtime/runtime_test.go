@@ -2,20 +2,27 @@ package runtime
 
 import (
 	"encoding/binary"
+	"fmt"
+	"math"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/bytecodealliance/wasmtime-go/v31"
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/mock/gomock"
 
+	checker "github.com/dadamu/contract-wasmvm/internal/code-checker"
 	callbackqueue "github.com/dadamu/contract-wasmvm/internal/contract/callback-queue"
+	"github.com/dadamu/contract-wasmvm/internal/contract/gas"
 	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
 	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces/testutil"
+	metering "github.com/dadamu/contract-wasmvm/internal/gas-metering"
 )
 
 type RuntimeTestSuite struct {
 	suite.Suite
+	engine     *wasmtime.Engine
 	queue      *callbackqueue.CallbackQueue
 	events     *[]interfaces.ResultEvent
 	repository *testutil.MockIContractRepository
@@ -37,14 +44,22 @@ func (suite *RuntimeTestSuite) SetupTest() {
 
 	config := wasmtime.NewConfig()
 	config.SetConsumeFuel(true)
-	engine := wasmtime.NewEngineWithConfig(config)
-	module, err := wasmtime.NewModule(engine, wasmFile)
+	suite.engine = wasmtime.NewEngineWithConfig(config)
+	suite.runtime = suite.newRuntime(wasmFile)
+}
+
+func (suite *RuntimeTestSuite) newRuntime(wasm []byte) *Runtime {
+	return suite.newRuntimeWithConfig(wasm, gas.DefaultConfig())
+}
+
+func (suite *RuntimeTestSuite) newRuntimeWithConfig(wasm []byte, gasConfig gas.Config) *Runtime {
+	module, err := wasmtime.NewModule(suite.engine, wasm)
 	if err != nil {
 		suite.T().Fatalf("failed to create module: %v", err)
 	}
 
-	suite.runtime = NewRuntimeFromModule(
-		engine,
+	runtime, err := NewRuntimeFromModule(
+		suite.engine,
 		suite.queue,
 		suite.events,
 		suite.repository,
@@ -53,7 +68,37 @@ func (suite *RuntimeTestSuite) SetupTest() {
 		[]byte("state"),
 		"contractId",
 		20_000,
+		gasConfig,
 	)
+	if err != nil {
+		suite.T().Fatalf("failed to create runtime: %v", err)
+	}
+	return runtime
+}
+
+// newPricedRuntime creates a runtime from testdata/test.wasm charging the host
+// functions with gas.PricedConfig
+func (suite *RuntimeTestSuite) newPricedRuntime() *Runtime {
+	wasm, err := os.ReadFile("testdata/test.wasm")
+	if err != nil {
+		suite.T().Fatalf("failed to read module: %v", err)
+	}
+	return suite.newRuntimeWithConfig(wasm, gas.PricedConfig())
+}
+
+// newHostRuntime creates a runtime from the hand-written testdata/host.wat module
+func (suite *RuntimeTestSuite) newHostRuntime() *Runtime {
+	wat, err := os.ReadFile("testdata/host.wat")
+	if err != nil {
+		suite.T().Fatalf("failed to read module: %v", err)
+	}
+
+	wasm, err := wasmtime.Wat2Wasm(string(wat))
+	if err != nil {
+		suite.T().Fatalf("failed to compile module: %v", err)
+	}
+
+	return suite.newRuntime(wasm)
 }
 
 func TestRuntimeTestSuite(t *testing.T) {
@@ -93,6 +138,59 @@ func (s *RuntimeTestSuite) TestGasRemaining() {
 	s.Require().Equal(uint64(8_296), remaining)
 }
 
+func (s *RuntimeTestSuite) TestGasRemainingPriced() {
+	s.runtime = s.newPricedRuntime()
+
+	loadedValue := []byte{1, 0, 0, 0}
+	savedValue := []byte{2, 0, 0, 0}
+	s.repository.EXPECT().LoadEntity("contractId", "test").Return(loadedValue)
+	s.repository.EXPECT().SaveEntity("contractId", "test", savedValue)
+
+	// The host functions are charged on top of the fuel, 8_296 gas remain
+	// with the default config
+	remaining, err := s.runtime.Run(interfaces.NewContractMessage("contractId", "addOne", []byte{}, "sender"))
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(4_032), remaining)
+}
+
+func (s *RuntimeTestSuite) TestGasUsage() {
+	s.runtime = s.newPricedRuntime()
+
+	// Setup the mock repository
+	loadedValue := []byte{1, 0, 0, 0} // Initial value: 1
+	savedValue := []byte{2, 0, 0, 0}  // Expected value after increment: 2
+
+	s.repository.EXPECT().LoadEntity("contractId", "test").Return(loadedValue)
+	s.repository.EXPECT().SaveEntity("contractId", "test", savedValue)
+
+	remaining, err := s.runtime.Run(interfaces.NewContractMessage("contractId", "addOne", []byte{}, "sender"))
+	s.Require().NoError(err)
+
+	config := gas.PricedConfig()
+	usage := s.runtime.GasUsage()
+	s.Require().Equal(uint64(20_000)-remaining, usage.Total)
+	s.Require().Equal(config.StorageReadCost(len("test")+len(loadedValue)), usage.Categories[gas.CategoryStorageRead])
+	s.Require().Equal(config.StorageWriteCost(len("test")+len(savedValue)), usage.Categories[gas.CategoryStorageWrite])
+
+	// Instantiation includes the fuel consumed by the start function of the module
+	s.Require().GreaterOrEqual(usage.Categories[gas.CategoryInstantiation], config.InstantiationCost)
+
+	var sum uint64
+	for _, amount := range usage.Categories {
+		sum += amount
+	}
+	s.Require().Equal(usage.Total, sum)
+}
+
+func (s *RuntimeTestSuite) TestGasUsageOutOfGas() {
+	_, err := s.runtime.Run(interfaces.NewContractMessage("contractId", "infiniteLoop", []byte{}, "sender"))
+	s.Require().Error(err)
+
+	// All the gas is burned even though the execution failed
+	usage := s.runtime.GasUsage()
+	s.Require().Equal(uint64(20_000), usage.Total)
+}
+
 func (s *RuntimeTestSuite) TestCrash() {
 	_, err := s.runtime.Run(interfaces.NewContractMessage("contractId", "crash", []byte{}, "sender"))
 	s.Require().Error(err)
@@ -138,5 +236,266 @@ func (s *RuntimeTestSuite) TestEmitEvent() {
 
 	s.Require().Equal("contractId", event.ContractId)
 	s.Require().Equal("event", event.Event)
-	s.Require().Equal("data", event.Data)
+	s.Require().Equal(0, event.Depth)
+
+	data, found := event.GetAttribute("data")
+	s.Require().True(found)
+	s.Require().Equal([]byte("data"), data)
+}
+
+func (s *RuntimeTestSuite) TestEmitEventAttributes() {
+	runtime := s.newHostRuntime()
+	_, err := runtime.Run(interfaces.NewContractMessage("contractId", "emitAttrs", []byte{}, "sender"))
+	s.Require().NoError(err)
+
+	event := (*s.events)[0]
+	s.Require().Equal("contractId", event.ContractId)
+	s.Require().Equal("transfer", event.Event)
+	s.Require().Equal([]interfaces.EventAttribute{
+		interfaces.NewEventAttribute("to", []byte("alice")),
+		interfaces.NewEventAttribute("raw", []byte{0x00, 0xff, 0x10}),
+	}, event.Attributes)
+}
+
+func (s *RuntimeTestSuite) TestEmitEventTooManyAttributes() {
+	runtime := s.newHostRuntime()
+	_, err := runtime.Run(interfaces.NewContractMessage("contractId", "emitTooManyAttrs", []byte{}, "sender"))
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), "too many event attributes")
+	s.Require().Empty(*s.events)
+}
+
+func (s *RuntimeTestSuite) TestEmitEventWithoutLimits() {
+	wat, err := os.ReadFile("testdata/host.wat")
+	s.Require().NoError(err)
+	wasm, err := wasmtime.Wat2Wasm(string(wat))
+	s.Require().NoError(err)
+	module, err := wasmtime.NewModule(s.engine, wasm)
+	s.Require().NoError(err)
+
+	// Enough gas for the event data
+	runtime, err := NewRuntimeFromModule(s.engine, s.queue, s.events, s.repository, module, []byte("state"), "contractId", 1_000_000, gas.DefaultConfig())
+	s.Require().NoError(err)
+
+	// event.emit accepts the events of the existing contracts, which are
+	// above the limits of the events with attributes
+	_, err = runtime.Run(interfaces.NewContractMessage("contractId", "emitLegacy", []byte{}, "sender"))
+	s.Require().NoError(err)
+
+	event := (*s.events)[0]
+	s.Require().Empty(event.Event)
+	data, found := event.GetAttribute("data")
+	s.Require().True(found)
+	s.Require().Len(data, 5000)
+	s.Require().Greater(len(data), interfaces.MaxEventAttributeValueSize)
+}
+
+func (s *RuntimeTestSuite) TestEmitEventDepth() {
+	// Simulate the runtime executing a message called by another contract
+	s.queue.Enqueue(interfaces.NewContractMessage("caller", "method", []byte{}, "sender"))
+	s.queue.Dequeue()
+	s.queue.Enqueue(interfaces.NewContractMessage("contractId", "emitEvent", []byte{}, "caller"))
+	s.queue.Dequeue()
+
+	_, err := s.runtime.Run(interfaces.NewContractMessage("contractId", "emitEvent", []byte{}, "caller"))
+	s.Require().NoError(err)
+
+	s.Require().Equal(1, (*s.events)[0].Depth)
+}
+
+func (s *RuntimeTestSuite) TestBankBalance() {
+	balance := make([]byte, 8)
+	binary.LittleEndian.PutUint64(balance, 100)
+
+	s.repository.EXPECT().GetBalance("alice", "uatom").Return(uint64(100))
+	s.repository.EXPECT().SaveEntity("contractId", "balance", balance)
+
+	runtime := s.newHostRuntime()
+	_, err := runtime.Run(interfaces.NewContractMessage("contractId", "balance", []byte{}, "sender"))
+	s.Require().NoError(err)
+}
+
+func (s *RuntimeTestSuite) TestBankBalanceSaturated() {
+	balance := make([]byte, 8)
+	binary.LittleEndian.PutUint64(balance, math.MaxInt64)
+
+	s.repository.EXPECT().GetBalance("alice", "uatom").Return(uint64(1 << 63))
+	s.repository.EXPECT().SaveEntity("contractId", "balance", balance)
+
+	runtime := s.newHostRuntime()
+	_, err := runtime.Run(interfaces.NewContractMessage("contractId", "balance", []byte{}, "sender"))
+	s.Require().NoError(err)
+}
+
+func (s *RuntimeTestSuite) TestBankTransfer() {
+	s.repository.EXPECT().
+		TransferCoins("contractId", "alice", []interfaces.Coin{interfaces.NewCoin("uatom", 10)}).
+		Return(nil)
+
+	runtime := s.newHostRuntime()
+	_, err := runtime.Run(interfaces.NewContractMessage("contractId", "transfer", []byte{}, "sender"))
+	s.Require().NoError(err)
+}
+
+func (s *RuntimeTestSuite) TestBankTransferInsufficientBalance() {
+	s.repository.EXPECT().
+		TransferCoins("contractId", "alice", []interfaces.Coin{interfaces.NewCoin("uatom", 10)}).
+		Return(fmt.Errorf("insufficient balance of uatom: 0 < 10"))
+
+	runtime := s.newHostRuntime()
+	_, err := runtime.Run(interfaces.NewContractMessage("contractId", "transfer", []byte{}, "sender"))
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), "insufficient balance")
+}
+
+func (s *RuntimeTestSuite) TestBankTransferInvalidAmount() {
+	// The repository is not called
+	for _, method := range []string{"transferNegative", "transferZero"} {
+		runtime := s.newHostRuntime()
+		_, err := runtime.Run(interfaces.NewContractMessage("contractId", method, []byte{}, "sender"))
+		s.Require().ErrorIs(err, ErrInvalidAmount)
+	}
+}
+
+func (s *RuntimeTestSuite) TestTracer() {
+	s.runtime = s.newPricedRuntime()
+	tracer := testutil.NewMockTracer(gomock.NewController(s.T()))
+	s.runtime.SetTracer(tracer)
+
+	loadedValue := []byte{1, 0, 0, 0}
+	savedValue := []byte{2, 0, 0, 0}
+	s.repository.EXPECT().LoadEntity("contractId", "test").Return(loadedValue)
+	s.repository.EXPECT().SaveEntity("contractId", "test", savedValue)
+
+	config := gas.PricedConfig()
+	gomock.InOrder(
+		tracer.EXPECT().OnHostCall("contractId", "db.load", []any{"test"}, config.StorageReadCost(len("test")+len(loadedValue))),
+		tracer.EXPECT().OnStorageRead("contractId", "test", loadedValue),
+		tracer.EXPECT().OnHostCall("contractId", "db.save", []any{"test", savedValue}, config.StorageWriteCost(len("test")+len(savedValue))),
+		tracer.EXPECT().OnStorageWrite("contractId", "test", savedValue),
+	)
+
+	_, err := s.runtime.Run(interfaces.NewContractMessage("contractId", "addOne", []byte{}, "sender"))
+	s.Require().NoError(err)
+}
+
+func (s *RuntimeTestSuite) TestTracerTrap() {
+	tracer := testutil.NewMockTracer(gomock.NewController(s.T()))
+	s.runtime.SetTracer(tracer)
+
+	tracer.EXPECT().OnHostCall("contractId", "abort", gomock.Any(), uint64(0))
+	tracer.EXPECT().OnTrap("contractId", gomock.Any())
+
+	_, err := s.runtime.Run(interfaces.NewContractMessage("contractId", "crash", []byte{}, "sender"))
+	s.Require().Error(err)
+}
+
+func (s *RuntimeTestSuite) TestStartFunction() {
+	wasm, err := wasmtime.Wat2Wasm(`
+		(module
+		  (memory (export "memory") 1)
+		  (global $ready (mut i32) (i32.const 0))
+		  (func (export "__new") (param i32 i32) (result i32) (i32.const 1024))
+		  (func (export "_start") (global.set $ready (i32.const 1)))
+		  (func (export "check") (param i32 i32 i32)
+		    (if (i32.eqz (global.get $ready)) (then unreachable))))
+	`)
+	s.Require().NoError(err)
+
+	// The exported start function runs once at instantiation
+	_, err = s.newRuntime(wasm).Run(interfaces.NewContractMessage("contractId", "check", []byte{}, "sender"))
+	s.Require().NoError(err)
+}
+
+func (s *RuntimeTestSuite) TestHostFunctions() {
+	functions := make(map[string]checker.FuncType)
+	for _, function := range HostFunctions() {
+		functions[function.Module+"."+function.Name] = function.Type
+	}
+
+	s.Require().Len(functions, 9)
+	s.Require().Equal("(i32, i32) -> ()", functions["runtime.db.save"].String())
+	s.Require().Equal("(i64, i32) -> (i32)", functions["runtime.contract.create"].String())
+	s.Require().Equal("(i32, i32, i64) -> ()", functions["runtime.bank.transfer"].String())
+	s.Require().Equal("(i32, i32, i32, i32) -> ()", functions["env.abort"].String())
+}
+
+// recursiveContract is a contract whose "recurse" method never returns
+const recursiveContract = `
+	(module
+	  (memory (export "memory") 1)
+	  (func (export "__new") (param i32 i32) (result i32) (i32.const 1024))
+	  (func $recurse (export "recurse") (param i32 i32 i32)
+	    (call $recurse (local.get 0) (local.get 1) (local.get 2))))
+`
+
+func (s *RuntimeTestSuite) TestStackHeightLimit() {
+	wasm, err := wasmtime.Wat2Wasm(recursiveContract)
+	s.Require().NoError(err)
+
+	module, err := checker.ParseModule(wasm)
+	s.Require().NoError(err)
+
+	limited, err := metering.LimitStack(module, 64)
+	s.Require().NoError(err)
+
+	_, err = s.newRuntime(limited).Run(interfaces.NewContractMessage("contractId", "recurse", []byte{}, "sender"))
+	s.Require().ErrorIs(err, ErrStackOverflow)
+}
+
+func (s *RuntimeTestSuite) TestNativeStackOverflow() {
+	wasm, err := wasmtime.Wat2Wasm(recursiveContract)
+	s.Require().NoError(err)
+
+	module, err := wasmtime.NewModule(s.engine, wasm)
+	s.Require().NoError(err)
+
+	// Enough gas for the native stack to be exhausted first
+	runtime, err := NewRuntimeFromModule(s.engine, s.queue, s.events, s.repository, module, []byte("state"), "contractId", 100_000_000, gas.DefaultConfig())
+	s.Require().NoError(err)
+
+	_, err = runtime.Run(interfaces.NewContractMessage("contractId", "recurse", []byte{}, "sender"))
+	s.Require().ErrorIs(err, ErrStackOverflow)
+}
+
+func (s *RuntimeTestSuite) TestExecutionTimeout() {
+	engine := NewInterruptibleEngine()
+	ticker := NewEpochTicker(engine)
+	defer ticker.Stop()
+
+	wasmFile, err := os.ReadFile("testdata/test.wasm")
+	s.Require().NoError(err)
+	module, err := wasmtime.NewModule(engine, wasmFile)
+	s.Require().NoError(err)
+
+	// The gas limit is far from being reached at the deadline
+	runtime, err := NewRuntimeFromModule(
+		engine, s.queue, s.events, s.repository, module,
+		[]byte("state"), "contractId", 1<<50, gas.DefaultConfig(),
+		WithDeadline(time.Now().Add(50*time.Millisecond)),
+	)
+	s.Require().NoError(err)
+
+	start := time.Now()
+	_, err = runtime.Run(interfaces.NewContractMessage("contractId", "infiniteLoop", []byte{}, "sender"))
+	s.Require().ErrorIs(err, ErrExecutionTimeout)
+	s.Require().Less(time.Since(start), time.Second)
+}
+
+func (s *RuntimeTestSuite) TestInterruptibleEngineWithoutDeadline() {
+	engine := NewInterruptibleEngine()
+	ticker := NewEpochTicker(engine)
+	defer ticker.Stop()
+
+	wasmFile, err := os.ReadFile("testdata/test.wasm")
+	s.Require().NoError(err)
+	module, err := wasmtime.NewModule(engine, wasmFile)
+	s.Require().NoError(err)
+
+	// Without a deadline the execution runs until it is out of gas
+	runtime, err := NewRuntimeFromModule(engine, s.queue, s.events, s.repository, module, []byte("state"), "contractId", 20_000, gas.DefaultConfig())
+	s.Require().NoError(err)
+
+	_, err = runtime.Run(interfaces.NewContractMessage("contractId", "infiniteLoop", []byte{}, "sender"))
+	s.Require().ErrorContains(err, "all fuel consumed")
 }
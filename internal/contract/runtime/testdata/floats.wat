@@ -0,0 +1,86 @@
+;; Float-heavy contract saving the bits of its results to the "floats" entity,
+;; several operations produce NaN whose bits depend on the CPU unless the
+;; engine canonicalizes them.
+(module
+  (import "runtime" "db.save" (func $save (param i32 i32)))
+
+  (memory (export "memory") 1)
+  (global $heap (mut i32) (i32.const 1024))
+
+  ;; "floats"
+  (data (i32.const 16) "\0c\00\00\00f\00l\00o\00a\00t\00s\00")
+
+  (func $new (export "__new") (param $size i32) (param $id i32) (result i32)
+    (local $ptr i32)
+    (local.set $ptr (i32.add (global.get $heap) (i32.const 4)))
+    (i32.store (global.get $heap) (local.get $size))
+    (global.set $heap
+      (i32.and
+        (i32.add (i32.add (local.get $ptr) (local.get $size)) (i32.const 7))
+        (i32.const -8)))
+    (local.get $ptr))
+
+  (func (export "init") (param i32 i32 i32))
+
+  ;; Sum of 1/i for i in [1, n]
+  (func $harmonic (param $n i32) (result f64)
+    (local $i i32)
+    (local $sum f64)
+    (local.set $i (i32.const 1))
+    (block $done
+      (loop $next
+        (br_if $done (i32.gt_u (local.get $i) (local.get $n)))
+        (local.set $sum
+          (f64.add (local.get $sum)
+            (f64.div (f64.const 1) (f64.convert_i32_u (local.get $i)))))
+        (local.set $i (i32.add (local.get $i) (i32.const 1)))
+        (br $next)))
+    (local.get $sum))
+
+  (func $f64 (param $buf i32) (param $index i32) (param $value f64)
+    (i64.store
+      (i32.add (local.get $buf) (i32.shl (local.get $index) (i32.const 3)))
+      (i64.reinterpret_f64 (local.get $value))))
+
+  (func $f32 (param $buf i32) (param $index i32) (param $value f32)
+    (i64.store
+      (i32.add (local.get $buf) (i32.shl (local.get $index) (i32.const 3)))
+      (i64.extend_i32_u (i32.reinterpret_f32 (local.get $value)))))
+
+  (func (export "compute") (param i32 i32 i32)
+    (local $buf i32)
+    (local.set $buf (call $new (i32.const 96) (i32.const 1)))
+
+    ;; 0: 0 / 0
+    (call $f64 (local.get $buf) (i32.const 0) (f64.div (f64.const 0) (f64.const 0)))
+    ;; 1: sqrt(-1)
+    (call $f64 (local.get $buf) (i32.const 1) (f64.sqrt (f64.const -1)))
+    ;; 2: NaN with payload + 1
+    (call $f32 (local.get $buf) (i32.const 2)
+      (f32.add (f32.reinterpret_i32 (i32.const 0x7fa00001)) (f32.const 1)))
+    ;; 3: negative NaN with payload * 2
+    (call $f64 (local.get $buf) (i32.const 3)
+      (f64.mul (f64.reinterpret_i64 (i64.const 0xfff0000000000001)) (f64.const 2)))
+    ;; 4: demoted NaN with payload
+    (call $f32 (local.get $buf) (i32.const 4)
+      (f32.demote_f64 (f64.reinterpret_i64 (i64.const 0x7ff0000000000123))))
+    ;; 5: promoted NaN with payload
+    (call $f64 (local.get $buf) (i32.const 5)
+      (f64.promote_f32 (f32.reinterpret_i32 (i32.const 0xffc00123))))
+    ;; 6: min(NaN, 1)
+    (call $f64 (local.get $buf) (i32.const 6)
+      (f64.min (f64.reinterpret_i64 (i64.const 0x7ff4000000000000)) (f64.const 1)))
+    ;; 7: nearest(2.5)
+    (call $f64 (local.get $buf) (i32.const 7) (f64.nearest (f64.const 2.5)))
+    ;; 8: 0.1 + 0.2
+    (call $f64 (local.get $buf) (i32.const 8) (f64.add (f64.const 0.1) (f64.const 0.2)))
+    ;; 9: sqrt(2) in single precision
+    (call $f32 (local.get $buf) (i32.const 9) (f32.sqrt (f32.const 2)))
+    ;; 10: harmonic number of 1000
+    (call $f64 (local.get $buf) (i32.const 10) (call $harmonic (i32.const 1000)))
+    ;; 11: saturating truncation of NaN
+    (i64.store offset=88 (local.get $buf)
+      (i64.extend_i32_s (i32.trunc_sat_f64_s (f64.div (f64.const 0) (f64.const 0)))))
+
+    (call $save (i32.const 20) (local.get $buf)))
+)
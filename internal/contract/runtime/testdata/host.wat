@@ -0,0 +1,73 @@
+;; Hand-written contract exercising the host functions which are not covered
+;; by test.wasm. It mimics the AssemblyScript memory layout: every object is
+;; prefixed by its byte length, and strings are UTF-16 encoded.
+(module
+  (import "runtime" "db.save" (func $save (param i32 i32)))
+  (import "runtime" "bank.balance" (func $balance (param i32 i32) (result i64)))
+  (import "runtime" "bank.transfer" (func $transfer (param i32 i32 i64)))
+  (import "runtime" "event.emit" (func $emit (param i32 i32)))
+  (import "runtime" "event.emit_attrs" (func $emitAttrs (param i32 i32)))
+
+  (memory (export "memory") 1)
+  (global $heap (mut i32) (i32.const 1024))
+
+  ;; "alice"
+  (data (i32.const 16) "\0a\00\00\00a\00l\00i\00c\00e\00")
+  ;; "uatom"
+  (data (i32.const 32) "\0a\00\00\00u\00a\00t\00o\00m\00")
+  ;; "balance"
+  (data (i32.const 48) "\0e\00\00\00b\00a\00l\00a\00n\00c\00e\00")
+  ;; "transfer"
+  (data (i32.const 72) "\10\00\00\00t\00r\00a\00n\00s\00f\00e\00r\00")
+  ;; Attributes [("to", "alice"), ("raw", 0x00ff10)]
+  (data (i32.const 96) "\21\00\00\00\02\00\00\00\02\00\00\00to\05\00\00\00alice\03\00\00\00raw\03\00\00\00\00\ff\10")
+  ;; Attributes declaring 65 entries
+  (data (i32.const 144) "\04\00\00\00\41\00\00\00")
+  ;; ""
+  (data (i32.const 160) "\00\00\00\00")
+  ;; 5000 NUL characters
+  (data (i32.const 8188) "\10\27\00\00")
+
+  (func $new (export "__new") (param $size i32) (param $id i32) (result i32)
+    (local $ptr i32)
+    (local.set $ptr (i32.add (global.get $heap) (i32.const 4)))
+    (i32.store (global.get $heap) (local.get $size))
+    (global.set $heap
+      (i32.and
+        (i32.add (i32.add (local.get $ptr) (local.get $size)) (i32.const 7))
+        (i32.const -8)))
+    (local.get $ptr))
+
+  (func (export "init") (param i32 i32 i32))
+
+  ;; Saves the "uatom" balance of "alice" to the "balance" entity
+  (func (export "balance") (param i32 i32 i32)
+    (local $buf i32)
+    (local.set $buf (call $new (i32.const 8) (i32.const 1)))
+    (i64.store (local.get $buf) (call $balance (i32.const 20) (i32.const 36)))
+    (call $save (i32.const 52) (local.get $buf)))
+
+  ;; Transfers 10 "uatom" from the contract to "alice"
+  (func (export "transfer") (param i32 i32 i32)
+    (call $transfer (i32.const 20) (i32.const 36) (i64.const 10)))
+
+  ;; Transfers -1 "uatom" from the contract to "alice"
+  (func (export "transferNegative") (param i32 i32 i32)
+    (call $transfer (i32.const 20) (i32.const 36) (i64.const -1)))
+
+  ;; Transfers 0 "uatom" from the contract to "alice"
+  (func (export "transferZero") (param i32 i32 i32)
+    (call $transfer (i32.const 20) (i32.const 36) (i64.const 0)))
+
+  ;; Emits the "transfer" event with binary attributes
+  (func (export "emitAttrs") (param i32 i32 i32)
+    (call $emitAttrs (i32.const 76) (i32.const 100)))
+
+  ;; Emits the "transfer" event with more attributes than allowed
+  (func (export "emitTooManyAttrs") (param i32 i32 i32)
+    (call $emitAttrs (i32.const 76) (i32.const 148)))
+
+  ;; Emits an event without name and with data above the attribute size limit
+  (func (export "emitLegacy") (param i32 i32 i32)
+    (call $emit (i32.const 164) (i32.const 8192)))
+)
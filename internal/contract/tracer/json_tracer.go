@@ -0,0 +1,116 @@
+package tracer
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+)
+
+var _ interfaces.Tracer = (*JSONTracer)(nil)
+
+// JSONTracer writes every traced step as a JSON object on its own line.
+// Write errors do not interrupt the execution, the first one is kept in Err.
+type JSONTracer struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	err     error
+}
+
+func NewJSONTracer(w io.Writer) *JSONTracer {
+	return &JSONTracer{
+		encoder: json.NewEncoder(w),
+	}
+}
+
+// Err returns the first error that occurred while writing the trace
+func (t *JSONTracer) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+func (t *JSONTracer) OnMessageStart(msg interfaces.ContractMessage, depth int, gasLimit uint64) {
+	t.write(map[string]any{
+		"type":      "message_start",
+		"contract":  msg.Contract,
+		"method":    msg.Method,
+		"sender":    msg.Sender,
+		"args":      msg.Args,
+		"funds":     msg.Funds,
+		"depth":     depth,
+		"gas_limit": gasLimit,
+	})
+}
+
+func (t *JSONTracer) OnMessageEnd(msg interfaces.ContractMessage, depth int, gasUsed uint64, err error) {
+	t.write(map[string]any{
+		"type":     "message_end",
+		"contract": msg.Contract,
+		"method":   msg.Method,
+		"depth":    depth,
+		"gas_used": gasUsed,
+		"error":    errorString(err),
+	})
+}
+
+func (t *JSONTracer) OnHostCall(contractId string, name string, args []any, gas uint64) {
+	t.write(map[string]any{
+		"type":     "host_call",
+		"contract": contractId,
+		"name":     name,
+		"args":     args,
+		"gas":      gas,
+	})
+}
+
+func (t *JSONTracer) OnStorageRead(contractId string, key string, value []byte) {
+	t.write(map[string]any{
+		"type":     "storage_read",
+		"contract": contractId,
+		"key":      key,
+		"value":    value,
+	})
+}
+
+func (t *JSONTracer) OnStorageWrite(contractId string, key string, value []byte) {
+	t.write(map[string]any{
+		"type":     "storage_write",
+		"contract": contractId,
+		"key":      key,
+		"value":    value,
+	})
+}
+
+func (t *JSONTracer) OnEvent(event interfaces.ResultEvent) {
+	t.write(map[string]any{
+		"type":  "event",
+		"event": event,
+	})
+}
+
+func (t *JSONTracer) OnTrap(contractId string, err error) {
+	t.write(map[string]any{
+		"type":     "trap",
+		"contract": contractId,
+		"error":    errorString(err),
+	})
+}
+
+func (t *JSONTracer) write(entry map[string]any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.err != nil {
+		return
+	}
+	t.err = t.encoder.Encode(entry)
+}
+
+func errorString(err error) any {
+	if err == nil {
+		return nil
+	}
+	return err.Error()
+}
@@ -0,0 +1,53 @@
+package tracer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+)
+
+func TestJSONTracer(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewJSONTracer(&buf)
+
+	msg := interfaces.NewContractMessage("contract", "addOne", []byte{0x01}, "sender")
+	tracer.OnMessageStart(msg, 0, 20_000)
+	tracer.OnHostCall("contract", "db.load", []any{"test"}, 1_024)
+	tracer.OnStorageRead("contract", "test", []byte{0x01})
+	tracer.OnStorageWrite("contract", "test", []byte{0x02})
+	tracer.OnTrap("contract", fmt.Errorf("wasm trap"))
+	tracer.OnMessageEnd(msg, 0, 15_968, fmt.Errorf("wasm trap"))
+	require.NoError(t, tracer.Err())
+
+	var entries []map[string]any
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+
+	require.Len(t, entries, 6)
+	require.Equal(t, "message_start", entries[0]["type"])
+	require.Equal(t, "addOne", entries[0]["method"])
+	require.Equal(t, float64(20_000), entries[0]["gas_limit"])
+
+	require.Equal(t, "host_call", entries[1]["type"])
+	require.Equal(t, "db.load", entries[1]["name"])
+	require.Equal(t, float64(1_024), entries[1]["gas"])
+
+	require.Equal(t, "storage_read", entries[2]["type"])
+	require.Equal(t, "storage_write", entries[3]["type"])
+
+	require.Equal(t, "trap", entries[4]["type"])
+	require.Equal(t, "wasm trap", entries[4]["error"])
+
+	require.Equal(t, "message_end", entries[5]["type"])
+	require.Equal(t, float64(15_968), entries[5]["gas_used"])
+}
@@ -0,0 +1,89 @@
+package metering
+
+import (
+	checker "github.com/dadamu/contract-wasmvm/internal/code-checker"
+)
+
+// CostTable is the gas charged for executing each opcode
+type CostTable struct {
+	// Default is the cost of the opcodes missing from Opcodes
+	Default uint64
+	Opcodes map[checker.Opcode]uint64
+
+	// MemoryGrowPage is charged for each page requested by memory.grow, on top
+	// of the cost of the opcode
+	MemoryGrowPage uint64
+	// BulkMemoryByte is charged for each byte written by memory.copy,
+	// memory.fill and memory.init, on top of the cost of their opcode
+	BulkMemoryByte uint64
+}
+
+// Cost returns the gas charged for executing the opcode
+func (t CostTable) Cost(opcode checker.Opcode) uint64 {
+	if cost, ok := t.Opcodes[opcode]; ok {
+		return cost
+	}
+	return t.Default
+}
+
+// DefaultCostTable returns the cost table weighting opcodes by the work they
+// do, structural opcodes are free and everything else costs at least 1
+func DefaultCostTable() CostTable {
+	opcodes := map[checker.Opcode]uint64{
+		// Structural opcodes do not execute anything by themselves
+		checker.OpNop:   0,
+		checker.OpBlock: 0,
+		checker.OpLoop:  0,
+		checker.OpElse:  0,
+		checker.OpEnd:   0,
+
+		checker.OpBrTable: 3,
+
+		// Calls set up a frame, indirect calls also check the signature
+		checker.OpCall:               10,
+		checker.OpCallIndirect:       15,
+		checker.OpReturnCall:         10,
+		checker.OpReturnCallIndirect: 15,
+
+		checker.OpGlobalGet: 2,
+		checker.OpGlobalSet: 2,
+
+		checker.OpMemorySize: 2,
+		checker.OpMemoryGrow: 1000,
+
+		// i32.mul, i64.mul
+		0x6C: 3,
+		0x7E: 3,
+
+		// f32.div, f32.sqrt, f64.div, f64.sqrt
+		0x95: 8,
+		0x91: 16,
+		0xA3: 8,
+		0x9F: 16,
+	}
+
+	// Loads and stores
+	for code := checker.Opcode(0x28); code <= 0x3E; code++ {
+		opcodes[code] = 2
+	}
+
+	// i32.div_s, i32.div_u, i32.rem_s, i32.rem_u
+	for code := checker.Opcode(0x6D); code <= 0x70; code++ {
+		opcodes[code] = 8
+	}
+
+	// i64.div_s, i64.div_u, i64.rem_s, i64.rem_u
+	for code := checker.Opcode(0x7F); code <= 0x82; code++ {
+		opcodes[code] = 16
+	}
+
+	return CostTable{
+		Default: 1,
+		Opcodes: opcodes,
+
+		// Grown pages of 64 KiB are mapped lazily and zeroed by the host on
+		// first access, which is cheaper than a bulk fill of the same size
+		MemoryGrowPage: 4096,
+		BulkMemoryByte: 1,
+	}
+}
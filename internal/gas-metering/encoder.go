@@ -0,0 +1,94 @@
+package metering
+
+import (
+	"fmt"
+
+	checker "github.com/dadamu/contract-wasmvm/internal/code-checker"
+)
+
+var (
+	wasmMagic   = []byte{0x00, 0x61, 0x73, 0x6D}
+	wasmVersion = []byte{0x01, 0x00, 0x00, 0x00}
+)
+
+// funcTypeForm prefixes function types in the type section
+const funcTypeForm = 0x60
+
+func appendUnsigned(bz []byte, value uint64) []byte {
+	for {
+		b := byte(value & 0x7F)
+		value >>= 7
+		if value == 0 {
+			return append(bz, b)
+		}
+		bz = append(bz, b|0x80)
+	}
+}
+
+func appendU32(bz []byte, value uint32) []byte {
+	return appendUnsigned(bz, uint64(value))
+}
+
+func appendSigned(bz []byte, value int64) []byte {
+	for {
+		b := byte(value & 0x7F)
+		value >>= 7
+		if (value == 0 && b&0x40 == 0) || (value == -1 && b&0x40 != 0) {
+			return append(bz, b)
+		}
+		bz = append(bz, b|0x80)
+	}
+}
+
+// appendI32Const encodes an i32.const of the value reinterpreted as signed
+func appendI32Const(bz []byte, value uint32) []byte {
+	bz = append(bz, byte(checker.OpI32Const))
+	return appendSigned(bz, int64(int32(value)))
+}
+
+func appendName(bz []byte, name string) []byte {
+	bz = appendU32(bz, uint32(len(name)))
+	return append(bz, name...)
+}
+
+func appendSection(bz []byte, id checker.SectionID, payload []byte) []byte {
+	bz = append(bz, byte(id))
+	bz = appendU32(bz, uint32(len(payload)))
+	return append(bz, payload...)
+}
+
+func appendFuncType(bz []byte, funcType checker.FuncType) []byte {
+	bz = append(bz, funcTypeForm)
+	bz = appendU32(bz, uint32(len(funcType.Params)))
+	for _, param := range funcType.Params {
+		bz = append(bz, byte(param))
+	}
+	bz = appendU32(bz, uint32(len(funcType.Results)))
+	for _, result := range funcType.Results {
+		bz = append(bz, byte(result))
+	}
+	return bz
+}
+
+func appendGlobalType(bz []byte, globalType checker.GlobalType) []byte {
+	bz = append(bz, byte(globalType.ValType))
+	if globalType.Mutable {
+		return append(bz, 0x01)
+	}
+	return append(bz, 0x00)
+}
+
+// splitVector returns the element count of a vector and its encoded elements
+func splitVector(payload []byte) (uint32, []byte, error) {
+	var count uint64
+	for i, b := range payload {
+		if i >= 5 {
+			break
+		}
+		count |= uint64(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			return uint32(count), payload[i+1:], nil
+		}
+	}
+	return 0, nil, fmt.Errorf("malformed vector length")
+}
@@ -0,0 +1,108 @@
+package metering
+
+import (
+	checker "github.com/dadamu/contract-wasmvm/internal/code-checker"
+)
+
+const (
+	// GasModule and GasFunction name the host function instrumented code calls
+	// with the cost of each metered block
+	GasModule   = "runtime"
+	GasFunction = "gas"
+)
+
+// GasFunctionType is the signature of the gas host function
+var GasFunctionType = checker.FuncType{Params: []checker.ValType{checker.ValTypeI64}}
+
+// IsInstrumented reports whether the module imports the gas host function
+func IsInstrumented(module *checker.Module) bool {
+	for _, imp := range module.Imports {
+		if imp.Kind == checker.ExternalFunc && imp.Module == GasModule && imp.Name == GasFunction {
+			return true
+		}
+	}
+	return false
+}
+
+// Instrument rewrites the module so that each basic block calls the gas host
+// function with the cost of its instructions before executing them. The
+// memory instructions whose cost depends on their operands go through a
+// function charging the pages or bytes first.
+// The gas function is imported after the existing imports, so the indices of
+// the defined functions are shifted by one.
+func Instrument(module *checker.Module, costs CostTable) ([]byte, error) {
+	rw, err := newRewriter(module, hostImport{module: GasModule, name: GasFunction, typ: GasFunctionType})
+	if err != nil {
+		return nil, err
+	}
+
+	charges := addMemoryCharges(rw, costs)
+	rw.body = func(bz []byte, function checker.Function) []byte {
+		return appendMeteredExpr(bz, rw, costs, charges, function.Instructions)
+	}
+	return rw.encode()
+}
+
+// appendMeteredExpr encodes the function body charging the cost of each
+// basic block at its start, the instructions with a charge function are
+// replaced by a call of the function
+func appendMeteredExpr(bz []byte, rw *rewriter, costs CostTable, charges map[string]uint32, instructions []checker.Instruction) []byte {
+	for _, block := range splitBlocks(instructions) {
+		var cost uint64
+		for _, instruction := range instructions[block.start:block.end] {
+			cost += costs.Cost(instruction.Opcode)
+		}
+
+		if cost > 0 {
+			bz = append(bz, byte(checker.OpI64Const))
+			bz = appendSigned(bz, int64(cost))
+			bz = append(bz, byte(checker.OpCall))
+			bz = appendU32(bz, rw.hostFunc)
+		}
+
+		for _, instruction := range instructions[block.start:block.end] {
+			if charge, found := charges[string(instruction.Raw)]; found {
+				bz = append(bz, byte(checker.OpCall))
+				bz = appendU32(bz, charge)
+				continue
+			}
+			bz = rw.appendInstruction(bz, instruction)
+		}
+	}
+	return bz
+}
+
+type basicBlock struct {
+	start, end int
+}
+
+// splitBlocks splits the instructions into the sequences which are always
+// executed entirely once entered, a sequence ends after any instruction that
+// may branch or is the target of a branch
+func splitBlocks(instructions []checker.Instruction) []basicBlock {
+	var blocks []basicBlock
+	start := 0
+	for i, instruction := range instructions {
+		if endsBlock(instruction.Opcode) {
+			blocks = append(blocks, basicBlock{start: start, end: i + 1})
+			start = i + 1
+		}
+	}
+	if start < len(instructions) {
+		blocks = append(blocks, basicBlock{start: start, end: len(instructions)})
+	}
+	return blocks
+}
+
+func endsBlock(opcode checker.Opcode) bool {
+	switch opcode {
+	case checker.OpLoop, checker.OpIf, checker.OpElse, checker.OpEnd,
+		checker.OpBr, checker.OpBrIf, checker.OpBrTable, checker.OpReturn,
+		checker.OpUnreachable, checker.OpReturnCall, checker.OpReturnCallIndirect,
+		checker.OpReturnCallRef, checker.OpBrOnNull, checker.OpBrOnNonNull,
+		checker.OpTry, checker.OpCatch, checker.OpCatchAll, checker.OpDelegate,
+		checker.OpThrow, checker.OpRethrow, checker.OpThrowRef:
+		return true
+	}
+	return false
+}
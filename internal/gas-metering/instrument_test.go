@@ -0,0 +1,255 @@
+package metering
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/bytecodealliance/wasmtime-go/v31"
+	"github.com/stretchr/testify/require"
+
+	checker "github.com/dadamu/contract-wasmvm/internal/code-checker"
+)
+
+// instantiate instruments the module and instantiates it with a gas function
+// adding the charged costs to gasUsed
+func instantiate(t *testing.T, wat string, costs CostTable, gasUsed *uint64) (*wasmtime.Store, *wasmtime.Instance) {
+	code, err := wasmtime.Wat2Wasm(wat)
+	require.NoError(t, err)
+
+	module, err := checker.ParseModule(code)
+	require.NoError(t, err)
+
+	instrumented, err := Instrument(module, costs)
+	require.NoError(t, err)
+
+	parsed, err := checker.ParseModule(instrumented)
+	require.NoError(t, err)
+	require.True(t, IsInstrumented(parsed))
+
+	engine := wasmtime.NewEngine()
+	compiled, err := wasmtime.NewModule(engine, instrumented)
+	require.NoError(t, err)
+
+	store := wasmtime.NewStore(engine)
+	linker := wasmtime.NewLinker(engine)
+	require.NoError(t, linker.DefineFunc(store, GasModule, GasFunction, func(amount int64) {
+		*gasUsed += uint64(amount)
+	}))
+	require.NoError(t, linker.DefineFunc(store, "env", "log", func(int32) {}))
+
+	instance, err := linker.Instantiate(store, compiled)
+	require.NoError(t, err)
+	return store, instance
+}
+
+func TestLoopCost(t *testing.T) {
+	var gasUsed uint64
+	store, instance := instantiate(t, `
+		(module
+		  (func (export "count") (param $n i32) (result i32)
+		    (local $i i32)
+		    (loop $loop
+		      (local.set $i (i32.add (local.get $i) (i32.const 1)))
+		      (br_if $loop (i32.lt_u (local.get $i) (local.get $n))))
+		    (local.get $i)))
+	`, CostTable{Default: 1}, &gasUsed)
+
+	count := instance.GetFunc(store, "count")
+	result, err := count.Call(store, 10)
+	require.NoError(t, err)
+	require.Equal(t, int32(10), result)
+
+	// The loop instruction, 8 instructions per iteration, the end of the loop
+	// and the final local.get and end
+	require.Equal(t, uint64(1+10*8+1+2), gasUsed)
+}
+
+func TestWeightedCosts(t *testing.T) {
+	wat := `
+		(module
+		  (func (export "run") (param $n i64) (result i64)
+		    (local $i i64)
+		    (loop $loop
+		      (local.set $i (i64.add (local.get $i) (i64.const 1)))
+		      (drop (%s (local.get $n) (i64.const 3)))
+		      (br_if $loop (i64.lt_u (local.get $i) (local.get $n))))
+		    (local.get $i)))
+	`
+
+	var addGas, divGas uint64
+	store, instance := instantiate(t, fmt.Sprintf(wat, "i64.add"), DefaultCostTable(), &addGas)
+	_, err := instance.GetFunc(store, "run").Call(store, int64(100))
+	require.NoError(t, err)
+
+	store, instance = instantiate(t, fmt.Sprintf(wat, "i64.div_u"), DefaultCostTable(), &divGas)
+	_, err = instance.GetFunc(store, "run").Call(store, int64(100))
+	require.NoError(t, err)
+
+	// Division costs 16 instead of 1 on each of the 100 iterations
+	require.Equal(t, addGas+100*15, divGas)
+}
+
+func TestMemoryCharges(t *testing.T) {
+	wat := `
+		(module
+		  (memory 1)
+		  (func (export "grow") (param i32) (result i32)
+		    (memory.grow (local.get 0)))
+		  (func (export "fill") (param i32)
+		    (memory.fill (i32.const 0) (i32.const 7) (local.get 0)))
+		  (func (export "copy") (param i32)
+		    (memory.copy (i32.const 1024) (i32.const 0) (local.get 0))))
+	`
+	costs := CostTable{Default: 1, MemoryGrowPage: 100, BulkMemoryByte: 2}
+
+	var gasUsed uint64
+	store, instance := instantiate(t, wat, costs, &gasUsed)
+	charged := func(name string, arg int32) uint64 {
+		before := gasUsed
+		_, err := instance.GetFunc(store, name).Call(store, arg)
+		require.NoError(t, err)
+		return gasUsed - before
+	}
+
+	// The instructions cost 1 each, the pages and bytes are charged on top
+	require.Equal(t, uint64(3+3*100), charged("grow", 3))
+	require.Equal(t, uint64(3), charged("grow", 0))
+	require.Equal(t, uint64(5+1000*2), charged("fill", 1000))
+	require.Equal(t, uint64(5+512*2), charged("copy", 512))
+}
+
+func TestMemoryChargeBeforeExecution(t *testing.T) {
+	code, err := wasmtime.Wat2Wasm(`
+		(module
+		  (memory (export "memory") 1)
+		  (func (export "fill") (param i32)
+		    (memory.fill (i32.const 0) (i32.const 7) (local.get 0))))
+	`)
+	require.NoError(t, err)
+
+	module, err := checker.ParseModule(code)
+	require.NoError(t, err)
+
+	instrumented, err := Instrument(module, DefaultCostTable())
+	require.NoError(t, err)
+
+	engine := wasmtime.NewEngine()
+	compiled, err := wasmtime.NewModule(engine, instrumented)
+	require.NoError(t, err)
+
+	// The gas function traps once the limit is exceeded, the memory is not
+	// filled since the bytes are charged first
+	store := wasmtime.NewStore(engine)
+	linker := wasmtime.NewLinker(engine)
+	require.NoError(t, linker.DefineFunc(store, GasModule, GasFunction, func(amount int64) *wasmtime.Trap {
+		if amount > 1000 {
+			return wasmtime.NewTrap("out of gas")
+		}
+		return nil
+	}))
+	instance, err := linker.Instantiate(store, compiled)
+	require.NoError(t, err)
+
+	_, err = instance.GetFunc(store, "fill").Call(store, 4096)
+	require.ErrorContains(t, err, "out of gas")
+
+	memory := instance.GetExport(store, "memory").Memory()
+	require.Zero(t, memory.UnsafeData(store)[0])
+}
+
+func TestRemapFunctionIndices(t *testing.T) {
+	var gasUsed uint64
+	store, instance := instantiate(t, `
+		(module
+		  (import "env" "log" (func $log (param i32)))
+		  (type $unary (func (param i32) (result i32)))
+		  (table 2 funcref)
+		  (elem (i32.const 0) $double $triple)
+		  (global $ref funcref (ref.func $double))
+		  (func $double (type $unary) (i32.mul (local.get 0) (i32.const 2)))
+		  (func $triple (type $unary) (i32.mul (local.get 0) (i32.const 3)))
+		  (func (export "direct") (param i32) (result i32)
+		    (call $log (local.get 0))
+		    (call $triple (local.get 0)))
+		  (func (export "indirect") (param i32 i32) (result i32)
+		    (call_indirect (type $unary) (local.get 0) (local.get 1)))
+		  (export "double" (func $double)))
+	`, DefaultCostTable(), &gasUsed)
+
+	result, err := instance.GetFunc(store, "direct").Call(store, 5)
+	require.NoError(t, err)
+	require.Equal(t, int32(15), result)
+
+	result, err = instance.GetFunc(store, "indirect").Call(store, 5, 0)
+	require.NoError(t, err)
+	require.Equal(t, int32(10), result)
+
+	result, err = instance.GetFunc(store, "double").Call(store, 7)
+	require.NoError(t, err)
+	require.Equal(t, int32(14), result)
+
+	require.NotZero(t, gasUsed)
+}
+
+func TestFunctionNamesRemapped(t *testing.T) {
+	code, err := wasmtime.Wat2Wasm(`
+		(module
+		  (import "env" "log" (func $log (param i32)))
+		  (func $helper)
+		  (func $main (call $helper)))
+	`)
+	require.NoError(t, err)
+
+	module, err := checker.ParseModule(code)
+	require.NoError(t, err)
+
+	instrumented, err := Instrument(module, DefaultCostTable())
+	require.NoError(t, err)
+
+	parsed, err := checker.ParseModule(instrumented)
+	require.NoError(t, err)
+	require.Equal(t, "log", parsed.FuncName(0))
+	require.Equal(t, "helper", parsed.FuncName(2))
+	require.Equal(t, "main", parsed.FuncName(3))
+
+	// main calls helper at its new index
+	calls := 0
+	for _, instruction := range parsed.Functions[1].Instructions {
+		if instruction.Opcode == checker.OpCall && instruction.Indices[0] == 2 {
+			calls++
+		}
+	}
+	require.Equal(t, 1, calls)
+}
+
+func TestInstrumentTwice(t *testing.T) {
+	code, err := wasmtime.Wat2Wasm(`(module (func))`)
+	require.NoError(t, err)
+
+	module, err := checker.ParseModule(code)
+	require.NoError(t, err)
+
+	instrumented, err := Instrument(module, DefaultCostTable())
+	require.NoError(t, err)
+
+	module, err = checker.ParseModule(instrumented)
+	require.NoError(t, err)
+
+	_, err = Instrument(module, DefaultCostTable())
+	require.ErrorContains(t, err, "module already imports runtime.gas")
+}
+
+func TestInstrumentAssemblyScript(t *testing.T) {
+	code, err := os.ReadFile("../code-checker/testdata/accept/assemblyscript.wasm")
+	require.NoError(t, err)
+
+	module, err := checker.ParseModule(code)
+	require.NoError(t, err)
+
+	instrumented, err := Instrument(module, DefaultCostTable())
+	require.NoError(t, err)
+
+	_, err = wasmtime.NewModule(wasmtime.NewEngine(), instrumented)
+	require.NoError(t, err)
+}
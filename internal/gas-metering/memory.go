@@ -0,0 +1,86 @@
+package metering
+
+import (
+	checker "github.com/dadamu/contract-wasmvm/internal/code-checker"
+)
+
+var (
+	// memoryGrowType is the signature of memory.grow, taking the number of
+	// pages and returning the previous size
+	memoryGrowType = checker.FuncType{
+		Params:  []checker.ValType{checker.ValTypeI32},
+		Results: []checker.ValType{checker.ValTypeI32},
+	}
+	// bulkMemoryType is the signature of memory.copy, memory.fill and
+	// memory.init, the last operand being the number of bytes
+	bulkMemoryType = checker.FuncType{
+		Params: []checker.ValType{checker.ValTypeI32, checker.ValTypeI32, checker.ValTypeI32},
+	}
+)
+
+// memoryCharge returns the price of each unit of the last operand of the
+// instruction and the signature of the instruction, if its cost depends on it
+func memoryCharge(costs CostTable, opcode checker.Opcode) (uint64, checker.FuncType, bool) {
+	if opcode == checker.OpMemoryGrow {
+		return costs.MemoryGrowPage, memoryGrowType, costs.MemoryGrowPage > 0
+	}
+
+	if opcode.Prefix() == checker.PrefixMisc {
+		switch opcode.Code() {
+		case 0x08, 0x0A, 0x0B: // memory.init, memory.copy and memory.fill
+			return costs.BulkMemoryByte, bulkMemoryType, costs.BulkMemoryByte > 0
+		}
+	}
+	return 0, checker.FuncType{}, false
+}
+
+// addMemoryCharges appends a function for each distinct memory instruction
+// whose cost depends on its operands, the function charges the pages or bytes
+// before executing the instruction. It returns the appended functions by
+// instruction encoding.
+func addMemoryCharges(rw *rewriter, costs CostTable) map[string]uint32 {
+	charges := make(map[string]uint32)
+	for _, function := range rw.module.Functions {
+		for _, instruction := range function.Instructions {
+			price, funcType, ok := memoryCharge(costs, instruction.Opcode)
+			if !ok {
+				continue
+			}
+			if _, found := charges[string(instruction.Raw)]; found {
+				continue
+			}
+
+			charges[string(instruction.Raw)] = rw.numFuncs() + uint32(len(rw.functions))
+			rw.functions = append(rw.functions, extraFunction{
+				typeIndex: rw.typeIndex(funcType),
+				body:      rw.memoryChargeBody(instruction, price, len(funcType.Params)),
+			})
+		}
+	}
+	return charges
+}
+
+// memoryChargeBody encodes a function charging the price times its last
+// parameter, then executing the instruction with its parameters
+func (rw *rewriter) memoryChargeBody(instruction checker.Instruction, price uint64, params int) []byte {
+	// No locals
+	body := appendU32(nil, 0)
+
+	// The operand is unsigned and the price is capped below 2^32, so that the
+	// product does not overflow
+	body = append(body, byte(checker.OpLocalGet))
+	body = appendU32(body, uint32(params-1))
+	body = append(body, 0xAD) // i64.extend_i32_u
+	body = append(body, byte(checker.OpI64Const))
+	body = appendSigned(body, int64(min(price, 1<<32-1)))
+	body = append(body, 0x7E) // i64.mul
+	body = append(body, byte(checker.OpCall))
+	body = appendU32(body, rw.hostFunc)
+
+	for param := range params {
+		body = append(body, byte(checker.OpLocalGet))
+		body = appendU32(body, uint32(param))
+	}
+	body = append(body, instruction.Raw...)
+	return append(body, byte(checker.OpEnd))
+}
@@ -0,0 +1,183 @@
+package metering
+
+import (
+	checker "github.com/dadamu/contract-wasmvm/internal/code-checker"
+)
+
+// controlFrame is a block of the function body during the operand stack analysis
+type controlFrame struct {
+	// start is the operand height when the block was entered, without its parameters
+	start   uint64
+	params  uint64
+	results uint64
+}
+
+// operandStack tracks the operand height of a function body, like the stack
+// limiter of wasm-instrument does
+type operandStack struct {
+	height uint64
+	max    uint64
+	frames []controlFrame
+}
+
+func (s *operandStack) push(count uint64) {
+	s.height += count
+	s.max = max(s.max, s.height)
+}
+
+// pop removes the operands, never below the start of the current block as the
+// operands of an unreachable section may come from the polymorphic stack
+func (s *operandStack) pop(count uint64) {
+	start := s.frames[len(s.frames)-1].start
+	if s.height < start+count {
+		s.height = start
+		return
+	}
+	s.height -= count
+}
+
+func (s *operandStack) enter(params uint64, results uint64) {
+	s.pop(params)
+	s.frames = append(s.frames, controlFrame{start: s.height, params: params, results: results})
+	s.push(params)
+}
+
+// unreachable drops the operands of the current block after an unconditional branch
+func (s *operandStack) unreachable() {
+	s.height = s.frames[len(s.frames)-1].start
+}
+
+// call pops the parameters and pushes the results of the function type
+func (s *operandStack) call(funcType checker.FuncType) {
+	s.pop(uint64(len(funcType.Params)))
+	s.push(uint64(len(funcType.Results)))
+}
+
+// maxOperandHeight returns the maximum number of operands the function keeps on
+// the stack, the values of the unknown instructions are counted as pushed to
+// overestimate the height
+func maxOperandHeight(module *checker.Module, function checker.Function) uint64 {
+	funcType, _ := module.FuncType(function.Index)
+	stack := &operandStack{frames: []controlFrame{{results: uint64(len(funcType.Results))}}}
+
+	for _, instruction := range function.Instructions {
+		if len(stack.frames) == 0 {
+			break
+		}
+
+		switch op := instruction.Opcode; op {
+		case checker.OpNop, checker.OpLocalTee, checker.OpMemoryGrow,
+			checker.OpRefIsNull, checker.OpRefAsNonNull, checker.OpBrOnNull:
+		case checker.OpBlock, checker.OpLoop, checker.OpTry:
+			stack.enter(blockArity(module, instruction.BlockType))
+		case checker.OpIf:
+			stack.pop(1)
+			stack.enter(blockArity(module, instruction.BlockType))
+		case checker.OpElse, checker.OpCatch, checker.OpCatchAll:
+			frame := stack.frames[len(stack.frames)-1]
+			stack.height = frame.start
+			stack.push(frame.params)
+		case checker.OpEnd, checker.OpDelegate:
+			frame := stack.frames[len(stack.frames)-1]
+			stack.frames = stack.frames[:len(stack.frames)-1]
+			stack.height = frame.start
+			stack.push(frame.results)
+		case checker.OpUnreachable, checker.OpBr, checker.OpReturn,
+			checker.OpThrow, checker.OpRethrow, checker.OpThrowRef:
+			stack.unreachable()
+		case checker.OpBrTable:
+			stack.pop(1)
+			stack.unreachable()
+		case checker.OpBrIf, checker.OpDrop, checker.OpLocalSet, checker.OpGlobalSet, checker.OpBrOnNonNull:
+			stack.pop(1)
+		case checker.OpCall, checker.OpReturnCall:
+			calledType, _ := module.FuncType(instruction.Indices[0])
+			stack.call(calledType)
+			if op == checker.OpReturnCall {
+				stack.unreachable()
+			}
+		case checker.OpCallIndirect, checker.OpReturnCallIndirect, checker.OpCallRef, checker.OpReturnCallRef:
+			stack.pop(1)
+			if typeIndex := instruction.Indices[0]; int(typeIndex) < len(module.Types) {
+				stack.call(module.Types[typeIndex])
+			}
+			if op == checker.OpReturnCallIndirect || op == checker.OpReturnCallRef {
+				stack.unreachable()
+			}
+		case checker.OpSelect, checker.OpSelectTyped:
+			stack.pop(2)
+		case checker.OpLocalGet, checker.OpGlobalGet, checker.OpMemorySize,
+			checker.OpI32Const, checker.OpI64Const, checker.OpF32Const, checker.OpF64Const,
+			checker.OpRefNull, checker.OpRefFunc:
+			stack.push(1)
+		case checker.OpTableGet:
+		case checker.OpTableSet:
+			stack.pop(2)
+		default:
+			pops, pushes := numericArity(op)
+			stack.pop(pops)
+			stack.push(pushes)
+		}
+	}
+	return stack.max
+}
+
+// blockArity returns the number of parameters and results of a block type
+func blockArity(module *checker.Module, blockType int64) (uint64, uint64) {
+	switch {
+	case blockType == checker.BlockTypeEmpty:
+		return 0, 0
+	case blockType < 0:
+		return 0, 1
+	case blockType < int64(len(module.Types)):
+		funcType := module.Types[blockType]
+		return uint64(len(funcType.Params)), uint64(len(funcType.Results))
+	}
+	return 0, 0
+}
+
+// numericArity returns the number of operands popped and pushed by the memory,
+// numeric and miscellaneous instructions
+func numericArity(op checker.Opcode) (uint64, uint64) {
+	if op.Prefix() == checker.PrefixMisc {
+		switch code := op.Code(); {
+		case code <= 0x07: // trunc_sat conversions
+			return 1, 1
+		case code == 0x08, code == 0x0A, code == 0x0B, code == 0x0C, code == 0x0E, code == 0x11:
+			// memory.init, memory.copy, memory.fill, table.init, table.copy and table.fill
+			return 3, 0
+		case code == 0x09, code == 0x0D: // data.drop and elem.drop
+			return 0, 0
+		case code == 0x0F: // table.grow
+			return 2, 1
+		case code == 0x10: // table.size
+			return 0, 1
+		}
+		return 0, 1
+	}
+	if op.Prefix() != 0 {
+		return 0, 1
+	}
+
+	switch code := byte(op); {
+	case code >= 0x28 && code <= 0x35: // loads
+		return 1, 1
+	case code >= 0x36 && code <= 0x3E: // stores
+		return 2, 0
+	case code == 0x45 || code == 0x50: // eqz
+		return 1, 1
+	case code >= 0x46 && code <= 0x66: // comparisons
+		return 2, 1
+	case code >= 0x67 && code <= 0x69, code >= 0x79 && code <= 0x7B: // clz, ctz and popcnt
+		return 1, 1
+	case code >= 0x6A && code <= 0x8A: // integer binary operations
+		return 2, 1
+	case code >= 0x8B && code <= 0x91, code >= 0x99 && code <= 0x9F: // float unary operations
+		return 1, 1
+	case code >= 0x92 && code <= 0xA6: // float binary operations
+		return 2, 1
+	case code >= 0xA7 && code <= 0xC4: // conversions and sign extensions
+		return 1, 1
+	}
+	return 0, 1
+}
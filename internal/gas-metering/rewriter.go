@@ -0,0 +1,377 @@
+package metering
+
+import (
+	"fmt"
+	"sort"
+
+	checker "github.com/dadamu/contract-wasmvm/internal/code-checker"
+)
+
+// sectionOrder is the position non-custom sections must appear at in a module
+var sectionOrder = map[checker.SectionID]int{
+	checker.SectionType:      1,
+	checker.SectionImport:    2,
+	checker.SectionFunction:  3,
+	checker.SectionTable:     4,
+	checker.SectionMemory:    5,
+	checker.SectionTag:       6,
+	checker.SectionGlobal:    7,
+	checker.SectionExport:    8,
+	checker.SectionStart:     9,
+	checker.SectionElement:   10,
+	checker.SectionDataCount: 11,
+	checker.SectionCode:      12,
+	checker.SectionData:      13,
+}
+
+// hostImport is a host function imported by an instrumentation pass
+type hostImport struct {
+	module string
+	name   string
+	typ    checker.FuncType
+}
+
+// extraFunction is a function defined by an instrumentation pass
+type extraFunction struct {
+	typeIndex uint32
+	// body is the encoded locals and instructions
+	body []byte
+}
+
+// rewriter re-encodes a module with a host function imported after the
+// existing imports, so that the indices of the defined functions are shifted
+// by one. Passes may also append globals and functions and rewrite bodies.
+type rewriter struct {
+	module *checker.Module
+	host   hostImport
+
+	// hostFunc is the index of the imported host function
+	hostFunc uint32
+	// hostType is the index of the host function type
+	hostType uint32
+	// types are the function types appended to the type section
+	types []checker.FuncType
+
+	// redirect returns the function to use in place of a shifted function
+	// index everywhere but in the functions added by the pass, e.g. a wrapper
+	redirect func(index uint32) uint32
+	// body encodes the instructions of a defined function, the locals are
+	// encoded by the rewriter
+	body func(bz []byte, function checker.Function) []byte
+
+	globals   [][]byte
+	functions []extraFunction
+
+	// written holds the sections written so far
+	written map[checker.SectionID]bool
+}
+
+func newRewriter(module *checker.Module, host hostImport) (*rewriter, error) {
+	for _, imp := range module.Imports {
+		if imp.Kind == checker.ExternalFunc && imp.Module == host.module && imp.Name == host.name {
+			return nil, fmt.Errorf("module already imports %s.%s", host.module, host.name)
+		}
+	}
+
+	rw := &rewriter{
+		module:   module,
+		host:     host,
+		hostFunc: module.NumImportedFuncs(),
+		written:  make(map[checker.SectionID]bool),
+	}
+	rw.hostType = rw.typeIndex(host.typ)
+
+	rw.redirect = func(index uint32) uint32 { return index }
+	rw.body = func(bz []byte, function checker.Function) []byte {
+		return rw.appendExpr(bz, function.Instructions)
+	}
+	return rw, nil
+}
+
+// typeIndex returns the index of the function type, which is appended to the
+// type section if the module lacks it
+func (rw *rewriter) typeIndex(funcType checker.FuncType) uint32 {
+	for index, existing := range rw.module.Types {
+		if existing.Equal(funcType) {
+			return uint32(index)
+		}
+	}
+	for index, existing := range rw.types {
+		if existing.Equal(funcType) {
+			return uint32(len(rw.module.Types) + index)
+		}
+	}
+
+	rw.types = append(rw.types, funcType)
+	return uint32(len(rw.module.Types) + len(rw.types) - 1)
+}
+
+// shift returns the new index of a function after the host import is inserted
+func (rw *rewriter) shift(index uint32) uint32 {
+	if index >= rw.hostFunc {
+		return index + 1
+	}
+	return index
+}
+
+// target returns the function referenced in place of the original one
+func (rw *rewriter) target(index uint32) uint32 {
+	return rw.redirect(rw.shift(index))
+}
+
+// numFuncs returns the number of functions of the rewritten module, before the
+// ones added by the pass
+func (rw *rewriter) numFuncs() uint32 {
+	return rw.hostFunc + 1 + uint32(len(rw.module.Functions))
+}
+
+// numGlobals returns the number of globals of the original module
+func (rw *rewriter) numGlobals() uint32 {
+	count := uint32(len(rw.module.Globals))
+	for _, imp := range rw.module.Imports {
+		if imp.Kind == checker.ExternalGlobal {
+			count++
+		}
+	}
+	return count
+}
+
+func (rw *rewriter) encode() ([]byte, error) {
+	bz := append([]byte{}, wasmMagic...)
+	bz = append(bz, wasmVersion...)
+
+	for _, section := range rw.module.Sections {
+		// Sections the module lacks are created before the first section that
+		// must follow them
+		if order, ok := sectionOrder[section.ID]; ok {
+			bz = rw.appendMissing(bz, order)
+		}
+
+		var err error
+		bz, err = rw.appendSection(bz, section)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rw.appendMissing(bz, len(sectionOrder)+1), nil
+}
+
+// appendMissing writes the sections required by the pass which precede the
+// given position and are missing from the module
+func (rw *rewriter) appendMissing(bz []byte, order int) []byte {
+	required := []checker.SectionID{checker.SectionType, checker.SectionImport}
+	if len(rw.globals) > 0 {
+		required = append(required, checker.SectionGlobal)
+	}
+	if len(rw.functions) > 0 {
+		required = append(required, checker.SectionFunction, checker.SectionCode)
+	}
+
+	for _, id := range required {
+		if sectionOrder[id] < order && !rw.written[id] {
+			bz, _ = rw.appendSection(bz, checker.Section{ID: id, Payload: []byte{0x00}})
+		}
+	}
+	return bz
+}
+
+func (rw *rewriter) appendSection(bz []byte, section checker.Section) ([]byte, error) {
+	rw.written[section.ID] = true
+	payload := section.Payload
+
+	switch section.ID {
+	case checker.SectionType:
+		_, types, err := splitVector(section.Payload)
+		if err != nil {
+			return nil, err
+		}
+		payload = rw.typePayload(types)
+
+	case checker.SectionImport:
+		count, imports, err := splitVector(section.Payload)
+		if err != nil {
+			return nil, err
+		}
+		payload = rw.importPayload(count, imports)
+
+	case checker.SectionFunction:
+		payload = rw.functionPayload()
+
+	case checker.SectionGlobal:
+		payload = rw.globalPayload()
+
+	case checker.SectionExport:
+		payload = rw.exportPayload()
+
+	case checker.SectionStart:
+		if rw.module.Start != nil {
+			payload = appendU32(nil, rw.target(*rw.module.Start))
+		}
+
+	case checker.SectionElement:
+		payload = rw.elementPayload()
+
+	case checker.SectionCode:
+		payload = rw.codePayload()
+
+	case checker.SectionCustom:
+		if section.Name == "name" {
+			payload = rw.namePayload()
+		}
+	}
+
+	return appendSection(bz, section.ID, payload), nil
+}
+
+func (rw *rewriter) typePayload(types []byte) []byte {
+	payload := appendU32(nil, uint32(len(rw.module.Types)+len(rw.types)))
+	payload = append(payload, types...)
+	for _, funcType := range rw.types {
+		payload = appendFuncType(payload, funcType)
+	}
+	return payload
+}
+
+func (rw *rewriter) importPayload(count uint32, imports []byte) []byte {
+	payload := appendU32(nil, count+1)
+	payload = append(payload, imports...)
+	payload = appendName(payload, rw.host.module)
+	payload = appendName(payload, rw.host.name)
+	payload = append(payload, byte(checker.ExternalFunc))
+	return appendU32(payload, rw.hostType)
+}
+
+func (rw *rewriter) functionPayload() []byte {
+	payload := appendU32(nil, uint32(len(rw.module.Functions)+len(rw.functions)))
+	for _, function := range rw.module.Functions {
+		payload = appendU32(payload, function.TypeIndex)
+	}
+	for _, function := range rw.functions {
+		payload = appendU32(payload, function.typeIndex)
+	}
+	return payload
+}
+
+func (rw *rewriter) globalPayload() []byte {
+	payload := appendU32(nil, uint32(len(rw.module.Globals)+len(rw.globals)))
+	for _, global := range rw.module.Globals {
+		payload = appendGlobalType(payload, global.Type)
+		payload = rw.appendExpr(payload, global.Init)
+	}
+	for _, global := range rw.globals {
+		payload = append(payload, global...)
+	}
+	return payload
+}
+
+func (rw *rewriter) exportPayload() []byte {
+	payload := appendU32(nil, uint32(len(rw.module.Exports)))
+	for _, export := range rw.module.Exports {
+		index := export.Index
+		if export.Kind == checker.ExternalFunc {
+			index = rw.target(index)
+		}
+
+		payload = appendName(payload, export.Name)
+		payload = append(payload, byte(export.Kind))
+		payload = appendU32(payload, index)
+	}
+	return payload
+}
+
+func (rw *rewriter) elementPayload() []byte {
+	payload := appendU32(nil, uint32(len(rw.module.Elements)))
+	for _, segment := range rw.module.Elements {
+		active := segment.Flags&0x01 == 0
+		explicitTable := segment.Flags&0x02 != 0
+		usesExprs := segment.Flags&0x04 != 0
+
+		payload = appendU32(payload, segment.Flags)
+		if active && explicitTable {
+			payload = appendU32(payload, segment.TableIndex)
+		}
+		if active {
+			payload = rw.appendExpr(payload, segment.OffsetExpr)
+		}
+		if !active || explicitTable {
+			if usesExprs {
+				payload = append(payload, byte(segment.ElemType))
+			} else {
+				payload = append(payload, 0x00)
+			}
+		}
+
+		if usesExprs {
+			payload = appendU32(payload, uint32(len(segment.Exprs)))
+			for _, expr := range segment.Exprs {
+				payload = rw.appendExpr(payload, expr)
+			}
+		} else {
+			payload = appendU32(payload, uint32(len(segment.FuncIndices)))
+			for _, index := range segment.FuncIndices {
+				payload = appendU32(payload, rw.target(index))
+			}
+		}
+	}
+	return payload
+}
+
+func (rw *rewriter) codePayload() []byte {
+	payload := appendU32(nil, uint32(len(rw.module.Functions)+len(rw.functions)))
+	for _, function := range rw.module.Functions {
+		body := appendU32(nil, uint32(len(function.Locals)))
+		for _, local := range function.Locals {
+			body = appendU32(body, local.Count)
+			body = append(body, byte(local.Type))
+		}
+		body = rw.body(body, function)
+
+		payload = appendU32(payload, uint32(len(body)))
+		payload = append(payload, body...)
+	}
+	for _, function := range rw.functions {
+		payload = appendU32(payload, uint32(len(function.body)))
+		payload = append(payload, function.body...)
+	}
+	return payload
+}
+
+// namePayload keeps the function names subsection of the name section, with
+// the indices of the defined functions shifted
+func (rw *rewriter) namePayload() []byte {
+	indices := make([]uint32, 0, len(rw.module.FunctionNames))
+	for index := range rw.module.FunctionNames {
+		indices = append(indices, index)
+	}
+	sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+
+	names := appendU32(nil, uint32(len(indices)))
+	for _, index := range indices {
+		names = appendU32(names, rw.shift(index))
+		names = appendName(names, rw.module.FunctionNames[index])
+	}
+
+	// The function names subsection has id 1
+	payload := appendName(nil, "name")
+	payload = append(payload, 0x01)
+	payload = appendU32(payload, uint32(len(names)))
+	return append(payload, names...)
+}
+
+// appendInstruction encodes the instruction with its function index replaced
+func (rw *rewriter) appendInstruction(bz []byte, instruction checker.Instruction) []byte {
+	switch instruction.Opcode {
+	case checker.OpCall, checker.OpReturnCall, checker.OpRefFunc:
+		bz = append(bz, byte(instruction.Opcode))
+		return appendU32(bz, rw.target(instruction.Indices[0]))
+	}
+	return append(bz, instruction.Raw...)
+}
+
+func (rw *rewriter) appendExpr(bz []byte, instructions []checker.Instruction) []byte {
+	for _, instruction := range instructions {
+		bz = rw.appendInstruction(bz, instruction)
+	}
+	return bz
+}
@@ -0,0 +1,125 @@
+package metering
+
+import (
+	"math"
+
+	checker "github.com/dadamu/contract-wasmvm/internal/code-checker"
+)
+
+const (
+	// StackModule and StackOverflowFunction name the host function called by
+	// code limited with LimitStack when its stack height exceeds the limit
+	StackModule           = "runtime"
+	StackOverflowFunction = "stack_overflow"
+
+	// DefaultMaxStackHeight is the stack height contracts may use, it allows
+	// thousands of nested calls of functions with a few locals
+	DefaultMaxStackHeight = uint32(16 * 1024)
+)
+
+// StackOverflowFunctionType is the signature of the stack overflow host function
+var StackOverflowFunctionType = checker.FuncType{}
+
+// IsStackLimited reports whether the module imports the stack overflow host function
+func IsStackLimited(module *checker.Module) bool {
+	for _, imp := range module.Imports {
+		if imp.Kind == checker.ExternalFunc && imp.Module == StackModule && imp.Name == StackOverflowFunction {
+			return true
+		}
+	}
+	return false
+}
+
+// FrameHeight returns the stack height used by a call of the function, one
+// for the frame itself plus its parameters, locals and maximum operand height
+func FrameHeight(module *checker.Module, function checker.Function) uint32 {
+	funcType, _ := module.FuncType(function.Index)
+	height := uint64(1+len(funcType.Params)) + maxOperandHeight(module, function)
+	for _, local := range function.Locals {
+		height += uint64(local.Count)
+	}
+	return uint32(min(height, math.MaxUint32))
+}
+
+// LimitStack rewrites the module so that every call of a defined function goes
+// through a wrapper adding the frame height of the function to a global, the
+// wrapper calls the stack overflow host function if the height exceeds
+// maxHeight. Unlike the native stack limit of the engine, the depth at which
+// recursion fails does not depend on the host.
+func LimitStack(module *checker.Module, maxHeight uint32) ([]byte, error) {
+	rw, err := newRewriter(module, hostImport{
+		module: StackModule,
+		name:   StackOverflowFunction,
+		typ:    StackOverflowFunctionType,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The stack height is kept in a global appended after the existing ones
+	heightGlobal := rw.numGlobals()
+	global := appendGlobalType(nil, checker.GlobalType{ValType: checker.ValTypeI32, Mutable: true})
+	global = appendI32Const(global, 0)
+	global = append(global, byte(checker.OpEnd))
+	rw.globals = append(rw.globals, global)
+
+	// The wrappers are appended after the defined functions, in the same order
+	firstWrapper := rw.numFuncs()
+	for i, function := range module.Functions {
+		rw.functions = append(rw.functions, extraFunction{
+			typeIndex: function.TypeIndex,
+			body:      rw.wrapperBody(rw.hostFunc+1+uint32(i), heightGlobal, maxHeight, function),
+		})
+	}
+
+	rw.redirect = func(index uint32) uint32 {
+		if index > rw.hostFunc {
+			return firstWrapper + index - rw.hostFunc - 1
+		}
+		return index
+	}
+	return rw.encode()
+}
+
+// wrapperBody encodes a function forwarding its parameters to the wrapped one,
+// with the frame height added to the stack height for the duration of the call
+func (rw *rewriter) wrapperBody(wrapped uint32, heightGlobal uint32, maxHeight uint32, function checker.Function) []byte {
+	height := FrameHeight(rw.module, function)
+
+	// No locals
+	body := appendU32(nil, 0)
+
+	// Increase the height and call the overflow function above the maximum
+	body = appendAddHeight(body, heightGlobal, height, 0x6A)
+	body = append(body, byte(checker.OpGlobalGet))
+	body = appendU32(body, heightGlobal)
+	body = appendI32Const(body, maxHeight)
+	body = append(body, 0x4B)                     // i32.gt_u
+	body = append(body, byte(checker.OpIf), 0x40) // empty block type
+	body = append(body, byte(checker.OpCall))
+	body = appendU32(body, rw.hostFunc)
+	body = append(body, byte(checker.OpEnd))
+
+	funcType, _ := rw.module.FuncType(function.Index)
+	for param := range funcType.Params {
+		body = append(body, byte(checker.OpLocalGet))
+		body = appendU32(body, uint32(param))
+	}
+	body = append(body, byte(checker.OpCall))
+	body = appendU32(body, wrapped)
+
+	// Decrease the height once the call returned, its results are left on the stack
+	body = appendAddHeight(body, heightGlobal, height, 0x6B)
+	return append(body, byte(checker.OpEnd))
+}
+
+// appendAddHeight encodes the update of the stack height global with the
+// i32.add (0x6A) or i32.sub (0x6B) opcode
+func appendAddHeight(bz []byte, heightGlobal uint32, height uint32, opcode byte) []byte {
+	bz = append(bz, byte(checker.OpGlobalGet))
+	bz = appendU32(bz, heightGlobal)
+	bz = appendI32Const(bz, height)
+	bz = append(bz, opcode)
+	bz = append(bz, byte(checker.OpGlobalSet))
+	return appendU32(bz, heightGlobal)
+}
@@ -0,0 +1,150 @@
+package metering
+
+import (
+	"testing"
+
+	"github.com/bytecodealliance/wasmtime-go/v31"
+	"github.com/stretchr/testify/require"
+
+	checker "github.com/dadamu/contract-wasmvm/internal/code-checker"
+)
+
+// instantiateLimited limits the stack of the module and instantiates it with a
+// stack overflow function trapping with "stack overflow"
+func instantiateLimited(t *testing.T, wat string, maxHeight uint32) (*wasmtime.Store, *wasmtime.Instance) {
+	code, err := wasmtime.Wat2Wasm(wat)
+	require.NoError(t, err)
+
+	module, err := checker.ParseModule(code)
+	require.NoError(t, err)
+
+	limited, err := LimitStack(module, maxHeight)
+	require.NoError(t, err)
+
+	parsed, err := checker.ParseModule(limited)
+	require.NoError(t, err)
+	require.True(t, IsStackLimited(parsed))
+
+	engine := wasmtime.NewEngine()
+	compiled, err := wasmtime.NewModule(engine, limited)
+	require.NoError(t, err)
+
+	store := wasmtime.NewStore(engine)
+	linker := wasmtime.NewLinker(engine)
+	require.NoError(t, linker.DefineFunc(store, StackModule, StackOverflowFunction, func() *wasmtime.Trap {
+		return wasmtime.NewTrap("stack overflow")
+	}))
+
+	instance, err := linker.Instantiate(store, compiled)
+	require.NoError(t, err)
+	return store, instance
+}
+
+func TestRecursionDepth(t *testing.T) {
+	// Each call of $rec has a frame height of 4 with its parameter and at most
+	// two operands
+	store, instance := instantiateLimited(t, `
+		(module
+		  (func $rec (export "rec") (param $n i32) (result i32)
+		    (if (result i32) (local.get $n)
+		      (then (i32.add (call $rec (i32.sub (local.get $n) (i32.const 1))) (i32.const 1)))
+		      (else (i32.const 0)))))
+	`, 200)
+	rec := instance.GetFunc(store, "rec")
+
+	// 50 nested calls use a height of exactly 200, which is restored after
+	// each call
+	for i := 0; i < 2; i++ {
+		result, err := rec.Call(store, 49)
+		require.NoError(t, err)
+		require.Equal(t, int32(49), result)
+	}
+
+	_, err := rec.Call(store, 50)
+	require.ErrorContains(t, err, "stack overflow")
+}
+
+func TestFrameHeight(t *testing.T) {
+	code, err := wasmtime.Wat2Wasm(`
+		(module
+		  (func (param i32 i64) (local f32 f32 i32)))
+	`)
+	require.NoError(t, err)
+
+	module, err := checker.ParseModule(code)
+	require.NoError(t, err)
+	require.Equal(t, uint32(6), FrameHeight(module, module.Functions[0]))
+}
+
+func TestMaxOperandHeight(t *testing.T) {
+	code, err := wasmtime.Wat2Wasm(`
+		(module
+		  (type $pair (func (param i32) (result i32 i32)))
+		  (func $callee (param i32 i32) (result i32) (local.get 0))
+		  (func (param i32) (result i32)
+		    (i32.add
+		      (local.get 0)
+		      (i32.mul
+		        (local.get 0)
+		        (call $callee (local.get 0) (i32.const 1)))))
+		  (func (param i32) (result i32)
+		    (block $exit (result i32)
+		      i32.const 1
+		      i32.const 2
+		      i32.const 3
+		      br $exit
+		      i32.const 4
+		      i32.const 5
+		      drop)
+		    local.get 0
+		    (block (type $pair) local.get 0)
+		    i32.add
+		    i32.add))
+	`)
+	require.NoError(t, err)
+
+	module, err := checker.ParseModule(code)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), maxOperandHeight(module, module.Functions[1]))
+	// The operands after the branch are counted from the start of the block
+	require.Equal(t, uint64(3), maxOperandHeight(module, module.Functions[2]))
+}
+
+func TestLimitStackWithoutGlobals(t *testing.T) {
+	store, instance := instantiateLimited(t, `
+		(module
+		  (table 1 funcref)
+		  (elem (i32.const 0) $answer)
+		  (func $answer (result i32) (i32.const 42))
+		  (func (export "indirect") (result i32)
+		    (call_indirect (result i32) (i32.const 0))))
+	`, DefaultMaxStackHeight)
+
+	result, err := instance.GetFunc(store, "indirect").Call(store)
+	require.NoError(t, err)
+	require.Equal(t, int32(42), result)
+}
+
+func TestLimitStackThenInstrument(t *testing.T) {
+	code, err := wasmtime.Wat2Wasm(`
+		(module
+		  (global $counter (mut i32) (i32.const 0))
+		  (func (export "run") (result i32) (global.get $counter)))
+	`)
+	require.NoError(t, err)
+
+	module, err := checker.ParseModule(code)
+	require.NoError(t, err)
+
+	limited, err := LimitStack(module, DefaultMaxStackHeight)
+	require.NoError(t, err)
+
+	module, err = checker.ParseModule(limited)
+	require.NoError(t, err)
+
+	instrumented, err := Instrument(module, DefaultCostTable())
+	require.NoError(t, err)
+
+	_, err = wasmtime.NewModule(wasmtime.NewEngine(), instrumented)
+	require.NoError(t, err)
+}
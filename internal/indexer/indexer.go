@@ -0,0 +1,264 @@
+package indexer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	dbm "github.com/cosmos/iavl/db"
+
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+)
+
+const (
+	DefaultQueryLimit = 100
+	MaxQueryLimit     = 1000
+)
+
+// EventIndexer persists the result events by height, tx index and contract id
+// in a key-value database, so they can be queried after the execution.
+type EventIndexer struct {
+	db dbm.DB
+}
+
+func NewEventIndexer(db dbm.DB) *EventIndexer {
+	return &EventIndexer{
+		db: db,
+	}
+}
+
+type IndexedEvent struct {
+	Height     uint64                 `json:"height"`
+	TxIndex    uint32                 `json:"tx_index"`
+	EventIndex uint32                 `json:"event_index"`
+	Event      interfaces.ResultEvent `json:"event"`
+}
+
+// Cursor returns the query cursor continuing right after the event
+func (e IndexedEvent) Cursor() []byte {
+	return position{height: e.Height, txIndex: e.TxIndex, eventIndex: e.EventIndex}.bytes()
+}
+
+// AttributeFilter matches the events having an attribute with the key,
+// a nil value matches any value.
+type AttributeFilter struct {
+	Key   string
+	Value []byte
+}
+
+type EventQuery struct {
+	ContractId string
+	Event      string
+	Attributes []AttributeFilter
+
+	// FromHeight and ToHeight are inclusive, a zero ToHeight means no upper bound
+	FromHeight uint64
+	ToHeight   uint64
+
+	// Limit is the page size, Cursor is the NextCursor of the previous page
+	Limit  int
+	Cursor []byte
+}
+
+type QueryResult struct {
+	Events []IndexedEvent `json:"events"`
+
+	// NextCursor is nil if there are no more events matching the query
+	NextCursor []byte `json:"next_cursor"`
+}
+
+// IndexEvents stores the events emitted by the transaction at the given height
+func (ei *EventIndexer) IndexEvents(height uint64, txIndex uint32, events []interfaces.ResultEvent) error {
+	batch := ei.db.NewBatch()
+	defer batch.Close()
+
+	for i, event := range events {
+		pos := position{height: height, txIndex: txIndex, eventIndex: uint32(i)}
+		posBz := pos.bytes()
+
+		eventBz, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		keys := [][]byte{
+			append(newContractIndexPrefix(event.ContractId), posBz...),
+			append(newEventNameIndexPrefix(event.Event), posBz...),
+		}
+		for _, attribute := range event.Attributes {
+			keys = append(keys,
+				append(newAttributeKeyIndexPrefix(attribute.Key), posBz...),
+				append(newAttributeIndexPrefix(attribute.Key, attribute.Value), posBz...),
+			)
+		}
+
+		if err := batch.Set(newEventKey(pos), eventBz); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := batch.Set(key, []byte{}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return batch.Write()
+}
+
+// Query returns the events matching all the filters of the query in execution order
+func (ei *EventIndexer) Query(query EventQuery) (*QueryResult, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultQueryLimit
+	}
+	if limit > MaxQueryLimit {
+		limit = MaxQueryLimit
+	}
+
+	prefix := selectIndexPrefix(query)
+	start, end, err := iterationRange(prefix, query)
+	if err != nil {
+		return nil, err
+	}
+
+	iterator, err := ei.db.Iterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	result := &QueryResult{Events: make([]IndexedEvent, 0)}
+	for ; iterator.Valid(); iterator.Next() {
+		pos, err := parsePosition(iterator.Key()[len(prefix):])
+		if err != nil {
+			return nil, err
+		}
+
+		event, err := ei.getEvent(pos)
+		if err != nil {
+			return nil, err
+		}
+
+		if !matchQuery(event.Event, query) {
+			continue
+		}
+
+		// One more event matched after the page is full, so there is a next page
+		if len(result.Events) == limit {
+			result.NextCursor = result.Events[limit-1].Cursor()
+			break
+		}
+		result.Events = append(result.Events, event)
+	}
+
+	if err := iterator.Error(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (ei *EventIndexer) getEvent(pos position) (IndexedEvent, error) {
+	eventBz, err := ei.db.Get(newEventKey(pos))
+	if err != nil {
+		return IndexedEvent{}, err
+	}
+
+	if eventBz == nil {
+		return IndexedEvent{}, fmt.Errorf("indexed event not found at height %d, tx %d, event %d", pos.height, pos.txIndex, pos.eventIndex)
+	}
+
+	var event interfaces.ResultEvent
+	if err := json.Unmarshal(eventBz, &event); err != nil {
+		return IndexedEvent{}, err
+	}
+
+	return IndexedEvent{
+		Height:     pos.height,
+		TxIndex:    pos.txIndex,
+		EventIndex: pos.eventIndex,
+		Event:      event,
+	}, nil
+}
+
+// selectIndexPrefix picks the most selective index for the query,
+// the other filters are applied on the loaded events.
+func selectIndexPrefix(query EventQuery) []byte {
+	for _, attribute := range query.Attributes {
+		if attribute.Value != nil {
+			return newAttributeIndexPrefix(attribute.Key, attribute.Value)
+		}
+	}
+
+	if query.ContractId != "" {
+		return newContractIndexPrefix(query.ContractId)
+	}
+
+	if query.Event != "" {
+		return newEventNameIndexPrefix(query.Event)
+	}
+
+	if len(query.Attributes) > 0 {
+		return newAttributeKeyIndexPrefix(query.Attributes[0].Key)
+	}
+
+	return []byte(EVENT_PREFIX)
+}
+
+// iterationRange returns the keys range of the index covering the query heights,
+// starting right after the cursor if the query continues a previous page.
+func iterationRange(prefix []byte, query EventQuery) ([]byte, []byte, error) {
+	start := append(bytes.Clone(prefix), position{height: query.FromHeight}.bytes()...)
+
+	if query.Cursor != nil {
+		cursor, err := parsePosition(query.Cursor)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid cursor: %w", err)
+		}
+
+		// Every key of the index has the same size, so appending a zero byte
+		// gives the smallest key after the cursor
+		cursorStart := append(append(bytes.Clone(prefix), cursor.bytes()...), 0x00)
+		if bytes.Compare(cursorStart, start) > 0 {
+			start = cursorStart
+		}
+	}
+
+	end := prefixEnd(prefix)
+	if query.ToHeight > 0 && query.ToHeight < ^uint64(0) {
+		end = append(bytes.Clone(prefix), position{height: query.ToHeight + 1}.bytes()...)
+	}
+
+	return start, end, nil
+}
+
+func matchQuery(event interfaces.ResultEvent, query EventQuery) bool {
+	if query.ContractId != "" && event.ContractId != query.ContractId {
+		return false
+	}
+
+	if query.Event != "" && event.Event != query.Event {
+		return false
+	}
+
+	for _, filter := range query.Attributes {
+		if !matchAttribute(event, filter) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchAttribute(event interfaces.ResultEvent, filter AttributeFilter) bool {
+	for _, attribute := range event.Attributes {
+		if attribute.Key != filter.Key {
+			continue
+		}
+
+		if filter.Value == nil || bytes.Equal(attribute.Value, filter.Value) {
+			return true
+		}
+	}
+	return false
+}
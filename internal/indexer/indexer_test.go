@@ -0,0 +1,162 @@
+package indexer
+
+import (
+	"testing"
+
+	dbm "github.com/cosmos/iavl/db"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+)
+
+type IndexerTestSuite struct {
+	suite.Suite
+	indexer *EventIndexer
+}
+
+func (s *IndexerTestSuite) SetupTest() {
+	s.indexer = NewEventIndexer(dbm.NewMemDB())
+
+	// Height 1: a transfer from contract1 and an initialization of contract2
+	s.Require().NoError(s.indexer.IndexEvents(1, 0, []interfaces.ResultEvent{
+		newTransferEvent("contract1", "alice"),
+		{ContractId: "contract2", Event: "initialized"},
+	}))
+
+	// Height 2: two transactions emitting transfers from both contracts
+	s.Require().NoError(s.indexer.IndexEvents(2, 0, []interfaces.ResultEvent{
+		newTransferEvent("contract2", "bob"),
+	}))
+	s.Require().NoError(s.indexer.IndexEvents(2, 1, []interfaces.ResultEvent{
+		newTransferEvent("contract1", "bob"),
+		newTransferEvent("contract1", "alice"),
+	}))
+}
+
+func TestIndexerTestSuite(t *testing.T) {
+	suite.Run(t, new(IndexerTestSuite))
+}
+
+func newTransferEvent(contractId, to string) interfaces.ResultEvent {
+	return interfaces.ResultEvent{
+		ContractId: contractId,
+		Event:      "transfer",
+		Attributes: []interfaces.EventAttribute{
+			interfaces.NewEventAttribute("to", []byte(to)),
+			interfaces.NewEventAttribute("raw", []byte{0x00, 0xff}),
+		},
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func (s *IndexerTestSuite) TestQueryAll() {
+	result, err := s.indexer.Query(EventQuery{})
+	s.Require().NoError(err)
+	s.Require().Len(result.Events, 5)
+	s.Require().Nil(result.NextCursor)
+
+	// Events are returned in execution order
+	s.Require().Equal(uint64(1), result.Events[0].Height)
+	s.Require().Equal(uint32(1), result.Events[1].EventIndex)
+	s.Require().Equal(uint32(1), result.Events[3].TxIndex)
+	s.Require().Equal(uint32(1), result.Events[4].EventIndex)
+
+	// Binary attribute values are preserved
+	raw, found := result.Events[0].Event.GetAttribute("raw")
+	s.Require().True(found)
+	s.Require().Equal([]byte{0x00, 0xff}, raw)
+}
+
+func (s *IndexerTestSuite) TestQueryByContract() {
+	result, err := s.indexer.Query(EventQuery{ContractId: "contract1"})
+	s.Require().NoError(err)
+	s.Require().Len(result.Events, 3)
+	for _, event := range result.Events {
+		s.Require().Equal("contract1", event.Event.ContractId)
+	}
+}
+
+func (s *IndexerTestSuite) TestQueryByEventName() {
+	result, err := s.indexer.Query(EventQuery{Event: "initialized"})
+	s.Require().NoError(err)
+	s.Require().Len(result.Events, 1)
+	s.Require().Equal("contract2", result.Events[0].Event.ContractId)
+}
+
+func (s *IndexerTestSuite) TestQueryByAttribute() {
+	result, err := s.indexer.Query(EventQuery{
+		ContractId: "contract1",
+		Attributes: []AttributeFilter{{Key: "to", Value: []byte("bob")}},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(result.Events, 1)
+	s.Require().Equal(uint64(2), result.Events[0].Height)
+	s.Require().Equal(uint32(1), result.Events[0].TxIndex)
+
+	// A nil value matches any value of the attribute
+	result, err = s.indexer.Query(EventQuery{
+		Attributes: []AttributeFilter{{Key: "to"}},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(result.Events, 4)
+}
+
+func (s *IndexerTestSuite) TestQueryHeightRange() {
+	result, err := s.indexer.Query(EventQuery{FromHeight: 2})
+	s.Require().NoError(err)
+	s.Require().Len(result.Events, 3)
+
+	result, err = s.indexer.Query(EventQuery{Event: "transfer", ToHeight: 1})
+	s.Require().NoError(err)
+	s.Require().Len(result.Events, 1)
+
+	result, err = s.indexer.Query(EventQuery{FromHeight: 3})
+	s.Require().NoError(err)
+	s.Require().Empty(result.Events)
+}
+
+func (s *IndexerTestSuite) TestQueryPagination() {
+	query := EventQuery{Event: "transfer", Limit: 2}
+
+	result, err := s.indexer.Query(query)
+	s.Require().NoError(err)
+	s.Require().Len(result.Events, 2)
+	s.Require().NotNil(result.NextCursor)
+	s.Require().Equal("alice", string(result.Events[0].Event.Attributes[0].Value))
+	s.Require().Equal("bob", string(result.Events[1].Event.Attributes[0].Value))
+
+	query.Cursor = result.NextCursor
+	result, err = s.indexer.Query(query)
+	s.Require().NoError(err)
+	s.Require().Len(result.Events, 2)
+	s.Require().Nil(result.NextCursor)
+	s.Require().Equal(uint32(1), result.Events[0].TxIndex)
+	s.Require().Equal(uint32(1), result.Events[1].EventIndex)
+}
+
+func (s *IndexerTestSuite) TestQueryInvalidCursor() {
+	_, err := s.indexer.Query(EventQuery{Cursor: []byte("invalid")})
+	s.Require().Error(err)
+}
+
+func TestGoLevelDBBackend(t *testing.T) {
+	db, err := dbm.NewGoLevelDB("events", t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	indexer := NewEventIndexer(db)
+	if err := indexer.IndexEvents(1, 0, []interfaces.ResultEvent{newTransferEvent("contract1", "alice")}); err != nil {
+		t.Fatalf("failed to index events: %v", err)
+	}
+
+	result, err := indexer.Query(EventQuery{ContractId: "contract1"})
+	if err != nil {
+		t.Fatalf("failed to query events: %v", err)
+	}
+	if len(result.Events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(result.Events))
+	}
+}
@@ -0,0 +1,88 @@
+package indexer
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const EVENT_PREFIX = "events/"
+
+const CONTRACT_INDEX_PREFIX = "index/contract/"
+const EVENT_NAME_INDEX_PREFIX = "index/event/"
+const ATTRIBUTE_KEY_INDEX_PREFIX = "index/attribute_key/"
+const ATTRIBUTE_INDEX_PREFIX = "index/attribute/"
+
+// positionSize is the size of the encoded (height, tx index, event index) position
+const positionSize = 8 + 4 + 4
+
+// position locates an event in the chain, it is encoded in big endian so
+// that the keys of every index are sorted by execution order.
+type position struct {
+	height     uint64
+	txIndex    uint32
+	eventIndex uint32
+}
+
+func (p position) bytes() []byte {
+	bz := make([]byte, positionSize)
+	binary.BigEndian.PutUint64(bz[0:8], p.height)
+	binary.BigEndian.PutUint32(bz[8:12], p.txIndex)
+	binary.BigEndian.PutUint32(bz[12:16], p.eventIndex)
+	return bz
+}
+
+func parsePosition(bz []byte) (position, error) {
+	if len(bz) != positionSize {
+		return position{}, fmt.Errorf("invalid event position size: %d", len(bz))
+	}
+
+	return position{
+		height:     binary.BigEndian.Uint64(bz[0:8]),
+		txIndex:    binary.BigEndian.Uint32(bz[8:12]),
+		eventIndex: binary.BigEndian.Uint32(bz[12:16]),
+	}, nil
+}
+
+// appendLengthPrefixed appends the part prefixed by its length, so that
+// variable-length parts can not collide with each other in the index keys.
+func appendLengthPrefixed(bz []byte, part []byte) []byte {
+	bz = binary.BigEndian.AppendUint32(bz, uint32(len(part)))
+	return append(bz, part...)
+}
+
+func newEventKey(pos position) []byte {
+	return append([]byte(EVENT_PREFIX), pos.bytes()...)
+}
+
+func newContractIndexPrefix(contractId string) []byte {
+	return appendLengthPrefixed([]byte(CONTRACT_INDEX_PREFIX), []byte(contractId))
+}
+
+func newEventNameIndexPrefix(event string) []byte {
+	return appendLengthPrefixed([]byte(EVENT_NAME_INDEX_PREFIX), []byte(event))
+}
+
+func newAttributeKeyIndexPrefix(key string) []byte {
+	return appendLengthPrefixed([]byte(ATTRIBUTE_KEY_INDEX_PREFIX), []byte(key))
+}
+
+func newAttributeIndexPrefix(key string, value []byte) []byte {
+	prefix := appendLengthPrefixed([]byte(ATTRIBUTE_INDEX_PREFIX), []byte(key))
+	return appendLengthPrefixed(prefix, value)
+}
+
+// prefixEnd returns the exclusive end key of the iteration over the prefix
+func prefixEnd(prefix []byte) []byte {
+	end := make([]byte, len(prefix))
+	copy(end, prefix)
+
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+
+	// The prefix is all 0xff, iterate to the end of the database
+	return nil
+}
@@ -0,0 +1,259 @@
+package inspector
+
+import (
+	"fmt"
+	"sort"
+
+	checker "github.com/dadamu/contract-wasmvm/internal/code-checker"
+	"github.com/dadamu/contract-wasmvm/internal/contract/runtime"
+)
+
+// Report describes the content of a module and whether it can be deployed
+type Report struct {
+	Size int `json:"size"`
+
+	Imports        []Import        `json:"imports"`
+	Exports        []Export        `json:"exports"`
+	Sections       []Section       `json:"sections"`
+	CustomSections []CustomSection `json:"custom_sections"`
+	Memories       []Memory        `json:"memories"`
+	Tables         []Table         `json:"tables"`
+
+	// Functions is the number of functions defined by the module
+	Functions int `json:"functions"`
+	// Opcodes counts the instructions of the module by opcode, most used first
+	Opcodes []OpcodeCount `json:"opcodes"`
+
+	Verdict Verdict `json:"verdict"`
+}
+
+type Import struct {
+	Module string `json:"module"`
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	// Type is the signature of functions and the type of other imports
+	Type string `json:"type"`
+}
+
+type Export struct {
+	Name  string `json:"name"`
+	Kind  string `json:"kind"`
+	Index uint32 `json:"index"`
+	// Signature is set for function exports
+	Signature string `json:"signature,omitempty"`
+}
+
+type Section struct {
+	ID     string `json:"id"`
+	Offset int    `json:"offset"`
+	Size   int    `json:"size"`
+}
+
+type CustomSection struct {
+	Name string `json:"name"`
+	Size int    `json:"size"`
+}
+
+type Memory struct {
+	Imported bool `json:"imported"`
+	// Min and Max are in pages, Max is nil if the memory is unbounded
+	Min      uint64  `json:"min"`
+	Max      *uint64 `json:"max,omitempty"`
+	Shared   bool    `json:"shared,omitempty"`
+	Memory64 bool    `json:"memory64,omitempty"`
+}
+
+type Table struct {
+	Imported bool    `json:"imported"`
+	ElemType string  `json:"elem_type"`
+	Min      uint64  `json:"min"`
+	Max      *uint64 `json:"max,omitempty"`
+}
+
+type OpcodeCount struct {
+	Opcode string `json:"opcode"`
+	Count  int    `json:"count"`
+}
+
+// Verdict tells whether the module passes the checks made at deploy time
+type Verdict struct {
+	Passed     bool                `json:"passed"`
+	Violations []checker.Violation `json:"violations"`
+}
+
+type options struct {
+	policy *checker.Policy
+	limits checker.CodeLimits
+}
+
+type Option func(*options)
+
+// WithPolicy overrides the default policy the verdict is given with
+func WithPolicy(policy *checker.Policy) Option {
+	return func(o *options) {
+		o.policy = policy
+	}
+}
+
+// WithCodeLimits overrides the default resource limits the verdict is given with
+func WithCodeLimits(limits checker.CodeLimits) Option {
+	return func(o *options) {
+		o.limits = limits
+	}
+}
+
+// Inspect decodes the module and describes its content, the verdict is given
+// with the checks made at deploy time
+func Inspect(code []byte, opts ...Option) (*Report, error) {
+	o := options{
+		policy: checker.DefaultPolicy(),
+		limits: checker.DefaultCodeLimits(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	module, err := checker.ParseModule(code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode module: %w", err)
+	}
+
+	report := &Report{
+		Size:      module.Size,
+		Functions: len(module.Functions),
+	}
+	report.addImports(module)
+	report.addExports(module)
+	report.addSections(module)
+	report.addOpcodes(module)
+
+	for _, memory := range module.Memories {
+		report.Memories = append(report.Memories, newMemory(false, memory))
+	}
+	for _, table := range module.Tables {
+		report.Tables = append(report.Tables, newTable(false, table))
+	}
+
+	checkReport := checker.CheckCodeSize(len(code), o.limits)
+	checkReport.Merge(checker.CheckDeployable(module, o.policy, runtime.HostFunctions(), o.limits))
+	report.Verdict = Verdict{
+		Passed:     checkReport.Passed(),
+		Violations: checkReport.Violations,
+	}
+
+	return report, nil
+}
+
+func (r *Report) addImports(module *checker.Module) {
+	for _, imp := range module.Imports {
+		entry := Import{Module: imp.Module, Name: imp.Name, Kind: imp.Kind.String()}
+
+		switch imp.Kind {
+		case checker.ExternalFunc:
+			if int(imp.TypeIndex) < len(module.Types) {
+				entry.Type = module.Types[imp.TypeIndex].String()
+			}
+		case checker.ExternalTable:
+			r.Tables = append(r.Tables, newTable(true, imp.Table))
+			entry.Type = fmt.Sprintf("%s %s", imp.Table.ElemType, formatLimits(imp.Table.Limits))
+		case checker.ExternalMemory:
+			r.Memories = append(r.Memories, newMemory(true, imp.Memory))
+			entry.Type = formatLimits(imp.Memory)
+		case checker.ExternalGlobal:
+			entry.Type = imp.Global.ValType.String()
+			if imp.Global.Mutable {
+				entry.Type = "mut " + entry.Type
+			}
+		}
+
+		r.Imports = append(r.Imports, entry)
+	}
+}
+
+func (r *Report) addExports(module *checker.Module) {
+	for _, export := range module.Exports {
+		entry := Export{Name: export.Name, Kind: export.Kind.String(), Index: export.Index}
+		if export.Kind == checker.ExternalFunc {
+			if funcType, ok := module.FuncType(export.Index); ok {
+				entry.Signature = funcType.String()
+			}
+		}
+		r.Exports = append(r.Exports, entry)
+	}
+}
+
+func (r *Report) addSections(module *checker.Module) {
+	for _, section := range module.Sections {
+		r.Sections = append(r.Sections, Section{
+			ID:     section.ID.String(),
+			Offset: section.Offset,
+			Size:   len(section.Payload),
+		})
+
+		if section.ID == checker.SectionCustom {
+			r.CustomSections = append(r.CustomSections, CustomSection{
+				Name: section.Name,
+				Size: len(section.Payload),
+			})
+		}
+	}
+}
+
+func (r *Report) addOpcodes(module *checker.Module) {
+	counts := make(map[checker.Opcode]int)
+	module.WalkInstructions(func(_ *checker.Function, instruction checker.Instruction) {
+		counts[instruction.Opcode]++
+	})
+
+	for opcode, count := range counts {
+		r.Opcodes = append(r.Opcodes, OpcodeCount{Opcode: opcode.String(), Count: count})
+	}
+	sort.Slice(r.Opcodes, func(i, j int) bool {
+		if r.Opcodes[i].Count != r.Opcodes[j].Count {
+			return r.Opcodes[i].Count > r.Opcodes[j].Count
+		}
+		return r.Opcodes[i].Opcode < r.Opcodes[j].Opcode
+	})
+}
+
+func newMemory(imported bool, limits checker.Limits) Memory {
+	return Memory{
+		Imported: imported,
+		Min:      limits.Min,
+		Max:      maxOf(limits),
+		Shared:   limits.Shared,
+		Memory64: limits.Memory64,
+	}
+}
+
+func newTable(imported bool, table checker.TableType) Table {
+	return Table{
+		Imported: imported,
+		ElemType: table.ElemType.String(),
+		Min:      table.Limits.Min,
+		Max:      maxOf(table.Limits),
+	}
+}
+
+func maxOf(limits checker.Limits) *uint64 {
+	if !limits.HasMax {
+		return nil
+	}
+	max := limits.Max
+	return &max
+}
+
+// formatLimits formats the limits as "min..max" followed by the flags
+func formatLimits(limits checker.Limits) string {
+	text := fmt.Sprintf("%d..", limits.Min)
+	if limits.HasMax {
+		text += fmt.Sprint(limits.Max)
+	}
+	if limits.Shared {
+		text += " shared"
+	}
+	if limits.Memory64 {
+		text += " i64"
+	}
+	return text
+}
@@ -0,0 +1,90 @@
+package inspector
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/bytecodealliance/wasmtime-go/v31"
+	"github.com/stretchr/testify/require"
+
+	checker "github.com/dadamu/contract-wasmvm/internal/code-checker"
+)
+
+func TestInspect(t *testing.T) {
+	code, err := wasmtime.Wat2Wasm(`
+		(module
+		  (import "runtime" "db.load" (func (param i32) (result i32)))
+		  (import "env" "table" (table 2 10 funcref))
+		  (memory (export "memory") 1 4)
+		  (func (export "__new") (param i32 i32) (result i32) (i32.const 1024))
+		  (func (export "init") (param i32 i32 i32))
+		  (func $add (export "add") (param i32 i32 i32)
+		    (drop (f64.add (f64.const 1) (f64.const 2)))))
+	`)
+	require.NoError(t, err)
+
+	report, err := Inspect(code)
+	require.NoError(t, err)
+
+	require.Equal(t, len(code), report.Size)
+	require.Equal(t, 3, report.Functions)
+
+	require.Equal(t, []Import{
+		{Module: "runtime", Name: "db.load", Kind: "func", Type: "(i32) -> (i32)"},
+		{Module: "env", Name: "table", Kind: "table", Type: "funcref 2..10"},
+	}, report.Imports)
+
+	require.Len(t, report.Exports, 4)
+	require.Equal(t, Export{Name: "__new", Kind: "func", Index: 1, Signature: "(i32, i32) -> (i32)"}, report.Exports[1])
+
+	require.Len(t, report.Tables, 1)
+	require.True(t, report.Tables[0].Imported)
+	require.Len(t, report.Memories, 1)
+	require.Equal(t, uint64(4), *report.Memories[0].Max)
+
+	// The custom name section is listed with the sections
+	require.Equal(t, "name", report.CustomSections[0].Name)
+	require.Equal(t, "custom", report.Sections[len(report.Sections)-1].ID)
+
+	// f64.const appears twice, the functions end with end
+	require.Contains(t, report.Opcodes, OpcodeCount{Opcode: "f64.const", Count: 2})
+	require.Equal(t, OpcodeCount{Opcode: "end", Count: 3}, report.Opcodes[0])
+
+	// Both the floating point operation and the imported table are rejected
+	require.False(t, report.Verdict.Passed)
+	rules := make(map[string]bool)
+	for _, violation := range report.Verdict.Violations {
+		rules[violation.Rule] = true
+	}
+	require.True(t, rules[checker.RuleFloatingPoint])
+	require.True(t, rules[checker.RuleImports])
+
+	// The text output ends with the verdict
+	require.Contains(t, report.String(), "verdict: rejected with")
+}
+
+func TestInspectWithPolicy(t *testing.T) {
+	code, err := os.ReadFile("../runner/testdata/test.wasm")
+	require.NoError(t, err)
+
+	report, err := Inspect(code, WithPolicy(checker.StrictPolicy()))
+	require.NoError(t, err)
+
+	// AssemblyScript output uses bulk memory operations
+	require.False(t, report.Verdict.Passed)
+	require.Equal(t, checker.RuleBulkMemory, report.Verdict.Violations[0].Rule)
+
+	report, err = Inspect(code)
+	require.NoError(t, err)
+	require.True(t, report.Verdict.Passed)
+
+	bz, err := json.Marshal(report)
+	require.NoError(t, err)
+	require.Contains(t, string(bz), `"verdict":{"passed":true,"violations":null}`)
+}
+
+func TestInspectMalformed(t *testing.T) {
+	_, err := Inspect([]byte{0x00, 0x61, 0x73})
+	require.ErrorContains(t, err, "failed to decode module")
+}
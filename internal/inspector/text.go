@@ -0,0 +1,97 @@
+package inspector
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxListedOpcodes bounds the opcode histogram of the text output
+const maxListedOpcodes = 20
+
+// String formats the report for humans
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "size: %d bytes, %d functions\n", r.Size, r.Functions)
+
+	fmt.Fprintf(&b, "\nimports (%d):\n", len(r.Imports))
+	for _, imp := range r.Imports {
+		fmt.Fprintf(&b, "  %s.%s %s %s\n", imp.Module, imp.Name, imp.Kind, imp.Type)
+	}
+
+	fmt.Fprintf(&b, "\nexports (%d):\n", len(r.Exports))
+	for _, export := range r.Exports {
+		fmt.Fprintf(&b, "  %s %s %d", export.Name, export.Kind, export.Index)
+		if export.Signature != "" {
+			fmt.Fprintf(&b, " %s", export.Signature)
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "\nsections (%d):\n", len(r.Sections))
+	for _, section := range r.Sections {
+		fmt.Fprintf(&b, "  %-10s offset 0x%x, %d bytes\n", section.ID, section.Offset, section.Size)
+	}
+
+	if len(r.CustomSections) > 0 {
+		fmt.Fprintf(&b, "\ncustom sections (%d):\n", len(r.CustomSections))
+		for _, section := range r.CustomSections {
+			fmt.Fprintf(&b, "  %s %d bytes\n", section.Name, section.Size)
+		}
+	}
+
+	fmt.Fprintf(&b, "\nmemories (%d):\n", len(r.Memories))
+	for _, memory := range r.Memories {
+		fmt.Fprintf(&b, "  %s%s\n", formatRange(memory.Min, memory.Max, "pages"), formatFlags(memory.Imported, memory.Shared, memory.Memory64))
+	}
+
+	fmt.Fprintf(&b, "\ntables (%d):\n", len(r.Tables))
+	for _, table := range r.Tables {
+		fmt.Fprintf(&b, "  %s %s%s\n", table.ElemType, formatRange(table.Min, table.Max, "elements"), formatFlags(table.Imported, false, false))
+	}
+
+	fmt.Fprintf(&b, "\nopcodes (%d distinct):\n", len(r.Opcodes))
+	for i, opcode := range r.Opcodes {
+		if i == maxListedOpcodes {
+			fmt.Fprintf(&b, "  ... and %d more\n", len(r.Opcodes)-maxListedOpcodes)
+			break
+		}
+		fmt.Fprintf(&b, "  %-20s %d\n", opcode.Opcode, opcode.Count)
+	}
+
+	b.WriteString("\nverdict: ")
+	if r.Verdict.Passed {
+		b.WriteString("passed\n")
+	} else {
+		fmt.Fprintf(&b, "rejected with %d violations\n", len(r.Verdict.Violations))
+		for _, violation := range r.Verdict.Violations {
+			fmt.Fprintf(&b, "  %s\n", violation)
+		}
+	}
+
+	return b.String()
+}
+
+func formatRange(min uint64, max *uint64, unit string) string {
+	if max == nil {
+		return fmt.Sprintf("%d.. %s", min, unit)
+	}
+	return fmt.Sprintf("%d..%d %s", min, *max, unit)
+}
+
+func formatFlags(imported, shared, memory64 bool) string {
+	var flags []string
+	if imported {
+		flags = append(flags, "imported")
+	}
+	if shared {
+		flags = append(flags, "shared")
+	}
+	if memory64 {
+		flags = append(flags, "memory64")
+	}
+
+	if len(flags) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(flags, ", ") + ")"
+}
@@ -0,0 +1,393 @@
+// Package mempool holds the transactions waiting to be included in a block.
+// The transactions are checked without reading the state when they are added,
+// ordered by gas price then arrival and reaped in the sequence order of their
+// sender.
+package mempool
+
+import (
+	"container/heap"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	checker "github.com/dadamu/contract-wasmvm/internal/code-checker"
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+	"github.com/dadamu/contract-wasmvm/internal/contract/runtime"
+	"github.com/dadamu/contract-wasmvm/internal/transaction"
+)
+
+const (
+	// DefaultMaxTxSize is the maximum encoded size of a transaction, it leaves
+	// room for a deploy message with code of the default maximum size
+	DefaultMaxTxSize = 1 << 20
+	// DefaultMaxTxs is the maximum number of pending transactions
+	DefaultMaxTxs = 5000
+	// DefaultMaxBytes is the maximum encoded size of the pending transactions
+	DefaultMaxBytes = 64 << 20
+)
+
+var (
+	ErrTxTooLarge    = errors.New("transaction too large")
+	ErrTxInMempool   = errors.New("transaction already in mempool")
+	ErrSequenceInUse = errors.New("sequence already in mempool")
+	ErrMempoolFull   = errors.New("mempool is full")
+)
+
+// Entry is a pending transaction with the values computed when it was added
+type Entry struct {
+	Tx   *transaction.Tx
+	Hash []byte
+	// Sender is the address of the first signer, whose sequence the
+	// transaction carries
+	Sender string
+	// Size is the size of the canonical encoding
+	Size int
+
+	// arrival orders the transactions with the same gas price
+	arrival uint64
+}
+
+// before reports whether the entry has priority over the other one
+func (e *Entry) before(other *Entry) bool {
+	if e.Tx.GasPrice != other.Tx.GasPrice {
+		return e.Tx.GasPrice > other.Tx.GasPrice
+	}
+	return e.arrival < other.arrival
+}
+
+type Mempool struct {
+	mu sync.Mutex
+
+	maxTxSize int
+	maxTxs    int
+	maxBytes  int
+
+	// chainId is empty unless transactions of other chains are rejected
+	chainId string
+
+	codeLimits checker.CodeLimits
+	codePolicy *checker.Policy
+
+	// txs are the pending transactions by hex hash
+	txs map[string]*Entry
+	// senders are the pending transactions of every sender in sequence order
+	senders map[string][]*Entry
+	bytes   int
+	arrival uint64
+}
+
+type Option func(*Mempool)
+
+// WithMaxTxSize overrides the maximum encoded size of a transaction
+func WithMaxTxSize(size int) Option {
+	return func(mp *Mempool) {
+		mp.maxTxSize = size
+	}
+}
+
+// WithMaxTxs overrides the maximum number of pending transactions
+func WithMaxTxs(count int) Option {
+	return func(mp *Mempool) {
+		mp.maxTxs = count
+	}
+}
+
+// WithMaxBytes overrides the maximum encoded size of the pending transactions
+func WithMaxBytes(size int) Option {
+	return func(mp *Mempool) {
+		mp.maxBytes = size
+	}
+}
+
+// WithChainId rejects the transactions of other chains
+func WithChainId(chainId string) Option {
+	return func(mp *Mempool) {
+		mp.chainId = chainId
+	}
+}
+
+// WithCodeLimits overrides the resource limits deployed code is checked
+// against, it should match the limits of the runner
+func WithCodeLimits(limits checker.CodeLimits) Option {
+	return func(mp *Mempool) {
+		mp.codeLimits = limits
+	}
+}
+
+// WithCodePolicy overrides the policy deployed code is checked against, it
+// should match the policy of the runner
+func WithCodePolicy(policy *checker.Policy) Option {
+	return func(mp *Mempool) {
+		mp.codePolicy = policy
+	}
+}
+
+func NewMempool(opts ...Option) *Mempool {
+	mp := &Mempool{
+		maxTxSize:  DefaultMaxTxSize,
+		maxTxs:     DefaultMaxTxs,
+		maxBytes:   DefaultMaxBytes,
+		codeLimits: checker.DefaultCodeLimits(),
+		codePolicy: checker.DefaultPolicy(),
+		txs:        make(map[string]*Entry),
+		senders:    make(map[string][]*Entry),
+	}
+
+	for _, opt := range opts {
+		opt(mp)
+	}
+	return mp
+}
+
+// Add checks the encoded transaction and adds it to the mempool. When the
+// mempool is full, the transactions with the lowest priority are evicted, the
+// transaction is rejected if it has the lowest priority itself.
+func (mp *Mempool) Add(bz []byte) (*Entry, error) {
+	entry, err := mp.check(bz)
+	if err != nil {
+		return nil, err
+	}
+
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	key := hex.EncodeToString(entry.Hash)
+	if _, found := mp.txs[key]; found {
+		return nil, ErrTxInMempool
+	}
+
+	queue := mp.senders[entry.Sender]
+	i := sort.Search(len(queue), func(i int) bool {
+		return queue[i].Tx.Sequence >= entry.Tx.Sequence
+	})
+	if i < len(queue) && queue[i].Tx.Sequence == entry.Tx.Sequence {
+		return nil, fmt.Errorf("%w: account %s sequence %d", ErrSequenceInUse, entry.Sender, entry.Tx.Sequence)
+	}
+
+	mp.arrival++
+	entry.arrival = mp.arrival
+	mp.senders[entry.Sender] = append(queue[:i], append([]*Entry{entry}, queue[i:]...)...)
+	mp.txs[key] = entry
+	mp.bytes += entry.Size
+
+	for len(mp.txs) > mp.maxTxs || mp.bytes > mp.maxBytes {
+		evicted := mp.lowestPriority()
+		mp.remove(evicted)
+		if evicted == entry {
+			return nil, ErrMempoolFull
+		}
+	}
+	return entry, nil
+}
+
+// AddTx encodes the transaction and adds it to the mempool
+func (mp *Mempool) AddTx(tx *transaction.Tx) (*Entry, error) {
+	bz, err := tx.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return mp.Add(bz)
+}
+
+// check runs the checks which do not read the state: the size, the decoding,
+// the signatures and the code of the deploy messages
+func (mp *Mempool) check(bz []byte) (*Entry, error) {
+	if len(bz) > mp.maxTxSize {
+		return nil, fmt.Errorf("%w: %d bytes exceeds %d", ErrTxTooLarge, len(bz), mp.maxTxSize)
+	}
+
+	tx, err := transaction.Decode(bz)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	if err := tx.Validate(); err != nil {
+		return nil, err
+	}
+	if mp.chainId != "" && tx.ChainId != mp.chainId {
+		return nil, fmt.Errorf("invalid chain id: expected %q, got %q", mp.chainId, tx.ChainId)
+	}
+
+	sender, err := interfaces.VerifySignatures(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Undeployable code is rejected before it takes space in a block
+	for i, msg := range tx.Messages {
+		if deploy, ok := msg.(interfaces.DeployContractCodeMessage); ok {
+			if err := mp.checkCode(deploy.Code); err != nil {
+				return nil, fmt.Errorf("invalid message %d: %w", i, err)
+			}
+		}
+	}
+
+	hash, err := tx.Hash()
+	if err != nil {
+		return nil, err
+	}
+	return &Entry{Tx: tx, Hash: hash, Sender: sender, Size: len(bz)}, nil
+}
+
+func (mp *Mempool) checkCode(code []byte) error {
+	if err := checker.CheckCodeSize(len(code), mp.codeLimits).Err(); err != nil {
+		return err
+	}
+
+	module, err := checker.ParseModule(code)
+	if err != nil {
+		return fmt.Errorf("failed to check code: %w", err)
+	}
+	return checker.CheckDeployable(module, mp.codePolicy, runtime.HostFunctions(), mp.codeLimits).Err()
+}
+
+// lowestPriority returns the transaction to evict, it is the last in sequence
+// order of its sender so the others can still be included
+func (mp *Mempool) lowestPriority() *Entry {
+	var lowest *Entry
+	for _, queue := range mp.senders {
+		last := queue[len(queue)-1]
+		if lowest == nil || lowest.before(last) {
+			lowest = last
+		}
+	}
+	return lowest
+}
+
+// Reap returns up to maxTxs transactions whose gas limits sum up to maxGas at
+// most, a zero limit is unlimited. The transactions of a sender are returned
+// in consecutive sequence order starting from the sequence returned by
+// nextSequence, a nil nextSequence starts from the lowest pending sequence.
+// The transactions are kept until they are removed.
+func (mp *Mempool) Reap(maxTxs int, maxGas uint64, nextSequence func(sender string) uint64) []*Entry {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	// The candidates are the next transaction of every sender
+	candidates := &entryHeap{}
+	next := make(map[string]int, len(mp.senders))
+	for sender, queue := range mp.senders {
+		// Transactions with a used sequence are skipped until they are
+		// rechecked, and the sender is skipped if its next sequence is missing
+		if nextSequence != nil {
+			sequence := nextSequence(sender)
+			i := sort.Search(len(queue), func(i int) bool {
+				return queue[i].Tx.Sequence >= sequence
+			})
+			if i == len(queue) || queue[i].Tx.Sequence != sequence {
+				continue
+			}
+			next[sender] = i
+		}
+		heap.Push(candidates, queue[next[sender]])
+	}
+
+	var (
+		entries []*Entry
+		gas     uint64
+	)
+	for candidates.Len() > 0 && (maxTxs <= 0 || len(entries) < maxTxs) {
+		entry := heap.Pop(candidates).(*Entry)
+		// The later transactions of the sender cannot be included without it
+		if maxGas > 0 && gas+entry.Tx.GasLimit > maxGas {
+			continue
+		}
+		entries = append(entries, entry)
+		gas += entry.Tx.GasLimit
+
+		next[entry.Sender]++
+		queue := mp.senders[entry.Sender]
+		if i := next[entry.Sender]; i < len(queue) && queue[i].Tx.Sequence == entry.Tx.Sequence+1 {
+			heap.Push(candidates, queue[i])
+		}
+	}
+	return entries
+}
+
+// Remove removes the transactions with the hashes
+func (mp *Mempool) Remove(hashes ...[]byte) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	for _, hash := range hashes {
+		if entry, found := mp.txs[hex.EncodeToString(hash)]; found {
+			mp.remove(entry)
+		}
+	}
+}
+
+// Recheck removes the transactions which cannot be included anymore once a
+// block is committed: those with a sequence lower than the sequence returned
+// by nextSequence and those expiring before the height.
+func (mp *Mempool) Recheck(height uint64, nextSequence func(sender string) uint64) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	for sender, queue := range mp.senders {
+		sequence := nextSequence(sender)
+		// remove shifts the queue, so a copy is iterated
+		for _, entry := range append([]*Entry(nil), queue...) {
+			timeout := entry.Tx.TimeoutHeight
+			if entry.Tx.Sequence < sequence || (timeout > 0 && height > timeout) {
+				mp.remove(entry)
+			}
+		}
+	}
+}
+
+// Get returns the pending transaction with the hash
+func (mp *Mempool) Get(hash []byte) (*Entry, bool) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	entry, found := mp.txs[hex.EncodeToString(hash)]
+	return entry, found
+}
+
+// Size returns the number of pending transactions
+func (mp *Mempool) Size() int {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	return len(mp.txs)
+}
+
+// Bytes returns the encoded size of the pending transactions
+func (mp *Mempool) Bytes() int {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	return mp.bytes
+}
+
+func (mp *Mempool) remove(entry *Entry) {
+	delete(mp.txs, hex.EncodeToString(entry.Hash))
+	mp.bytes -= entry.Size
+
+	queue := mp.senders[entry.Sender]
+	for i := range queue {
+		if queue[i] == entry {
+			queue = append(queue[:i], queue[i+1:]...)
+			break
+		}
+	}
+	if len(queue) == 0 {
+		delete(mp.senders, entry.Sender)
+		return
+	}
+	mp.senders[entry.Sender] = queue
+}
+
+// entryHeap pops the entries by priority
+type entryHeap []*Entry
+
+func (h entryHeap) Len() int           { return len(h) }
+func (h entryHeap) Less(i, j int) bool { return h[i].before(h[j]) }
+func (h entryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *entryHeap) Push(x any)        { *h = append(*h, x.(*Entry)) }
+
+func (h *entryHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return entry
+}
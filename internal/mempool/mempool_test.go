@@ -0,0 +1,210 @@
+package mempool
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dadamu/contract-wasmvm/internal/auth"
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+	"github.com/dadamu/contract-wasmvm/internal/transaction"
+)
+
+func newKey(t *testing.T) auth.PrivKey {
+	key, err := auth.GenerateEd25519PrivKey()
+	require.NoError(t, err)
+	return key
+}
+
+func newTx(t *testing.T, key auth.PrivKey, sequence, gasPrice uint64) *transaction.Tx {
+	tx := transaction.NewTx(1000, nil, interfaces.NewContractMessage("contract", "method", nil, key.PubKey().Address()))
+	tx.ChainId = "testnet"
+	tx.Sequence = sequence
+	tx.GasPrice = gasPrice
+	require.NoError(t, tx.Sign(key))
+	return tx
+}
+
+func reapedTxs(entries []*Entry) []*transaction.Tx {
+	txs := make([]*transaction.Tx, 0, len(entries))
+	for _, entry := range entries {
+		txs = append(txs, entry.Tx)
+	}
+	return txs
+}
+
+func TestStatelessChecks(t *testing.T) {
+	mp := NewMempool(WithChainId("testnet"), WithMaxTxSize(1024))
+	key := newKey(t)
+
+	entry, err := mp.AddTx(newTx(t, key, 0, 1))
+	require.NoError(t, err)
+	require.Equal(t, key.PubKey().Address(), entry.Sender)
+
+	_, err = mp.AddTx(entry.Tx)
+	require.ErrorIs(t, err, ErrTxInMempool)
+
+	_, err = mp.AddTx(newTx(t, key, 0, 2))
+	require.ErrorIs(t, err, ErrSequenceInUse)
+
+	_, err = mp.Add(make([]byte, 1025))
+	require.ErrorIs(t, err, ErrTxTooLarge)
+
+	_, err = mp.Add([]byte{0x01, 0x00})
+	require.ErrorContains(t, err, "failed to decode transaction")
+
+	// The signatures and the chain id are checked
+	unsigned := newTx(t, key, 1, 1)
+	unsigned.Signatures = nil
+	_, err = mp.AddTx(unsigned)
+	require.ErrorIs(t, err, auth.ErrUnauthorized)
+
+	tampered := newTx(t, key, 1, 1)
+	tampered.GasPrice = 100
+	_, err = mp.AddTx(tampered)
+	require.ErrorIs(t, err, auth.ErrUnauthorized)
+
+	otherChain := newTx(t, key, 1, 1)
+	otherChain.ChainId = "mainnet"
+	otherChain.Signatures = nil
+	require.NoError(t, otherChain.Sign(key))
+	_, err = mp.AddTx(otherChain)
+	require.ErrorContains(t, err, "invalid chain id")
+
+	require.Equal(t, 1, mp.Size())
+}
+
+func TestCodeCheck(t *testing.T) {
+	mp := NewMempool()
+	key := newKey(t)
+	address := key.PubKey().Address()
+
+	code, err := os.ReadFile("../runner/testdata/test.wasm")
+	require.NoError(t, err)
+
+	deploy := func(sequence uint64, code []byte) *transaction.Tx {
+		tx := transaction.NewTx(1000, nil, interfaces.DeployContractCodeMessage{Code: code, Sender: address})
+		tx.Sequence = sequence
+		require.NoError(t, tx.Sign(key))
+		return tx
+	}
+
+	_, err = mp.AddTx(deploy(0, code))
+	require.NoError(t, err)
+
+	_, err = mp.AddTx(deploy(1, []byte{0x00, 0x61, 0x73, 0x6d, 0x02}))
+	require.ErrorContains(t, err, "invalid message 0: failed to check code")
+}
+
+func TestReapOrder(t *testing.T) {
+	mp := NewMempool()
+	alice, bob, carol := newKey(t), newKey(t), newKey(t)
+
+	// Bob pays the most but his second transaction waits for his first one,
+	// which pays less than alice
+	aliceTx := newTx(t, alice, 0, 5)
+	bobTxs := []*transaction.Tx{newTx(t, bob, 0, 1), newTx(t, bob, 1, 10)}
+	carolTxs := []*transaction.Tx{newTx(t, carol, 0, 5), newTx(t, carol, 1, 5)}
+
+	for _, tx := range []*transaction.Tx{bobTxs[1], aliceTx, carolTxs[0], bobTxs[0], carolTxs[1]} {
+		_, err := mp.AddTx(tx)
+		require.NoError(t, err)
+	}
+
+	// Same gas price transactions are ordered by arrival
+	require.Equal(t,
+		[]*transaction.Tx{aliceTx, carolTxs[0], carolTxs[1], bobTxs[0], bobTxs[1]},
+		reapedTxs(mp.Reap(0, 0, nil)))
+
+	// The reaped transactions are kept until they are removed
+	require.Equal(t, 5, mp.Size())
+	require.Equal(t, []*transaction.Tx{aliceTx, carolTxs[0]}, reapedTxs(mp.Reap(2, 0, nil)))
+
+	// A transaction exceeding the gas left skips its sender
+	require.Equal(t, []*transaction.Tx{aliceTx, carolTxs[0]}, reapedTxs(mp.Reap(0, 2500, nil)))
+
+	// Transactions with a used sequence are skipped
+	bobSequence := uint64(1)
+	nextSequence := func(sender string) uint64 {
+		if sender == bob.PubKey().Address() {
+			return bobSequence
+		}
+		return 0
+	}
+	require.Equal(t,
+		[]*transaction.Tx{bobTxs[1], aliceTx, carolTxs[0], carolTxs[1]},
+		reapedTxs(mp.Reap(0, 0, nextSequence)))
+
+	// Senders missing their next sequence are skipped
+	bobSequence = 2
+	require.Equal(t,
+		[]*transaction.Tx{aliceTx, carolTxs[0], carolTxs[1]},
+		reapedTxs(mp.Reap(0, 0, nextSequence)))
+}
+
+func TestRemoveAndRecheck(t *testing.T) {
+	mp := NewMempool()
+	alice, bob := newKey(t), newKey(t)
+
+	first, err := mp.AddTx(newTx(t, alice, 0, 1))
+	require.NoError(t, err)
+	_, err = mp.AddTx(newTx(t, alice, 1, 1))
+	require.NoError(t, err)
+
+	expiring := newTx(t, bob, 0, 1)
+	expiring.TimeoutHeight = 3
+	expiring.Signatures = nil
+	require.NoError(t, expiring.Sign(bob))
+	_, err = mp.AddTx(expiring)
+	require.NoError(t, err)
+
+	mp.Remove(first.Hash)
+	_, found := mp.Get(first.Hash)
+	require.False(t, found)
+	require.Equal(t, 2, mp.Size())
+
+	// Alice used her second sequence in another way and bob transaction expired
+	mp.Recheck(4, func(string) uint64 { return 2 })
+	require.Zero(t, mp.Size())
+	require.Zero(t, mp.Bytes())
+}
+
+func TestEviction(t *testing.T) {
+	mp := NewMempool(WithMaxTxs(3))
+	alice, bob, carol := newKey(t), newKey(t), newKey(t)
+
+	_, err := mp.AddTx(newTx(t, alice, 0, 10))
+	require.NoError(t, err)
+	_, err = mp.AddTx(newTx(t, alice, 1, 10))
+	require.NoError(t, err)
+	cheap, err := mp.AddTx(newTx(t, bob, 0, 1))
+	require.NoError(t, err)
+
+	// The cheapest transaction is evicted
+	expensive, err := mp.AddTx(newTx(t, carol, 0, 20))
+	require.NoError(t, err)
+	_, found := mp.Get(cheap.Hash)
+	require.False(t, found)
+
+	// A transaction with the lowest priority is rejected
+	_, err = mp.AddTx(newTx(t, bob, 0, 1))
+	require.ErrorIs(t, err, ErrMempoolFull)
+
+	// The last sequence of a sender is evicted first, even if it pays the same
+	_, err = mp.AddTx(newTx(t, bob, 0, 15))
+	require.NoError(t, err)
+	require.Equal(t, 3, mp.Size())
+	require.Len(t, mp.Reap(0, 0, nil), 3)
+	_, found = mp.Get(expensive.Hash)
+	require.True(t, found)
+
+	// The memory is bounded by the encoded size as well, the addresses may
+	// differ in length by a few bytes
+	mp = NewMempool(WithMaxBytes(expensive.Size + 16))
+	_, err = mp.AddTx(newTx(t, alice, 0, 1))
+	require.NoError(t, err)
+	_, err = mp.AddTx(newTx(t, bob, 0, 2))
+	require.NoError(t, err)
+	require.Equal(t, 1, mp.Size())
+}
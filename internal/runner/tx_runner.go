@@ -1,110 +1,362 @@
 package runner
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/dadamu/contract-wasmvm/internal/auth"
 	checker "github.com/dadamu/contract-wasmvm/internal/code-checker"
 	callbackqueue "github.com/dadamu/contract-wasmvm/internal/contract/callback-queue"
 	"github.com/dadamu/contract-wasmvm/internal/contract/executor"
+	"github.com/dadamu/contract-wasmvm/internal/contract/gas"
 	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+	"github.com/dadamu/contract-wasmvm/internal/contract/runtime"
+	metering "github.com/dadamu/contract-wasmvm/internal/gas-metering"
 	"github.com/dadamu/contract-wasmvm/internal/store"
 )
 
 const DEPLOY_GAS = uint64(0)
 
+var (
+	ErrInvalidChainId  = errors.New("invalid chain id")
+	ErrInvalidSequence = errors.New("invalid sequence")
+	ErrTxExpired       = errors.New("transaction expired")
+)
+
 type TxRunner struct {
 	executor executor.ContractExecutor
 	store    *store.CacheKVStore
+
+	codeLimits checker.CodeLimits
+	codePolicy *checker.Policy
+
+	// maxStackHeight is the stack height limit injected in deployed code, zero
+	// disables the limit
+	maxStackHeight uint32
+
+	// gasCosts is nil unless deployed code is instrumented with gas metering
+	gasCosts *metering.CostTable
+
+	// verifySignatures requires the senders of the messages to sign the
+	// transaction with the chain id and the sequence of the first signer
+	verifySignatures bool
+	chainId          string
+
+	// height is the height of the block the transactions are included in
+	height uint64
+}
+
+type Option func(*TxRunner)
+
+// WithCodeLimits overrides the default resource limits of deployed code
+func WithCodeLimits(limits checker.CodeLimits) Option {
+	return func(r *TxRunner) {
+		r.codeLimits = limits
+	}
+}
+
+// WithCodePolicy overrides the default policy deployed code is checked against
+func WithCodePolicy(policy *checker.Policy) Option {
+	return func(r *TxRunner) {
+		r.codePolicy = policy
+	}
+}
+
+// WithMaxStackHeight overrides the stack height limit of deployed code, zero
+// leaves recursion limited by the native stack of the engine only
+func WithMaxStackHeight(height uint32) Option {
+	return func(r *TxRunner) {
+		r.maxStackHeight = height
+	}
+}
+
+// WithGasMetering instruments deployed code to charge gas with the cost table
+// instead of the engine fuel, the executor should use runtime.NewMeteredEngine
+func WithGasMetering(costs metering.CostTable) Option {
+	return func(r *TxRunner) {
+		r.gasCosts = &costs
+	}
+}
+
+// WithSignatureVerification rejects the transactions unless they implement
+// interfaces.SignedTransaction for the chain, with valid signatures of every
+// message sender and the current sequence of the first signer. The public keys
+// of the signers are registered in the store and the sequence is incremented
+// even if the execution fails.
+func WithSignatureVerification(chainId string) Option {
+	return func(r *TxRunner) {
+		r.verifySignatures = true
+		r.chainId = chainId
+	}
 }
 
 func NewTxRunner(
 	executor executor.ContractExecutor,
 	store *store.CacheKVStore,
+	opts ...Option,
 ) *TxRunner {
-	return &TxRunner{
-		executor: executor,
-		store:    store,
+	runner := &TxRunner{
+		executor:   executor,
+		store:      store,
+		codeLimits: checker.DefaultCodeLimits(),
+		codePolicy: checker.DefaultPolicy(),
+
+		maxStackHeight: metering.DefaultMaxStackHeight,
 	}
+
+	for _, opt := range opts {
+		opt(runner)
+	}
+	return runner
+}
+
+type TxResult struct {
+	GasReport      *gas.Report
+	CallbackQueues []*callbackqueue.CallbackQueue
+	Events         []interfaces.ResultEvent
+
+	// Changeset is the ordered state updates made by the transaction
+	Changeset store.Changeset
+
+	// Included is set if the transaction was written into the store, either
+	// because it succeeded or because it was authenticated and its failed
+	// execution still incremented the sequence of the signer
+	Included bool
 }
 
-func (r *TxRunner) RunTransaction(tx interfaces.Transaction) (uint64, []*callbackqueue.CallbackQueue, []interfaces.ResultEvent, error) {
-	gasLimit := tx.GetGasLimit()
+// SetBlockHeight sets the height of the block the next transactions are
+// included in, transactions with a lower timeout height are rejected
+func (r *TxRunner) SetBlockHeight(height uint64) {
+	r.height = height
+}
+
+// RunTransaction runs the transaction messages in order on a branch of the store,
+// the branch is written into the store only if every message succeeded.
+// The result is returned even if the transaction failed, so the gas burned can be charged.
+// The errors of the underlying store are raised as panics with their original
+// value, they do not depend on the transaction so they must stop the caller.
+func (r *TxRunner) RunTransaction(tx interfaces.Transaction) (result *TxResult, err error) {
+	result = &TxResult{
+		GasReport:      gas.NewReport(tx.GetGasLimit()),
+		CallbackQueues: make([]*callbackqueue.CallbackQueue, 0),
+		Events:         make([]interfaces.ResultEvent, 0),
+		Changeset:      make(store.Changeset, 0),
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			if storeErr, ok := p.(interfaces.StoreError); ok {
+				panic(storeErr.Value)
+			}
+			result.CallbackQueues = nil
+			result.Events = nil
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	if r.verifySignatures {
+		// The authentication is written before the execution, so the sequence
+		// is incremented even if a message fails
+		authStore := r.store.Branch()
+		if err := r.authenticate(authStore, tx); err != nil {
+			result.CallbackQueues = nil
+			result.Events = nil
+			return result, err
+		}
+
+		result.Changeset = authStore.Changeset()
+		result.Included = true
+		authStore.Commit()
+	}
 
 	msgs := tx.GetMessages()
 	state := tx.GetState()
+	txStore := r.store.Branch()
 
-	callbackQueues := make([]*callbackqueue.CallbackQueue, 0)
-	resultEvents := make([]interfaces.ResultEvent, 0)
-	for _, msg := range msgs {
-		remaining, queue, events, err := r.runMessage(state, msg, gasLimit)
+	for msgIndex, msg := range msgs {
+		msgReport, queue, events, err := r.runMessage(txStore, state, msg, result.GasReport.Remaining)
+		result.GasReport.AddMessage(msgReport)
 		if err != nil {
-			return 0, nil, nil, err
+			result.CallbackQueues = nil
+			result.Events = nil
+			return result, err
 		}
 
-		gasLimit = remaining
-		callbackQueues = append(callbackQueues, queue)
-		resultEvents = append(resultEvents, events...)
+		for i := range events {
+			events[i].MsgIndex = msgIndex
+		}
+
+		result.CallbackQueues = append(result.CallbackQueues, queue)
+		result.Events = append(result.Events, events...)
 	}
 
-	return gasLimit, callbackQueues, resultEvents, nil
+	result.Changeset = append(result.Changeset, txStore.Changeset()...)
+	result.Included = true
+	txStore.Commit()
+
+	return result, nil
 }
 
-func (r *TxRunner) runMessage(state []byte, msg interfaces.VMMessage, gasLimit uint64) (uint64, *callbackqueue.CallbackQueue, []interfaces.ResultEvent, error) {
-	switch msg := msg.(type) {
+// authenticate checks the transaction is for the chain and not expired, every
+// message sender signed it and it carries the sequence of the first signer.
+// The public keys of the new signers are registered and the sequence is
+// incremented in the auth store.
+func (r *TxRunner) authenticate(authStore *store.CacheKVStore, tx interfaces.Transaction) error {
+	signedTx, ok := tx.(interfaces.SignedTransaction)
+	if !ok {
+		return fmt.Errorf("%w: transaction is not signed", auth.ErrUnauthorized)
+	}
 
-	case interfaces.DeployContractCodeMessage:
-		remaining, err := r.deployContract(msg, gasLimit)
+	if signedTx.GetChainId() != r.chainId {
+		return fmt.Errorf("%w: expected %q, got %q", ErrInvalidChainId, r.chainId, signedTx.GetChainId())
+	}
+
+	if timeout := signedTx.GetTimeoutHeight(); timeout > 0 && r.height > timeout {
+		return fmt.Errorf("%w: timeout height %d is before height %d", ErrTxExpired, timeout, r.height)
+	}
+
+	account, err := interfaces.VerifySignatures(signedTx)
+	if err != nil {
+		return err
+	}
+
+	// A replayed transaction carries a sequence already used
+	if sequence := authStore.GetSequence(account); signedTx.GetSequence() != sequence {
+		return fmt.Errorf("%w: account %s expected %d, got %d", ErrInvalidSequence, account, sequence, signedTx.GetSequence())
+	}
+
+	// The signatures are iterated instead of the signers so the keys are
+	// registered in a deterministic order
+	for _, signature := range signedTx.GetSignatures() {
+		_, found, err := authStore.GetPubKey(signature.PubKey.Address())
 		if err != nil {
-			r.store.Rollback()
-			return 0, nil, nil, err
+			return err
 		}
-		return remaining, nil, nil, nil
+		if !found {
+			authStore.SetPubKey(signature.PubKey)
+		}
+	}
+
+	authStore.IncrementSequence(account)
+	return nil
+}
+
+func (r *TxRunner) runMessage(txStore *store.CacheKVStore, state []byte, msg interfaces.VMMessage, gasLimit uint64) (*gas.MessageReport, *callbackqueue.CallbackQueue, []interfaces.ResultEvent, error) {
+	switch msg := msg.(type) {
+
+	case interfaces.DeployContractCodeMessage:
+		return r.deployContract(txStore, msg, gasLimit)
 
 	case interfaces.InitializeContractMessage:
-		remaining, callbackQueue, resultEvents, err := r.executor.InitializeContract(
-			r.store,
+		return r.executor.InitializeContract(
+			txStore,
 			state,
-			msg.CodeId,
-			msg.Args,
+			msg,
 			gasLimit,
 		)
-		if err != nil {
-			r.store.Rollback()
-			return 0, callbackQueue, resultEvents, err
-		}
-		return remaining, callbackQueue, resultEvents, nil
 
 	case interfaces.ContractMessage:
-		remaining, callbackQueue, resultEvents, err := r.executor.RunContract(r.store, state, msg, gasLimit)
-		if err != nil {
-			r.store.Rollback()
-			return 0, callbackQueue, resultEvents, err
-		}
-		return remaining, callbackQueue, resultEvents, nil
+		return r.executor.RunContract(txStore, state, msg, gasLimit)
 
 	default:
 		panic("unknown message type")
 	}
 }
 
-func (r *TxRunner) deployContract(msg interfaces.DeployContractCodeMessage, gasLimit uint64) (uint64, error) {
+// CheckStoredCode checks code stored without a deploy message, such as the
+// codes of a genesis file. The codes are exported as stored, so besides the
+// checks of the deploy, the code must import the host functions of the stack
+// limit and the gas metering the runner injects, and only those.
+func (r *TxRunner) CheckStoredCode(code []byte) error {
+	err := checker.CheckCodeSize(len(code), r.codeLimits).Err()
+	if err != nil {
+		return err
+	}
+
+	module, err := checker.ParseModule(code)
+	if err != nil {
+		return fmt.Errorf("failed to check code: %w", err)
+	}
+
+	// Code importing the gas function charges its own gas, it must not be
+	// given unlimited fuel unless the runner instruments code itself
+	hostFunctions := runtime.HostFunctions()
+	if r.gasCosts != nil {
+		if !metering.IsInstrumented(module) {
+			return runtime.ErrUnmeteredCode
+		}
+		hostFunctions = append(hostFunctions, checker.HostFunction{
+			Module: metering.GasModule,
+			Name:   metering.GasFunction,
+			Type:   metering.GasFunctionType,
+		})
+	}
+	if r.maxStackHeight > 0 {
+		if !metering.IsStackLimited(module) {
+			return fmt.Errorf("code is not stack limited")
+		}
+		hostFunctions = append(hostFunctions, checker.HostFunction{
+			Module: metering.StackModule,
+			Name:   metering.StackOverflowFunction,
+			Type:   metering.StackOverflowFunctionType,
+		})
+	}
+
+	return checker.CheckDeployable(module, r.codePolicy, hostFunctions, r.codeLimits).Err()
+}
+
+func (r *TxRunner) deployContract(txStore *store.CacheKVStore, msg interfaces.DeployContractCodeMessage, gasLimit uint64) (*gas.MessageReport, *callbackqueue.CallbackQueue, []interfaces.ResultEvent, error) {
+	report := gas.NewMessageReport()
+
 	// Consume gas limit
 	consumed := DEPLOY_GAS * uint64(len(msg.Code))
 	if consumed > gasLimit {
-		return 0, fmt.Errorf("not enough gas limit")
+		report.Add(gas.CategoryStorageWrite, gasLimit)
+		return report, nil, nil, fmt.Errorf("not enough gas limit")
+	}
+	report.Add(gas.CategoryStorageWrite, consumed)
+
+	// Reject oversized code before decoding it
+	err := checker.CheckCodeSize(len(msg.Code), r.codeLimits).Err()
+	if err != nil {
+		return report, nil, nil, err
 	}
 
-	isUndeterminstic, err := checker.ContainUndeterminsticOps(msg.Code)
+	module, err := checker.ParseModule(msg.Code)
 	if err != nil {
-		return 0, fmt.Errorf("failed to check code: %w", err)
+		return report, nil, nil, fmt.Errorf("failed to check code: %w", err)
 	}
 
-	if isUndeterminstic {
-		return 0, fmt.Errorf("code contains unsupported operations")
+	// Reject undeterministic code, code which could not be linked or run and
+	// code too expensive to compile
+	err = checker.CheckDeployable(module, r.codePolicy, runtime.HostFunctions(), r.codeLimits).Err()
+	if err != nil {
+		return report, nil, nil, err
+	}
+
+	// Limit recursion deterministically, the limited code is instrumented with
+	// gas metering afterwards so that the wrappers are charged as well
+	code := msg.Code
+	if r.maxStackHeight > 0 {
+		code, err = metering.LimitStack(module, r.maxStackHeight)
+		if err != nil {
+			return report, nil, nil, fmt.Errorf("failed to limit stack height: %w", err)
+		}
+
+		module, err = checker.ParseModule(code)
+		if err != nil {
+			return report, nil, nil, fmt.Errorf("failed to limit stack height: %w", err)
+		}
+	}
+
+	if r.gasCosts != nil {
+		code, err = metering.Instrument(module, *r.gasCosts)
+		if err != nil {
+			return report, nil, nil, fmt.Errorf("failed to instrument code: %w", err)
+		}
 	}
 
-	r.store.StoreContractCode(msg.Code)
-	gasLimit -= consumed
+	txStore.StoreContractCode(code)
 
-	return gasLimit, nil
+	return report, nil, nil, nil
 }
@@ -0,0 +1,661 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bytecodealliance/wasmtime-go/v31"
+	"github.com/cosmos/iavl"
+	dbm "github.com/cosmos/iavl/db"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/dadamu/contract-wasmvm/internal/auth"
+	checker "github.com/dadamu/contract-wasmvm/internal/code-checker"
+	"github.com/dadamu/contract-wasmvm/internal/contract/executor"
+	"github.com/dadamu/contract-wasmvm/internal/contract/gas"
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+	"github.com/dadamu/contract-wasmvm/internal/contract/runtime"
+	metering "github.com/dadamu/contract-wasmvm/internal/gas-metering"
+	"github.com/dadamu/contract-wasmvm/internal/store"
+	"github.com/dadamu/contract-wasmvm/internal/transaction"
+)
+
+type testTransaction struct {
+	gasLimit uint64
+	messages []interfaces.VMMessage
+}
+
+func (tx testTransaction) GetGasLimit() uint64                 { return tx.gasLimit }
+func (tx testTransaction) GetState() []byte                    { return []byte("state") }
+func (tx testTransaction) GetMessages() []interfaces.VMMessage { return tx.messages }
+
+type TxRunnerTestSuite struct {
+	suite.Suite
+	cache    *store.CacheKVStore
+	runner   *TxRunner
+	contract string
+}
+
+func (s *TxRunnerTestSuite) SetupTest() {
+	tree := iavl.NewMutableTree(dbm.NewMemDB(), 100, false, iavl.NewNopLogger())
+	s.cache = store.NewStore(tree).GetCached()
+
+	s.runner = NewTxRunner(*executor.NewContractExecutor(runtime.NewEngine()), s.cache)
+
+	code, err := os.ReadFile("testdata/test.wasm")
+	s.Require().NoError(err)
+
+	result, err := s.runner.RunTransaction(testTransaction{
+		gasLimit: 100_000,
+		messages: []interfaces.VMMessage{
+			interfaces.DeployContractCodeMessage{Code: code, Sender: "alice"},
+			interfaces.InitializeContractMessage{CodeId: 0, Sender: "alice"},
+		},
+	})
+	s.Require().NoError(err)
+	s.contract = result.Events[0].ContractId
+}
+
+func TestTxRunnerTestSuite(t *testing.T) {
+	suite.Run(t, new(TxRunnerTestSuite))
+}
+
+// -----------------------------------------------------------------------------
+
+func (s *TxRunnerTestSuite) TestRunTransaction() {
+	result, err := s.runner.RunTransaction(testTransaction{
+		gasLimit: 100_000,
+		messages: []interfaces.VMMessage{
+			interfaces.NewContractMessage(s.contract, "addOne", nil, "alice"),
+			interfaces.NewContractMessage(s.contract, "emitEvent", nil, "alice"),
+		},
+	})
+	s.Require().NoError(err)
+
+	s.Require().Len(result.GasReport.Messages, 2)
+	s.Require().Equal(result.GasReport.Limit-result.GasReport.Total, result.GasReport.Remaining)
+	s.Require().Contains(result.GasReport.Contracts, s.contract)
+
+	s.Require().Len(result.Events, 1)
+	s.Require().Equal(1, result.Events[0].MsgIndex)
+
+	s.Require().Len(result.Changeset, 1)
+	s.Require().Equal(store.ChangeKindEntity, result.Changeset[0].Kind)
+	s.Require().Equal(s.contract, result.Changeset[0].ContractId)
+	s.Require().Equal("test", result.Changeset[0].EntityKey)
+	s.Require().Nil(result.Changeset[0].OldValue)
+	s.Require().Equal([]byte{1, 0, 0, 0}, result.Changeset[0].NewValue)
+
+	s.Require().Equal([]byte{1, 0, 0, 0}, s.cache.LoadEntity(s.contract, "test"))
+}
+
+func (s *TxRunnerTestSuite) TestFailedTransactionDiscardsOnlyItsChanges() {
+	result, err := s.runner.RunTransaction(testTransaction{
+		gasLimit: 100_000,
+		messages: []interfaces.VMMessage{
+			interfaces.NewContractMessage(s.contract, "addOne", nil, "alice"),
+			interfaces.NewContractMessage(s.contract, "crash", nil, "alice"),
+		},
+	})
+	s.Require().Error(err)
+
+	// The gas burned is reported even though the transaction failed
+	s.Require().Len(result.GasReport.Messages, 2)
+	s.Require().NotZero(result.GasReport.Total)
+	s.Require().Empty(result.Changeset)
+
+	// The contract initialized by the previous transaction is kept
+	s.Require().Nil(s.cache.LoadEntity(s.contract, "test"))
+	_, err = s.cache.GetContractCodeByContract(s.contract)
+	s.Require().NoError(err)
+}
+
+func (s *TxRunnerTestSuite) TestFundsTransferredBeforeExecution() {
+	s.Require().NoError(s.cache.MintCoins("alice", []interfaces.Coin{interfaces.NewCoin("uatom", 100)}))
+
+	msg := interfaces.NewContractMessage(s.contract, "addOne", nil, "alice")
+	msg.Funds = []interfaces.Coin{interfaces.NewCoin("uatom", 40)}
+
+	_, err := s.runner.RunTransaction(testTransaction{
+		gasLimit: 100_000,
+		messages: []interfaces.VMMessage{msg},
+	})
+	s.Require().NoError(err)
+
+	s.Require().Equal(uint64(60), s.cache.GetBalance("alice", "uatom"))
+	s.Require().Equal(uint64(40), s.cache.GetBalance(s.contract, "uatom"))
+}
+
+func (s *TxRunnerTestSuite) TestFundsRolledBackOnFailure() {
+	s.Require().NoError(s.cache.MintCoins("alice", []interfaces.Coin{interfaces.NewCoin("uatom", 100)}))
+
+	msg := interfaces.NewContractMessage(s.contract, "crash", nil, "alice")
+	msg.Funds = []interfaces.Coin{interfaces.NewCoin("uatom", 40)}
+
+	_, err := s.runner.RunTransaction(testTransaction{
+		gasLimit: 100_000,
+		messages: []interfaces.VMMessage{msg},
+	})
+	s.Require().Error(err)
+
+	s.Require().Equal(uint64(100), s.cache.GetBalance("alice", "uatom"))
+	s.Require().Equal(uint64(0), s.cache.GetBalance(s.contract, "uatom"))
+}
+
+// newContractCode compiles a minimal contract exporting the given functions
+func (s *TxRunnerTestSuite) newContractCode(functions string) []byte {
+	code, err := wasmtime.Wat2Wasm(`
+		(module
+		  (memory (export "memory") 1)
+		  (func (export "__new") (param i32 i32) (result i32) (i32.const 1024))
+		  (func (export "init") (param i32 i32 i32))
+		  ` + functions + `)
+	`)
+	s.Require().NoError(err)
+	return code
+}
+
+func (s *TxRunnerTestSuite) TestDeployRejectsFloatingPointCode() {
+	code := s.newContractCode(`
+		(func (export "add") (param i32 i32 i32)
+		  (drop (f64.add (f64.const 1) (f64.const 2))))
+	`)
+
+	_, err := s.runner.RunTransaction(testTransaction{
+		gasLimit: 100_000,
+		messages: []interfaces.VMMessage{
+			interfaces.DeployContractCodeMessage{Code: code, Sender: "alice"},
+		},
+	})
+	s.Require().ErrorContains(err, "code contains unsupported operations")
+	s.Require().ErrorContains(err, "f64.add in function 2 (add)")
+
+	var checkErr *checker.CheckError
+	s.Require().ErrorAs(err, &checkErr)
+	s.Require().Len(checkErr.Report.Violations, 1)
+}
+
+func (s *TxRunnerTestSuite) TestDeployRejectsUnknownImport() {
+	code, err := wasmtime.Wat2Wasm(`
+		(module
+		  (import "runtime" "db.delete" (func (param i32)))
+		  (memory (export "memory") 1)
+		  (func (export "__new") (param i32 i32) (result i32) (i32.const 1024))
+		  (func (export "init") (param i32 i32 i32)))
+	`)
+	s.Require().NoError(err)
+
+	_, err = s.runner.RunTransaction(testTransaction{
+		gasLimit: 100_000,
+		messages: []interfaces.VMMessage{
+			interfaces.DeployContractCodeMessage{Code: code, Sender: "alice"},
+		},
+	})
+	s.Require().ErrorContains(err, "unknown host function runtime.db.delete")
+}
+
+func (s *TxRunnerTestSuite) TestDeployedCodeRuns() {
+	code := s.newContractCode(`
+		(func (export "run") (param i32 i32 i32))
+	`)
+
+	result, err := s.runner.RunTransaction(testTransaction{
+		gasLimit: 100_000,
+		messages: []interfaces.VMMessage{
+			interfaces.DeployContractCodeMessage{Code: code, Sender: "alice"},
+			interfaces.InitializeContractMessage{CodeId: 1, Sender: "alice"},
+		},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal("initialized", result.Events[0].Event)
+
+	data, found := result.Events[0].GetAttribute("data")
+	s.Require().True(found)
+	s.Require().Equal([]byte("true"), data)
+	sender, found := result.Events[0].GetAttribute("sender")
+	s.Require().True(found)
+	s.Require().Equal([]byte("alice"), sender)
+}
+
+func (s *TxRunnerTestSuite) TestDeployRejectsCodeExceedingLimits() {
+	limits := checker.DefaultCodeLimits()
+	limits.MaxInitialMemoryPages = 1
+
+	runner := NewTxRunner(
+		*executor.NewContractExecutor(runtime.NewEngine()),
+		s.cache,
+		WithCodeLimits(limits),
+	)
+
+	code, err := wasmtime.Wat2Wasm(`
+		(module
+		  (memory (export "memory") 2)
+		  (func (export "__new") (param i32 i32) (result i32) (i32.const 1024))
+		  (func (export "init") (param i32 i32 i32)))
+	`)
+	s.Require().NoError(err)
+
+	_, err = runner.RunTransaction(testTransaction{
+		gasLimit: 100_000,
+		messages: []interfaces.VMMessage{
+			interfaces.DeployContractCodeMessage{Code: code, Sender: "alice"},
+		},
+	})
+	s.Require().ErrorContains(err, "initial_memory_pages of 2 exceeds the maximum of 1 for memory 0")
+}
+
+func (s *TxRunnerTestSuite) TestDeployWithCodePolicy() {
+	runner := NewTxRunner(
+		*executor.NewContractExecutor(runtime.NewEngine()),
+		s.cache,
+		WithCodePolicy(checker.DevPolicy()),
+	)
+
+	code := s.newContractCode(`
+		(func (export "add") (param i32 i32 i32)
+		  (drop (f64.add (f64.const 1) (f64.const 2))))
+	`)
+
+	_, err := runner.RunTransaction(testTransaction{
+		gasLimit: 100_000,
+		messages: []interfaces.VMMessage{
+			interfaces.DeployContractCodeMessage{Code: code, Sender: "alice"},
+		},
+	})
+	s.Require().NoError(err)
+}
+
+func (s *TxRunnerTestSuite) TestDeployWithGasMetering() {
+	runner := NewTxRunner(
+		*executor.NewContractExecutor(runtime.NewMeteredEngine()),
+		s.cache,
+		WithGasMetering(metering.DefaultCostTable()),
+	)
+
+	code := s.newContractCode(`
+		(func (export "spin") (param i32 i32 i32)
+		  (loop $loop (br $loop)))
+	`)
+
+	result, err := runner.RunTransaction(testTransaction{
+		gasLimit: 100_000,
+		messages: []interfaces.VMMessage{
+			interfaces.DeployContractCodeMessage{Code: code, Sender: "alice"},
+			interfaces.InitializeContractMessage{CodeId: 1, Sender: "alice"},
+		},
+	})
+	s.Require().NoError(err)
+	contract := result.Events[0].ContractId
+
+	// The stored code is the instrumented one
+	stored, err := s.cache.GetContractCodeById(1)
+	s.Require().NoError(err)
+	module, err := checker.ParseModule(stored)
+	s.Require().NoError(err)
+	s.Require().True(metering.IsInstrumented(module))
+
+	result, err = runner.RunTransaction(testTransaction{
+		gasLimit: 100_000,
+		messages: []interfaces.VMMessage{
+			interfaces.NewContractMessage(contract, "spin", nil, "alice"),
+		},
+	})
+	s.Require().ErrorIs(err, gas.ErrOutOfGas)
+	s.Require().Equal(uint64(100_000), result.GasReport.Total)
+}
+
+func (s *TxRunnerTestSuite) TestCheckStoredCode() {
+	metered := NewTxRunner(
+		*executor.NewContractExecutor(runtime.NewMeteredEngine()),
+		s.cache,
+		WithGasMetering(metering.DefaultCostTable()),
+	)
+
+	code := s.newContractCode("")
+	_, err := metered.RunTransaction(testTransaction{
+		gasLimit: 100_000,
+		messages: []interfaces.VMMessage{
+			interfaces.DeployContractCodeMessage{Code: code, Sender: "alice"},
+		},
+	})
+	s.Require().NoError(err)
+
+	// The stored code passes the checks of the runner which deployed it
+	stored, err := s.cache.GetContractCodeById(1)
+	s.Require().NoError(err)
+	s.Require().NoError(metered.CheckStoredCode(stored))
+
+	// Runners consuming fuel reject code charging its own gas, and runners
+	// instrumenting code reject code which is not
+	s.Require().ErrorContains(s.runner.CheckStoredCode(stored), "unknown host function runtime.gas")
+	s.Require().ErrorIs(metered.CheckStoredCode(code), runtime.ErrUnmeteredCode)
+}
+
+func (s *TxRunnerTestSuite) TestUnmeteredCodeOnMeteredEngine() {
+	// The code is deployed without gas metering
+	code := s.newContractCode(`
+		(func (export "spin") (param i32 i32 i32)
+		  (loop $loop (br $loop)))
+	`)
+	_, err := s.runner.RunTransaction(testTransaction{
+		gasLimit: 100_000,
+		messages: []interfaces.VMMessage{
+			interfaces.DeployContractCodeMessage{Code: code, Sender: "alice"},
+		},
+	})
+	s.Require().NoError(err)
+
+	// The metered engine consumes no fuel, so the code would run without limit
+	runner := NewTxRunner(
+		*executor.NewContractExecutor(runtime.NewMeteredEngine()),
+		s.cache,
+		WithGasMetering(metering.DefaultCostTable()),
+	)
+	_, err = runner.RunTransaction(testTransaction{
+		gasLimit: 100_000,
+		messages: []interfaces.VMMessage{
+			interfaces.InitializeContractMessage{CodeId: 1, Sender: "alice"},
+		},
+	})
+	s.Require().ErrorIs(err, runtime.ErrUnmeteredCode)
+}
+
+func (s *TxRunnerTestSuite) TestDeployLimitsStackHeight() {
+	code := s.newContractCode(`
+		(func $recurse (export "recurse") (param i32 i32 i32)
+		  (call $recurse (local.get 0) (local.get 1) (local.get 2)))
+	`)
+
+	result, err := s.runner.RunTransaction(testTransaction{
+		gasLimit: 1_000_000,
+		messages: []interfaces.VMMessage{
+			interfaces.DeployContractCodeMessage{Code: code, Sender: "alice"},
+			interfaces.InitializeContractMessage{CodeId: 1, Sender: "alice"},
+		},
+	})
+	s.Require().NoError(err)
+	contract := result.Events[0].ContractId
+
+	_, err = s.runner.RunTransaction(testTransaction{
+		gasLimit: 1_000_000,
+		messages: []interfaces.VMMessage{
+			interfaces.NewContractMessage(contract, "recurse", nil, "alice"),
+		},
+	})
+	s.Require().ErrorIs(err, runtime.ErrStackOverflow)
+}
+
+func (s *TxRunnerTestSuite) TestDeployLimitsOperandStackHeight() {
+	// Each call keeps hundreds of operands on the stack before recursing, the
+	// frames are large enough for the native stack to overflow before a limit
+	// counting only the parameters and locals
+	depth := 500
+	var expression strings.Builder
+	for i := range depth {
+		fmt.Fprintf(&expression, "(i32.add (i32.load offset=%d (local.get 0)) ", i*4)
+	}
+	expression.WriteString("(call $deep (local.get 0))" + strings.Repeat(")", depth))
+	code := s.newContractCode(`
+		(func $deep (param i32) (result i32) ` + expression.String() + `)
+		(func (export "recurse") (param i32 i32 i32)
+		  (drop (call $deep (local.get 0))))
+	`)
+
+	result, err := s.runner.RunTransaction(testTransaction{
+		gasLimit: 1_000_000,
+		messages: []interfaces.VMMessage{
+			interfaces.DeployContractCodeMessage{Code: code, Sender: "alice"},
+			interfaces.InitializeContractMessage{CodeId: 1, Sender: "alice"},
+		},
+	})
+	s.Require().NoError(err)
+	contract := result.Events[0].ContractId
+
+	_, err = s.runner.RunTransaction(testTransaction{
+		gasLimit: 100_000_000,
+		messages: []interfaces.VMMessage{
+			interfaces.NewContractMessage(contract, "recurse", nil, "alice"),
+		},
+	})
+	s.Require().ErrorIs(err, runtime.ErrStackOverflow)
+
+	// The limit is reached through the stack overflow host function, not the
+	// native stack overflow trap
+	var trap *wasmtime.Trap
+	s.Require().False(errors.As(err, &trap))
+}
+
+func (s *TxRunnerTestSuite) TestExecutionTimeout() {
+	engine := runtime.NewInterruptibleEngine()
+	ticker := runtime.NewEpochTicker(engine)
+	defer ticker.Stop()
+
+	runner := NewTxRunner(*executor.NewContractExecutor(engine, executor.WithTimeout(50*time.Millisecond)), s.cache)
+
+	code := s.newContractCode(`
+		(func (export "spin") (param i32 i32 i32)
+		  (loop $loop (br $loop)))
+	`)
+
+	result, err := runner.RunTransaction(testTransaction{
+		gasLimit: 100_000,
+		messages: []interfaces.VMMessage{
+			interfaces.DeployContractCodeMessage{Code: code, Sender: "alice"},
+			interfaces.InitializeContractMessage{CodeId: 1, Sender: "alice"},
+		},
+	})
+	s.Require().NoError(err)
+	contract := result.Events[0].ContractId
+
+	// The gas limit is far from being reached at the deadline
+	_, err = runner.RunTransaction(testTransaction{
+		gasLimit: 1 << 50,
+		messages: []interfaces.VMMessage{
+			interfaces.NewContractMessage(contract, "spin", nil, "alice"),
+		},
+	})
+	s.Require().ErrorIs(err, runtime.ErrExecutionTimeout)
+}
+
+func (s *TxRunnerTestSuite) TestTrappingStartFunction() {
+	code := s.newContractCode(`
+		(func (export "_start")
+		  (loop $loop (br $loop)))
+	`)
+
+	// The trap of the start function fails the transaction and charges the
+	// fuel it consumed
+	result, err := s.runner.RunTransaction(testTransaction{
+		gasLimit: 100_000,
+		messages: []interfaces.VMMessage{
+			interfaces.DeployContractCodeMessage{Code: code, Sender: "alice"},
+			interfaces.InitializeContractMessage{CodeId: 1, Sender: "alice"},
+		},
+	})
+	s.Require().ErrorContains(err, "all fuel consumed")
+	s.Require().Equal(uint64(100_000), result.GasReport.Total)
+	s.Require().Equal(uint64(100_000), result.GasReport.Categories[gas.CategoryInstantiation])
+	s.Require().Equal(uint64(1), s.cache.GetTotalContractAmount())
+
+	// Metered code runs out of gas in the gas host function
+	runner := NewTxRunner(
+		*executor.NewContractExecutor(runtime.NewMeteredEngine()),
+		s.cache,
+		WithGasMetering(metering.DefaultCostTable()),
+	)
+	_, err = runner.RunTransaction(testTransaction{
+		gasLimit: 100_000,
+		messages: []interfaces.VMMessage{
+			interfaces.DeployContractCodeMessage{Code: code, Sender: "alice"},
+			interfaces.InitializeContractMessage{CodeId: 1, Sender: "alice"},
+		},
+	})
+	s.Require().ErrorIs(err, gas.ErrOutOfGas)
+}
+
+func (s *TxRunnerTestSuite) TestStorePanicIsRaised() {
+	tree := iavl.NewMutableTree(dbm.NewMemDB(), 100, false, iavl.NewNopLogger())
+	saved := store.NewStore(tree)
+	code, err := os.ReadFile("testdata/test.wasm")
+	s.Require().NoError(err)
+
+	result, err := NewTxRunner(*executor.NewContractExecutor(runtime.NewEngine()), saved.GetCached()).RunTransaction(testTransaction{
+		gasLimit: 100_000,
+		messages: []interfaces.VMMessage{
+			interfaces.DeployContractCodeMessage{Code: code, Sender: "alice"},
+			interfaces.InitializeContractMessage{CodeId: 0, Sender: "alice"},
+		},
+	})
+	s.Require().NoError(err)
+	saved.GetCached().Commit()
+	_, err = saved.SaveVersionWithId(1)
+	s.Require().NoError(err)
+
+	// The entities can no longer be read, the contract reads them in a host
+	// function
+	failing := store.NewCacheKVStore(
+		func(key []byte) []byte {
+			if strings.HasPrefix(string(key), store.CONTRACT_INITIALIZED_PREFIX+"/") {
+				panic("store unavailable")
+			}
+			value, err := tree.Get(key)
+			s.Require().NoError(err)
+			return value
+		},
+		func([]byte, []byte) {},
+		func([]byte) {},
+	)
+	runner := NewTxRunner(*executor.NewContractExecutor(runtime.NewEngine()), failing)
+
+	// The error of the store does not depend on the transaction, it is raised
+	// with its original value instead of failing the transaction
+	s.Require().PanicsWithValue("store unavailable", func() {
+		_, _ = runner.RunTransaction(testTransaction{
+			gasLimit: 100_000,
+			messages: []interfaces.VMMessage{
+				interfaces.NewContractMessage(result.Events[0].ContractId, "addOne", nil, "alice"),
+			},
+		})
+	})
+}
+
+func (s *TxRunnerTestSuite) newSigningRunner() *TxRunner {
+	return NewTxRunner(*executor.NewContractExecutor(runtime.NewEngine()), s.cache, WithSignatureVerification("testnet"))
+}
+
+func (s *TxRunnerTestSuite) newSignedTx(key auth.PrivKey, sequence uint64, messages ...interfaces.VMMessage) *transaction.Tx {
+	tx := transaction.NewTx(100_000, nil, messages...)
+	tx.ChainId = "testnet"
+	tx.Sequence = sequence
+	s.Require().NoError(tx.Sign(key))
+	return tx
+}
+
+func (s *TxRunnerTestSuite) TestSignatureVerification() {
+	runner := s.newSigningRunner()
+
+	alice, err := auth.GenerateEd25519PrivKey()
+	s.Require().NoError(err)
+	bob, err := auth.GenerateSecp256k1PrivKey()
+	s.Require().NoError(err)
+	aliceAddress, bobAddress := alice.PubKey().Address(), bob.PubKey().Address()
+
+	// Unsigned transactions are rejected
+	_, err = runner.RunTransaction(testTransaction{
+		gasLimit: 100_000,
+		messages: []interfaces.VMMessage{interfaces.NewContractMessage(s.contract, "addOne", nil, aliceAddress)},
+	})
+	s.Require().ErrorIs(err, auth.ErrUnauthorized)
+
+	// Every sender must sign
+	tx := s.newSignedTx(alice, 0,
+		interfaces.NewContractMessage(s.contract, "addOne", nil, aliceAddress),
+		interfaces.NewContractMessage(s.contract, "addOne", nil, bobAddress),
+	)
+	result, err := runner.RunTransaction(tx)
+	s.Require().ErrorIs(err, auth.ErrUnauthorized)
+	s.Require().ErrorContains(err, "sender "+bobAddress+" of message 1 did not sign")
+	s.Require().False(result.Included)
+
+	// Signatures over other bytes are rejected
+	tx.Signatures[0].Signature[0] ^= 0xff
+	s.Require().NoError(tx.Sign(bob))
+	_, err = runner.RunTransaction(tx)
+	s.Require().ErrorContains(err, "signature 0 is invalid")
+
+	tx.Signatures = nil
+	s.Require().NoError(tx.Sign(alice))
+	s.Require().NoError(tx.Sign(bob))
+	result, err = runner.RunTransaction(tx)
+	s.Require().NoError(err)
+	s.Require().True(result.Included)
+
+	// The keys of the signers are registered and the sequence of the first
+	// signer is incremented with the state changes
+	s.Require().Equal(store.ChangeKindPubKey, result.Changeset[0].Kind)
+	s.Require().Equal(aliceAddress, result.Changeset[0].Address)
+	s.Require().Equal(store.ChangeKindSequence, result.Changeset[2].Kind)
+	pubKey, found, err := s.cache.GetPubKey(bobAddress)
+	s.Require().NoError(err)
+	s.Require().True(found)
+	s.Require().Equal(bob.PubKey(), pubKey)
+	s.Require().Equal(uint64(1), s.cache.GetSequence(aliceAddress))
+	s.Require().Equal(uint64(0), s.cache.GetSequence(bobAddress))
+}
+
+func (s *TxRunnerTestSuite) TestReplayProtection() {
+	runner := s.newSigningRunner()
+
+	key, err := auth.GenerateEd25519PrivKey()
+	s.Require().NoError(err)
+	address := key.PubKey().Address()
+
+	tx := s.newSignedTx(key, 0, interfaces.NewContractMessage(s.contract, "addOne", nil, address))
+	_, err = runner.RunTransaction(tx)
+	s.Require().NoError(err)
+
+	// The same transaction cannot be included twice
+	result, err := runner.RunTransaction(tx)
+	s.Require().ErrorIs(err, ErrInvalidSequence)
+	s.Require().False(result.Included)
+
+	// Transactions of other chains are rejected
+	otherChain := s.newSignedTx(key, 1, interfaces.NewContractMessage(s.contract, "addOne", nil, address))
+	otherChain.ChainId = "mainnet"
+	_, err = runner.RunTransaction(otherChain)
+	s.Require().ErrorIs(err, ErrInvalidChainId)
+
+	// The sequence is incremented even if the execution fails
+	result, err = runner.RunTransaction(s.newSignedTx(key, 1, interfaces.NewContractMessage(s.contract, "unknownMethod", nil, address)))
+	s.Require().Error(err)
+	s.Require().True(result.Included)
+	s.Require().Len(result.Changeset, 1)
+	s.Require().Equal(store.ChangeKindSequence, result.Changeset[0].Kind)
+	s.Require().Equal(uint64(2), s.cache.GetSequence(address))
+}
+
+func (s *TxRunnerTestSuite) TestTimeoutHeight() {
+	runner := s.newSigningRunner()
+
+	key, err := auth.GenerateEd25519PrivKey()
+	s.Require().NoError(err)
+	address := key.PubKey().Address()
+
+	tx := transaction.NewTx(100_000, nil, interfaces.NewContractMessage(s.contract, "addOne", nil, address))
+	tx.ChainId = "testnet"
+	tx.TimeoutHeight = 10
+	s.Require().NoError(tx.Sign(key))
+
+	runner.SetBlockHeight(11)
+	_, err = runner.RunTransaction(tx)
+	s.Require().ErrorIs(err, ErrTxExpired)
+	s.Require().Equal(uint64(0), s.cache.GetSequence(address))
+
+	runner.SetBlockHeight(10)
+	_, err = runner.RunTransaction(tx)
+	s.Require().NoError(err)
+}
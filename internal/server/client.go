@@ -0,0 +1,252 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/dadamu/contract-wasmvm/internal/indexer"
+	"github.com/dadamu/contract-wasmvm/internal/transaction"
+)
+
+// Client calls the endpoints of a Server
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a client of the server at the base url, e.g. the url of
+// an httptest.Server
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL:    baseURL,
+		httpClient: http.DefaultClient,
+	}
+}
+
+// BroadcastTx runs the transaction and saves the state if it succeeded, a
+// failed transaction is reported by the error field of the response
+func (c *Client) BroadcastTx(tx *transaction.Tx) (*TxResponse, error) {
+	var response TxResponse
+	return &response, c.post("/txs", tx, &response)
+}
+
+// Simulate runs the transaction without saving the state
+func (c *Client) Simulate(tx *transaction.Tx) (*TxResponse, error) {
+	var response TxResponse
+	return &response, c.post("/simulate", tx, &response)
+}
+
+// Query runs a contract method without saving the state
+func (c *Client) Query(query QueryRequest) (*TxResponse, error) {
+	var response TxResponse
+	return &response, c.post("/query", query, &response)
+}
+
+// Tx returns the response of a broadcast transaction
+func (c *Client) Tx(hash string) (*TxResponse, error) {
+	var response TxResponse
+	return &response, c.get("/txs/"+url.PathEscape(hash), &response)
+}
+
+func (c *Client) Code(codeId uint64) (*CodeResponse, error) {
+	var response CodeResponse
+	return &response, c.get("/codes/"+strconv.FormatUint(codeId, 10), &response)
+}
+
+func (c *Client) Contract(contractId string) (*ContractResponse, error) {
+	var response ContractResponse
+	return &response, c.get("/contracts/"+url.PathEscape(contractId), &response)
+}
+
+func (c *Client) Account(address string) (*AccountResponse, error) {
+	var response AccountResponse
+	return &response, c.get("/accounts/"+url.PathEscape(address), &response)
+}
+
+// State reads the raw value of the key at the version, zero reads the latest version
+func (c *Client) State(key string, version uint64) (*StateResponse, error) {
+	query := url.Values{"key": {key}}
+	if version != 0 {
+		query.Set("version", strconv.FormatUint(version, 10))
+	}
+
+	var response StateResponse
+	return &response, c.get("/state?"+query.Encode(), &response)
+}
+
+func (c *Client) post(path string, request, response any) error {
+	bz, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Post(c.baseURL+path, "application/json", bytes.NewReader(bz))
+	if err != nil {
+		return err
+	}
+	return decodeResponse(resp, response)
+}
+
+func (c *Client) get(path string, response any) error {
+	resp, err := c.httpClient.Get(c.baseURL + path)
+	if err != nil {
+		return err
+	}
+	return decodeResponse(resp, response)
+}
+
+// decodeResponse decodes the body into response, error statuses are returned
+// as errors with the message of the server
+func decodeResponse(resp *http.Response, response any) error {
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResponse ErrorResponse
+		if err := json.Unmarshal(body, &errResponse); err != nil || errResponse.Error == "" {
+			return fmt.Errorf("request failed with status %d", resp.StatusCode)
+		}
+		return errors.New(errResponse.Error)
+	}
+
+	return json.Unmarshal(body, response)
+}
+
+// Subscribe streams the committed events matching the filter, starting with the
+// indexed events from the height if it is not zero
+func (c *Client) Subscribe(ctx context.Context, filter EventFilter, fromHeight uint64) (*Subscription, error) {
+	return c.subscribe(ctx, filter, fromHeight, "")
+}
+
+// Resume streams the committed events matching the filter after the event
+// with the id, e.g. the last one received by a closed subscription
+func (c *Client) Resume(ctx context.Context, filter EventFilter, lastEventId string) (*Subscription, error) {
+	return c.subscribe(ctx, filter, 0, lastEventId)
+}
+
+func (c *Client) subscribe(ctx context.Context, filter EventFilter, fromHeight uint64, lastEventId string) (*Subscription, error) {
+	query := url.Values{}
+	if filter.ContractId != "" {
+		query.Set("contract", filter.ContractId)
+	}
+	if filter.Event != "" {
+		query.Set("event", filter.Event)
+	}
+	if fromHeight != 0 {
+		query.Set("from_height", strconv.FormatUint(fromHeight, 10))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/events?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventId != "" {
+		req.Header.Set("Last-Event-ID", lastEventId)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, decodeResponse(resp, nil)
+	}
+
+	return &Subscription{
+		body:        resp.Body,
+		reader:      bufio.NewReader(resp.Body),
+		lastEventId: lastEventId,
+	}, nil
+}
+
+// Subscription reads the server-sent events of an event stream
+type Subscription struct {
+	body        io.ReadCloser
+	reader      *bufio.Reader
+	lastEventId string
+}
+
+// Next blocks until the next event, the stream errors of the server are
+// returned as errors
+func (s *Subscription) Next() (indexer.IndexedEvent, error) {
+	for {
+		id, name, data, err := s.readMessage()
+		if err != nil {
+			return indexer.IndexedEvent{}, err
+		}
+
+		switch name {
+		case "error":
+			var errResponse ErrorResponse
+			if err := json.Unmarshal(data, &errResponse); err != nil {
+				return indexer.IndexedEvent{}, err
+			}
+			return indexer.IndexedEvent{}, errors.New(errResponse.Error)
+
+		case "":
+			var event indexer.IndexedEvent
+			if err := json.Unmarshal(data, &event); err != nil {
+				return indexer.IndexedEvent{}, err
+			}
+			s.lastEventId = id
+			return event, nil
+		}
+	}
+}
+
+// LastEventId returns the id of the last event received, to resume from
+func (s *Subscription) LastEventId() string {
+	return s.lastEventId
+}
+
+func (s *Subscription) Close() error {
+	return s.body.Close()
+}
+
+// readMessage reads the fields of a message up to the blank line ending it
+func (s *Subscription) readMessage() (id, name string, data []byte, err error) {
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return "", "", nil, err
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if id == "" && name == "" && data == nil {
+				continue
+			}
+			return id, name, data, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ": ")
+		switch field {
+		case "id":
+			id = value
+		case "event":
+			name = value
+		case "data":
+			if data != nil {
+				data = append(data, '\n')
+			}
+			data = append(data, value...)
+		}
+	}
+}
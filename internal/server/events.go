@@ -0,0 +1,243 @@
+package server
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+	"github.com/dadamu/contract-wasmvm/internal/indexer"
+)
+
+// DefaultSubscriptionBuffer is the number of events queued for a subscriber
+// before it is considered too slow and disconnected
+const DefaultSubscriptionBuffer = 256
+
+// subscriptionHeartbeat is the interval of the comments keeping idle streams open
+const subscriptionHeartbeat = 15 * time.Second
+
+// EventFilter matches the events of the contract with the name, empty fields
+// match any value
+type EventFilter struct {
+	ContractId string
+	Event      string
+}
+
+func (f EventFilter) match(event interfaces.ResultEvent) bool {
+	if f.ContractId != "" && event.ContractId != f.ContractId {
+		return false
+	}
+	return f.Event == "" || event.Event == f.Event
+}
+
+type subscription struct {
+	filter EventFilter
+	events chan indexer.IndexedEvent
+}
+
+// eventBus delivers the committed events to the subscriptions, a subscription
+// whose buffer is full is closed instead of blocking the commits
+type eventBus struct {
+	mu            sync.Mutex
+	bufferSize    int
+	subscriptions map[*subscription]struct{}
+}
+
+func newEventBus(bufferSize int) *eventBus {
+	return &eventBus{
+		bufferSize:    bufferSize,
+		subscriptions: make(map[*subscription]struct{}),
+	}
+}
+
+func (b *eventBus) subscribe(filter EventFilter) *subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &subscription{
+		filter: filter,
+		events: make(chan indexer.IndexedEvent, b.bufferSize),
+	}
+	b.subscriptions[sub] = struct{}{}
+	return sub
+}
+
+func (b *eventBus) unsubscribe(sub *subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.subscriptions, sub)
+}
+
+func (b *eventBus) publish(events []indexer.IndexedEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscriptions {
+	deliver:
+		for _, event := range events {
+			if !sub.filter.match(event.Event) {
+				continue
+			}
+
+			select {
+			case sub.events <- event:
+			default:
+				// The subscriber fell behind, closing the channel tells it to
+				// resume from the last event it received
+				close(sub.events)
+				delete(b.subscriptions, sub)
+				break deliver
+			}
+		}
+	}
+}
+
+// handleEvents streams the committed events matching the filter as server-sent
+// events. The stream resumes after the Last-Event-ID header or at the
+// from_height parameter by replaying the indexed events first.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	filter := EventFilter{
+		ContractId: r.URL.Query().Get("contract"),
+		Event:      r.URL.Query().Get("event"),
+	}
+	query := indexer.EventQuery{ContractId: filter.ContractId, Event: filter.Event}
+
+	resume := false
+	if fromHeight := r.URL.Query().Get("from_height"); fromHeight != "" {
+		height, err := strconv.ParseUint(fromHeight, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid height: %w", err))
+			return
+		}
+		query.FromHeight = height
+		resume = true
+	}
+	if lastEventId := r.Header.Get("Last-Event-ID"); lastEventId != "" {
+		cursor, err := hex.DecodeString(lastEventId)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid last event id: %w", err))
+			return
+		}
+		query.Cursor = cursor
+		resume = true
+	}
+	if resume && s.indexer == nil {
+		writeError(w, http.StatusBadRequest, errors.New("resuming a subscription requires an event indexer"))
+		return
+	}
+
+	// Subscribing between two commits splits the events into the indexed
+	// heights, which are replayed, and the live ones
+	s.mu.Lock()
+	sub := s.events.subscribe(filter)
+	height := s.store.Version()
+	s.mu.Unlock()
+	defer s.events.unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	lastEventId := hex.EncodeToString(query.Cursor)
+	if resume && height > 0 && query.FromHeight <= height {
+		query.ToHeight = height
+		var err error
+		if lastEventId, err = s.replayEvents(w, query); err != nil {
+			writeStreamError(w, err)
+			return
+		}
+		flusher.Flush()
+	}
+
+	heartbeat := time.NewTicker(subscriptionHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+
+		case event, ok := <-sub.events:
+			if !ok {
+				writeStreamError(w, fmt.Errorf("subscription buffer overflowed after event %q, resume with it as last event id", lastEventId))
+				return
+			}
+			lastEventId, _ = writeStreamEvent(w, event)
+			flusher.Flush()
+		}
+	}
+}
+
+// replayEvents writes the indexed events of the query page by page and returns
+// the id of the last one
+func (s *Server) replayEvents(w io.Writer, query indexer.EventQuery) (string, error) {
+	query.Limit = indexer.MaxQueryLimit
+
+	lastEventId := hex.EncodeToString(query.Cursor)
+	for {
+		result, err := s.indexer.Query(query)
+		if err != nil {
+			return lastEventId, err
+		}
+
+		for _, event := range result.Events {
+			if lastEventId, err = writeStreamEvent(w, event); err != nil {
+				return lastEventId, err
+			}
+		}
+
+		if result.NextCursor == nil {
+			return lastEventId, nil
+		}
+		query.Cursor = result.NextCursor
+	}
+}
+
+// writeStreamEvent writes the event with its cursor as id and returns the id
+func writeStreamEvent(w io.Writer, event indexer.IndexedEvent) (string, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+
+	id := hex.EncodeToString(event.Cursor())
+	_, err = fmt.Fprintf(w, "id: %s\ndata: %s\n\n", id, data)
+	return id, err
+}
+
+func writeStreamError(w io.Writer, err error) {
+	data, _ := json.Marshal(ErrorResponse{Error: err.Error()})
+	fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+}
+
+// indexedEvents positions the events of the transaction committed at the height
+func indexedEvents(height uint64, txIndex uint32, events []interfaces.ResultEvent) []indexer.IndexedEvent {
+	indexed := make([]indexer.IndexedEvent, 0, len(events))
+	for i, event := range events {
+		indexed = append(indexed, indexer.IndexedEvent{
+			Height:     height,
+			TxIndex:    txIndex,
+			EventIndex: uint32(i),
+			Event:      event,
+		})
+	}
+	return indexed
+}
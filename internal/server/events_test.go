@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+	"github.com/dadamu/contract-wasmvm/internal/indexer"
+)
+
+func (s *ServerTestSuite) subscribe(filter EventFilter, fromHeight uint64) *Subscription {
+	sub, err := s.client.Subscribe(context.Background(), filter, fromHeight)
+	s.Require().NoError(err)
+	return sub
+}
+
+func (s *ServerTestSuite) broadcast(method string) {
+	response, err := s.client.BroadcastTx(s.executeTx(method))
+	s.Require().NoError(err)
+	s.Require().Empty(response.Error)
+}
+
+func (s *ServerTestSuite) TestSubscribe() {
+	sub := s.subscribe(EventFilter{ContractId: s.contract, Event: "event"}, 0)
+	defer sub.Close()
+
+	// Neither the failed transaction nor the transaction without events is delivered
+	_, err := s.client.BroadcastTx(s.executeTx("unknownMethod"))
+	s.Require().NoError(err)
+	s.broadcast("addOne")
+	s.broadcast("emitEvent")
+
+	event, err := sub.Next()
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(3), event.Height)
+	s.Require().Equal(s.contract, event.Event.ContractId)
+	s.Require().Equal("event", event.Event.Event)
+	s.Require().NotEmpty(sub.LastEventId())
+}
+
+func (s *ServerTestSuite) TestSubscribeFromHeight() {
+	s.broadcast("emitEvent")
+	s.broadcast("emitEvent")
+
+	// The indexed events are replayed before the live ones
+	sub := s.subscribe(EventFilter{}, 1)
+	defer sub.Close()
+	s.broadcast("emitEvent")
+
+	var heights []uint64
+	var names []string
+	for range 4 {
+		event, err := sub.Next()
+		s.Require().NoError(err)
+		heights = append(heights, event.Height)
+		names = append(names, event.Event.Event)
+	}
+	s.Require().Equal([]uint64{1, 2, 3, 4}, heights)
+	s.Require().Equal([]string{"initialized", "event", "event", "event"}, names)
+}
+
+func (s *ServerTestSuite) TestResume() {
+	filter := EventFilter{Event: "event"}
+	sub := s.subscribe(filter, 0)
+	s.broadcast("emitEvent")
+
+	event, err := sub.Next()
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(2), event.Height)
+	s.Require().NoError(sub.Close())
+
+	// The events committed while disconnected are delivered after resuming
+	s.broadcast("emitEvent")
+	resumed, err := s.client.Resume(context.Background(), filter, sub.LastEventId())
+	s.Require().NoError(err)
+	defer resumed.Close()
+	s.broadcast("emitEvent")
+
+	for _, height := range []uint64{3, 4} {
+		event, err := resumed.Next()
+		s.Require().NoError(err)
+		s.Require().Equal(height, event.Height)
+	}
+}
+
+func (s *ServerTestSuite) TestResumeRequiresIndexer() {
+	httpServer := httptest.NewServer(NewServer(s.store, s.runner))
+	defer httpServer.Close()
+
+	_, err := NewClient(httpServer.URL).Subscribe(context.Background(), EventFilter{}, 1)
+	s.Require().ErrorContains(err, "requires an event indexer")
+}
+
+func (s *ServerTestSuite) TestSlowSubscriberIsClosed() {
+	bus := newEventBus(1)
+	slow := bus.subscribe(EventFilter{})
+	filtered := bus.subscribe(EventFilter{ContractId: "other"})
+
+	events := indexedEvents(1, 0, []interfaces.ResultEvent{
+		{ContractId: "contract", Event: "first"},
+		{ContractId: "contract", Event: "second"},
+	})
+	bus.publish(events)
+
+	// The slow subscription keeps the buffered event and is then closed
+	received := []indexer.IndexedEvent{}
+	for event := range slow.events {
+		received = append(received, event)
+	}
+	s.Require().Equal(events[:1], received)
+
+	s.Require().Len(bus.subscriptions, 1)
+	s.Require().Contains(bus.subscriptions, filtered)
+}
@@ -0,0 +1,48 @@
+package server
+
+import "container/list"
+
+// DefaultMaxResults is the number of transaction results kept by the server
+const DefaultMaxResults = 10_000
+
+// resultStore keeps the latest transaction results by hash, the oldest result
+// is evicted once the maximum is reached
+type resultStore struct {
+	max     int
+	results map[string]*list.Element
+	// order holds the responses from the oldest to the latest stored
+	order *list.List
+}
+
+func newResultStore(max int) *resultStore {
+	return &resultStore{
+		max:     max,
+		results: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func (rs *resultStore) get(hash string) (*TxResponse, bool) {
+	element, found := rs.results[hash]
+	if !found {
+		return nil, false
+	}
+	return element.Value.(*TxResponse), true
+}
+
+// set stores the response as the latest result, replacing the result of the
+// same hash
+func (rs *resultStore) set(response *TxResponse) {
+	if element, found := rs.results[response.Hash]; found {
+		element.Value = response
+		rs.order.MoveToBack(element)
+		return
+	}
+
+	rs.results[response.Hash] = rs.order.PushBack(response)
+	for rs.order.Len() > rs.max {
+		oldest := rs.order.Front()
+		rs.order.Remove(oldest)
+		delete(rs.results, oldest.Value.(*TxResponse).Hash)
+	}
+}
@@ -0,0 +1,434 @@
+// Package server exposes a local node over HTTP, transactions are run by a
+// TxRunner and saved as a new version of the store.
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/dadamu/contract-wasmvm/internal/block"
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+	"github.com/dadamu/contract-wasmvm/internal/indexer"
+	"github.com/dadamu/contract-wasmvm/internal/mempool"
+	"github.com/dadamu/contract-wasmvm/internal/runner"
+	"github.com/dadamu/contract-wasmvm/internal/store"
+	"github.com/dadamu/contract-wasmvm/internal/transaction"
+)
+
+// DefaultQueryGasLimit is the gas limit of queries which do not set one
+const DefaultQueryGasLimit = 10_000_000
+
+// maxRequestSize bounds the body of requests, deploy messages carry the code
+const maxRequestSize = 8 << 20
+
+type Server struct {
+	// mu serializes the requests using the runner, which runs on the shared cache
+	mu     sync.Mutex
+	store  *store.Store
+	runner *runner.TxRunner
+
+	// queryRunner runs the queries and simulations, whose state is never saved
+	queryRunner *runner.TxRunner
+
+	// results are the latest responses of the broadcast transactions by hash
+	results *resultStore
+
+	// indexer is nil unless the committed events are indexed, which is
+	// required to resume subscriptions
+	indexer *indexer.EventIndexer
+	events  *eventBus
+
+	// mempool is nil unless the broadcast transactions wait to be included in
+	// a block instead of being run right away
+	mempool *mempool.Mempool
+
+	mux *http.ServeMux
+}
+
+type Option func(*Server)
+
+// WithEventIndexer indexes the events of the committed transactions, so
+// subscriptions can resume from a past height
+func WithEventIndexer(ei *indexer.EventIndexer) Option {
+	return func(s *Server) {
+		s.indexer = ei
+	}
+}
+
+// WithSubscriptionBuffer overrides the number of events queued for a subscriber
+func WithSubscriptionBuffer(size int) Option {
+	return func(s *Server) {
+		s.events = newEventBus(size)
+	}
+}
+
+// WithMaxResults overrides the number of transaction results kept, the results
+// of older transactions are no longer found
+func WithMaxResults(max int) Option {
+	return func(s *Server) {
+		s.results = newResultStore(max)
+	}
+}
+
+// WithMempool adds the broadcast transactions to the mempool, they are run
+// when ExecuteBlock is called
+func WithMempool(mp *mempool.Mempool) Option {
+	return func(s *Server) {
+		s.mempool = mp
+	}
+}
+
+// WithQueryRunner runs the queries and simulations with the runner instead of
+// the runner of the transactions, e.g. to bound their execution time with
+// executor.WithTimeout, which must never be done for transactions. Queries are
+// not signed, so the runner must not verify signatures. The runner must run on
+// the cache of the store.
+func WithQueryRunner(r *runner.TxRunner) Option {
+	return func(s *Server) {
+		s.queryRunner = r
+	}
+}
+
+// NewServer creates a server running transactions with the runner, the runner
+// must run on the cache of the store
+func NewServer(s *store.Store, r *runner.TxRunner, opts ...Option) *Server {
+	server := &Server{
+		store:       s,
+		runner:      r,
+		queryRunner: r,
+		results:     newResultStore(DefaultMaxResults),
+		events:      newEventBus(DefaultSubscriptionBuffer),
+		mux:         http.NewServeMux(),
+	}
+
+	for _, opt := range opts {
+		opt(server)
+	}
+
+	server.mux.HandleFunc("POST /txs", server.handleBroadcast)
+	server.mux.HandleFunc("POST /simulate", server.handleSimulate)
+	server.mux.HandleFunc("POST /query", server.handleQuery)
+	server.mux.HandleFunc("GET /txs/{hash}", server.handleTx)
+	server.mux.HandleFunc("GET /codes/{id}", server.handleCode)
+	server.mux.HandleFunc("GET /contracts/{id}", server.handleContract)
+	server.mux.HandleFunc("GET /accounts/{address}", server.handleAccount)
+	server.mux.HandleFunc("GET /state", server.handleState)
+	server.mux.HandleFunc("GET /events", server.handleEvents)
+	return server
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleBroadcast runs the transaction and saves the state as a new version if
+// it succeeded, failed transactions are reported with the error field. With a
+// mempool, the transaction is checked and reported as pending instead.
+func (s *Server) handleBroadcast(w http.ResponseWriter, r *http.Request) {
+	var tx transaction.Tx
+	if !decodeTx(w, r, &tx) {
+		return
+	}
+
+	if s.mempool != nil {
+		entry, err := s.mempool.AddTx(&tx)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, &TxResponse{Hash: hex.EncodeToString(entry.Hash), Pending: true})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	response, err := s.runTransaction(s.runner, &tx, true)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// A rejected replay does not replace the result of the included transaction
+	if _, found := s.results.get(response.Hash); !found || response.Height != 0 {
+		s.results.set(response)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleSimulate runs the transaction without saving the state
+func (s *Server) handleSimulate(w http.ResponseWriter, r *http.Request) {
+	var tx transaction.Tx
+	if !decodeTx(w, r, &tx) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	response, err := s.runTransaction(s.queryRunner, &tx, false)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleQuery runs a contract method without saving the state
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	var query QueryRequest
+	if !decodeJSON(w, r, &query) {
+		return
+	}
+
+	gasLimit := query.GasLimit
+	if gasLimit == 0 {
+		gasLimit = DefaultQueryGasLimit
+	}
+	tx := transaction.NewTx(gasLimit, nil, interfaces.NewContractMessage(query.Contract, query.Method, query.Args, query.Sender))
+	if err := tx.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	response, err := s.runTransaction(s.queryRunner, tx, false)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleTx(w http.ResponseWriter, r *http.Request) {
+	hash := r.PathValue("hash")
+
+	s.mu.Lock()
+	response, ok := s.results.get(hash)
+	s.mu.Unlock()
+
+	if !ok && s.mempool != nil {
+		if bz, err := hex.DecodeString(hash); err == nil {
+			_, ok = s.mempool.Get(bz)
+			response = &TxResponse{Hash: hash, Pending: true}
+		}
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("transaction not found: %s", hash))
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleCode(w http.ResponseWriter, r *http.Request) {
+	codeId, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid code id: %w", err))
+		return
+	}
+
+	s.mu.Lock()
+	code, err := s.store.GetCached().GetContractCodeById(codeId)
+	s.mu.Unlock()
+
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	hash := sha256.Sum256(code)
+	writeJSON(w, http.StatusOK, CodeResponse{
+		CodeId: codeId,
+		Code:   code,
+		Hash:   hex.EncodeToString(hash[:]),
+	})
+}
+
+func (s *Server) handleContract(w http.ResponseWriter, r *http.Request) {
+	contractId := r.PathValue("id")
+
+	s.mu.Lock()
+	codeId, err := s.store.GetCached().GetContractCodeId(contractId)
+	s.mu.Unlock()
+
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ContractResponse{Contract: contractId, CodeId: codeId})
+}
+
+// handleAccount returns the public key registered by the first signed
+// transaction of the account
+func (s *Server) handleAccount(w http.ResponseWriter, r *http.Request) {
+	address := r.PathValue("address")
+
+	s.mu.Lock()
+	pubKey, found, err := s.store.GetCached().GetPubKey(address)
+	s.mu.Unlock()
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Errorf("account not found: %s", address))
+		return
+	}
+	writeJSON(w, http.StatusOK, AccountResponse{Address: address, PubKey: pubKey})
+}
+
+// handleState reads the raw value of the key at the version, the latest
+// version by default
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing key"))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	version := s.store.Version()
+	if versionArg := r.URL.Query().Get("version"); versionArg != "" {
+		var err error
+		version, err = strconv.ParseUint(versionArg, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid version: %w", err))
+			return
+		}
+	}
+
+	value, err := s.store.GetVersioned([]byte(key), version)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, StateResponse{Key: key, Value: value, Version: version})
+}
+
+// runTransaction runs the transaction with the runner on the cache of the store
+// at the next height. If commit is set and the transaction was included, the cache is
+// saved as a new version and the events are indexed and published to the
+// subscriptions. The returned error is set only if the state could not be saved.
+func (s *Server) runTransaction(r *runner.TxRunner, tx *transaction.Tx, commit bool) (*TxResponse, error) {
+	hash, err := tx.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	cache := s.store.GetCached()
+	defer cache.Rollback()
+
+	version := s.store.WorkingVersion()
+	r.SetBlockHeight(version)
+
+	result, err := r.RunTransaction(tx)
+	response := &TxResponse{
+		Hash:      hex.EncodeToString(hash),
+		GasReport: result.GasReport,
+		Events:    result.Events,
+		Changeset: result.Changeset,
+	}
+	if err != nil {
+		response.Error = err.Error()
+	}
+	if !commit || !result.Included {
+		return response, nil
+	}
+
+	cache.Commit()
+	if _, err := s.store.SaveVersionWithId(version); err != nil {
+		return nil, fmt.Errorf("failed to save state: %w", err)
+	}
+
+	if s.indexer != nil {
+		if err := s.indexer.IndexEvents(version, 0, result.Events); err != nil {
+			return nil, fmt.Errorf("failed to index events: %w", err)
+		}
+	}
+	s.events.publish(indexedEvents(version, 0, result.Events))
+
+	response.Height = version
+	return response, nil
+}
+
+// ExecuteBlock executes a block of the mempool with the executor, which must
+// run on the store and the runner of the server. The results of the
+// transactions are recorded and their events published.
+func (s *Server) ExecuteBlock(executor *block.Executor) (*block.Block, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := executor.ExecuteBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, tx := range b.Txs {
+		response := newBlockTxResponse(tx)
+		response.Height = b.Height
+		s.results.set(response)
+		s.events.publish(indexedEvents(b.Height, uint32(i), tx.Result.Events))
+	}
+	for _, tx := range b.Rejected {
+		response := newBlockTxResponse(tx)
+		if _, found := s.results.get(response.Hash); !found {
+			s.results.set(response)
+		}
+	}
+	return b, nil
+}
+
+func newBlockTxResponse(tx *block.TxResult) *TxResponse {
+	response := &TxResponse{
+		Hash:      hex.EncodeToString(tx.Entry.Hash),
+		GasReport: tx.Result.GasReport,
+		Events:    tx.Result.Events,
+		Changeset: tx.Result.Changeset,
+	}
+	if tx.Err != nil {
+		response.Error = tx.Err.Error()
+	}
+	return response
+}
+
+func decodeTx(w http.ResponseWriter, r *http.Request, tx *transaction.Tx) bool {
+	if !decodeJSON(w, r, tx) {
+		return false
+	}
+	if err := tx.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return false
+	}
+	return true
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, ErrorResponse{Error: err.Error()})
+}
@@ -0,0 +1,309 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bytecodealliance/wasmtime-go/v31"
+	"github.com/cosmos/iavl"
+	dbm "github.com/cosmos/iavl/db"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/dadamu/contract-wasmvm/internal/auth"
+	"github.com/dadamu/contract-wasmvm/internal/block"
+	"github.com/dadamu/contract-wasmvm/internal/contract/executor"
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+	"github.com/dadamu/contract-wasmvm/internal/contract/runtime"
+	"github.com/dadamu/contract-wasmvm/internal/indexer"
+	"github.com/dadamu/contract-wasmvm/internal/mempool"
+	"github.com/dadamu/contract-wasmvm/internal/runner"
+	"github.com/dadamu/contract-wasmvm/internal/store"
+	"github.com/dadamu/contract-wasmvm/internal/transaction"
+)
+
+type ServerTestSuite struct {
+	suite.Suite
+	store      *store.Store
+	runner     *runner.TxRunner
+	httpServer *httptest.Server
+	client     *Client
+
+	contract string
+}
+
+func (s *ServerTestSuite) SetupTest() {
+	tree := iavl.NewMutableTree(dbm.NewMemDB(), 100, false, iavl.NewNopLogger())
+	s.store = store.NewStore(tree)
+	s.runner = runner.NewTxRunner(*executor.NewContractExecutor(runtime.NewEngine()), s.store.GetCached())
+
+	s.httpServer = httptest.NewServer(NewServer(s.store, s.runner, WithEventIndexer(indexer.NewEventIndexer(dbm.NewMemDB()))))
+	s.client = NewClient(s.httpServer.URL)
+
+	code, err := os.ReadFile("../runner/testdata/test.wasm")
+	s.Require().NoError(err)
+
+	response, err := s.client.BroadcastTx(transaction.NewTx(100_000, nil,
+		interfaces.DeployContractCodeMessage{Code: code, Sender: "alice"},
+		interfaces.InitializeContractMessage{CodeId: 0, Sender: "alice"},
+	))
+	s.Require().NoError(err)
+	s.Require().Empty(response.Error)
+	s.Require().Equal(uint64(1), response.Height)
+	s.contract = response.Events[0].ContractId
+}
+
+func (s *ServerTestSuite) TearDownTest() {
+	s.httpServer.Close()
+}
+
+func TestServerTestSuite(t *testing.T) {
+	suite.Run(t, new(ServerTestSuite))
+}
+
+// -----------------------------------------------------------------------------
+
+func (s *ServerTestSuite) executeTx(method string) *transaction.Tx {
+	return transaction.NewTx(100_000, nil, interfaces.NewContractMessage(s.contract, method, nil, "alice"))
+}
+
+func (s *ServerTestSuite) TestBroadcastTx() {
+	tx := s.executeTx("addOne")
+	response, err := s.client.BroadcastTx(tx)
+	s.Require().NoError(err)
+	s.Require().Empty(response.Error)
+	hash, err := tx.Hash()
+	s.Require().NoError(err)
+	s.Require().Equal(hex.EncodeToString(hash), response.Hash)
+	s.Require().Equal(uint64(2), response.Height)
+	s.Require().NotEmpty(response.Changeset)
+	s.Require().NotZero(response.GasReport.Total)
+
+	// The result can be fetched by hash
+	fetched, err := s.client.Tx(response.Hash)
+	s.Require().NoError(err)
+	s.Require().Equal(response, fetched)
+
+	_, err = s.client.Tx("unknown")
+	s.Require().ErrorContains(err, "transaction not found")
+}
+
+func (s *ServerTestSuite) TestMaxResults() {
+	httpServer := httptest.NewServer(NewServer(s.store, s.runner, WithMaxResults(2)))
+	defer httpServer.Close()
+	client := NewClient(httpServer.URL)
+
+	// Failed transactions are kept as well
+	var hashes []string
+	for _, method := range []string{"addOne", "unknownMethod", "emitEvent"} {
+		response, err := client.BroadcastTx(s.executeTx(method))
+		s.Require().NoError(err)
+		hashes = append(hashes, response.Hash)
+	}
+
+	// The oldest result is evicted
+	_, err := client.Tx(hashes[0])
+	s.Require().ErrorContains(err, "transaction not found")
+	for _, hash := range hashes[1:] {
+		_, err := client.Tx(hash)
+		s.Require().NoError(err)
+	}
+}
+
+func (s *ServerTestSuite) TestBroadcastFailedTx() {
+	response, err := s.client.BroadcastTx(s.executeTx("unknownMethod"))
+	s.Require().NoError(err)
+	s.Require().NotEmpty(response.Error)
+	s.Require().Zero(response.Height)
+
+	// The state is not saved
+	_, err = s.client.State("contracts/initialized/"+s.contract+"/test", 2)
+	s.Require().ErrorContains(err, "version 2 does not exist")
+}
+
+func (s *ServerTestSuite) TestInvalidTx() {
+	_, err := s.client.BroadcastTx(transaction.NewTx(100_000, nil))
+	s.Require().ErrorContains(err, "transaction has no messages")
+
+	_, err = s.client.Simulate(transaction.NewTx(100_000, nil, interfaces.DeployContractCodeMessage{Sender: "alice"}))
+	s.Require().ErrorContains(err, "deploy message has no code")
+}
+
+func (s *ServerTestSuite) TestSimulateAndQuery() {
+	response, err := s.client.Simulate(s.executeTx("addOne"))
+	s.Require().NoError(err)
+	s.Require().Empty(response.Error)
+	s.Require().Zero(response.Height)
+	s.Require().NotEmpty(response.Changeset)
+
+	response, err = s.client.Query(QueryRequest{Contract: s.contract, Method: "addOne", Sender: "alice"})
+	s.Require().NoError(err)
+	s.Require().Empty(response.Error)
+	s.Require().Equal(uint64(DefaultQueryGasLimit), response.GasReport.Limit)
+
+	// Neither saved the state
+	state, err := s.client.State("contracts/initialized/"+s.contract+"/test", 0)
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(1), state.Version)
+	s.Require().Nil(state.Value)
+}
+
+func (s *ServerTestSuite) TestQueryTimeout() {
+	engine := runtime.NewInterruptibleEngine()
+	ticker := runtime.NewEpochTicker(engine)
+	defer ticker.Stop()
+
+	queryRunner := runner.NewTxRunner(*executor.NewContractExecutor(engine, executor.WithTimeout(50*time.Millisecond)), s.store.GetCached())
+	httpServer := httptest.NewServer(NewServer(s.store, s.runner, WithQueryRunner(queryRunner)))
+	defer httpServer.Close()
+	client := NewClient(httpServer.URL)
+
+	code, err := wasmtime.Wat2Wasm(`
+		(module
+		  (memory (export "memory") 1)
+		  (func (export "__new") (param i32 i32) (result i32) (i32.const 1024))
+		  (func (export "init") (param i32 i32 i32))
+		  (func (export "spin") (param i32 i32 i32)
+		    (loop $loop (br $loop))))
+	`)
+	s.Require().NoError(err)
+
+	response, err := client.BroadcastTx(transaction.NewTx(100_000, nil,
+		interfaces.DeployContractCodeMessage{Code: code, Sender: "alice"},
+		interfaces.InitializeContractMessage{CodeId: 1, Sender: "alice"},
+	))
+	s.Require().NoError(err)
+	s.Require().Empty(response.Error)
+	contract := response.Events[0].ContractId
+
+	// The gas limit is far from being reached at the deadline
+	response, err = client.Query(QueryRequest{Contract: contract, Method: "spin", Sender: "alice", GasLimit: 1 << 50})
+	s.Require().NoError(err)
+	s.Require().Contains(response.Error, runtime.ErrExecutionTimeout.Error())
+
+	response, err = client.Simulate(transaction.NewTx(1<<50, nil, interfaces.NewContractMessage(contract, "spin", nil, "alice")))
+	s.Require().NoError(err)
+	s.Require().Contains(response.Error, runtime.ErrExecutionTimeout.Error())
+}
+
+func (s *ServerTestSuite) TestCodeAndContract() {
+	code, err := s.client.Code(0)
+	s.Require().NoError(err)
+	// The stored code is the deployed code after instrumentation
+	s.Require().Equal([]byte("\x00asm"), code.Code[:4])
+	hash := sha256.Sum256(code.Code)
+	s.Require().Equal(hex.EncodeToString(hash[:]), code.Hash)
+
+	_, err = s.client.Code(1)
+	s.Require().ErrorContains(err, "contract code not found")
+
+	contract, err := s.client.Contract(s.contract)
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(0), contract.CodeId)
+
+	_, err = s.client.Contract("unknown")
+	s.Require().ErrorContains(err, "contract does not exist")
+}
+
+func (s *ServerTestSuite) TestStateAtVersion() {
+	key := "contracts/initialized/" + s.contract + "/test"
+
+	_, err := s.client.BroadcastTx(s.executeTx("addOne"))
+	s.Require().NoError(err)
+	_, err = s.client.BroadcastTx(s.executeTx("addOne"))
+	s.Require().NoError(err)
+
+	state, err := s.client.State(key, 2)
+	s.Require().NoError(err)
+	s.Require().Equal([]byte{1, 0, 0, 0}, state.Value)
+
+	state, err = s.client.State(key, 0)
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(3), state.Version)
+	s.Require().Equal([]byte{2, 0, 0, 0}, state.Value)
+
+	_, err = s.client.State("", 0)
+	s.Require().ErrorContains(err, "missing key")
+}
+
+func (s *ServerTestSuite) TestSignedTx() {
+	r := runner.NewTxRunner(*executor.NewContractExecutor(runtime.NewEngine()), s.store.GetCached(), runner.WithSignatureVerification("testnet"))
+	httpServer := httptest.NewServer(NewServer(s.store, r))
+	defer httpServer.Close()
+	client := NewClient(httpServer.URL)
+
+	key, err := auth.GenerateEd25519PrivKey()
+	s.Require().NoError(err)
+	address := key.PubKey().Address()
+
+	_, err = client.Account(address)
+	s.Require().ErrorContains(err, "account not found")
+
+	tx := transaction.NewTx(100_000, nil, interfaces.NewContractMessage(s.contract, "unknownMethod", nil, address))
+	tx.ChainId = "testnet"
+	response, err := client.BroadcastTx(tx)
+	s.Require().NoError(err)
+	s.Require().Contains(response.Error, "transaction has no signatures")
+	s.Require().Zero(response.Height)
+
+	// A failed execution is included to increment the sequence
+	s.Require().NoError(tx.Sign(key))
+	response, err = client.BroadcastTx(tx)
+	s.Require().NoError(err)
+	s.Require().NotEmpty(response.Error)
+	s.Require().Equal(uint64(2), response.Height)
+
+	response, err = client.BroadcastTx(tx)
+	s.Require().NoError(err)
+	s.Require().Contains(response.Error, "invalid sequence")
+	s.Require().Zero(response.Height)
+
+	fetched, err := client.Tx(response.Hash)
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(2), fetched.Height)
+
+	account, err := client.Account(address)
+	s.Require().NoError(err)
+	s.Require().Equal(key.PubKey(), account.PubKey)
+}
+
+func (s *ServerTestSuite) TestMempool() {
+	r := runner.NewTxRunner(*executor.NewContractExecutor(runtime.NewEngine()), s.store.GetCached(), runner.WithSignatureVerification("testnet"))
+	mp := mempool.NewMempool(mempool.WithChainId("testnet"))
+	server := NewServer(s.store, r, WithMempool(mp))
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	client := NewClient(httpServer.URL)
+
+	key, err := auth.GenerateEd25519PrivKey()
+	s.Require().NoError(err)
+
+	// Transactions failing the mempool checks are rejected
+	tx := transaction.NewTx(100_000, nil, interfaces.NewContractMessage(s.contract, "emitEvent", nil, key.PubKey().Address()))
+	tx.ChainId = "testnet"
+	_, err = client.BroadcastTx(tx)
+	s.Require().ErrorContains(err, "transaction has no signatures")
+
+	s.Require().NoError(tx.Sign(key))
+	response, err := client.BroadcastTx(tx)
+	s.Require().NoError(err)
+	s.Require().True(response.Pending)
+
+	fetched, err := client.Tx(response.Hash)
+	s.Require().NoError(err)
+	s.Require().True(fetched.Pending)
+
+	b, err := server.ExecuteBlock(block.NewExecutor(s.store, r, mp))
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(2), b.Height)
+
+	fetched, err = client.Tx(response.Hash)
+	s.Require().NoError(err)
+	s.Require().False(fetched.Pending)
+	s.Require().Empty(fetched.Error)
+	s.Require().Equal(uint64(2), fetched.Height)
+	s.Require().Len(fetched.Events, 1)
+}
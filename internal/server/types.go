@@ -0,0 +1,58 @@
+package server
+
+import (
+	"github.com/dadamu/contract-wasmvm/internal/auth"
+	"github.com/dadamu/contract-wasmvm/internal/contract/gas"
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+	"github.com/dadamu/contract-wasmvm/internal/store"
+)
+
+type TxResponse struct {
+	// Hash is the hex encoded hash of the canonical encoding of the transaction
+	Hash string `json:"hash"`
+	// Height is the version of the state saved by the transaction, zero if it
+	// was not included or was simulated. Authenticated transactions are
+	// included even if their execution failed.
+	Height uint64 `json:"height,omitempty"`
+	// Pending is set while the transaction waits in the mempool
+	Pending   bool                     `json:"pending,omitempty"`
+	GasReport *gas.Report              `json:"gas_report"`
+	Events    []interfaces.ResultEvent `json:"events"`
+	Changeset store.Changeset          `json:"changeset"`
+	Error     string                   `json:"error,omitempty"`
+}
+
+type QueryRequest struct {
+	Contract string `json:"contract"`
+	Method   string `json:"method"`
+	Args     []byte `json:"args,omitempty"`
+	Sender   string `json:"sender"`
+	GasLimit uint64 `json:"gas_limit"`
+}
+
+type CodeResponse struct {
+	CodeId uint64 `json:"code_id"`
+	Code   []byte `json:"code"`
+	// Hash is the hex encoded SHA-256 of the code
+	Hash string `json:"hash"`
+}
+
+type ContractResponse struct {
+	Contract string `json:"contract"`
+	CodeId   uint64 `json:"code_id"`
+}
+
+type AccountResponse struct {
+	Address string      `json:"address"`
+	PubKey  auth.PubKey `json:"pub_key"`
+}
+
+type StateResponse struct {
+	Key     string `json:"key"`
+	Value   []byte `json:"value"`
+	Version uint64 `json:"version"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"strconv"
+
+	"github.com/dadamu/contract-wasmvm/internal/auth"
+)
+
+// GetPubKey returns the public key registered for the address
+func (ck *CacheKVStore) GetPubKey(
+	address string,
+) (auth.PubKey, bool, error) {
+	pubKeyBytes := ck.get(newPubKeyKey(address))
+	if pubKeyBytes == nil {
+		return auth.PubKey{}, false, nil
+	}
+
+	pubKey, err := auth.ParsePubKey(pubKeyBytes)
+	if err != nil {
+		return auth.PubKey{}, false, err
+	}
+	return pubKey, true, nil
+}
+
+// SetPubKey registers the public key for the address derived from it
+func (ck *CacheKVStore) SetPubKey(
+	pubKey auth.PubKey,
+) {
+	ck.set(newPubKeyKey(pubKey.Address()), pubKey.Bytes())
+}
+
+// GetSequence returns the sequence the next transaction of the account must carry
+func (ck *CacheKVStore) GetSequence(
+	address string,
+) uint64 {
+	sequenceBytes := ck.get(newSequenceKey(address))
+	if sequenceBytes == nil {
+		return 0
+	}
+
+	sequence, err := strconv.ParseUint(string(sequenceBytes), 10, 64)
+	if err != nil {
+		panic("failed to parse sequence")
+	}
+
+	return sequence
+}
+
+func (ck *CacheKVStore) IncrementSequence(
+	address string,
+) {
+	sequence := ck.GetSequence(address) + 1
+	ck.set(newSequenceKey(address), []byte(strconv.FormatUint(sequence, 10)))
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+)
+
+func (ck *CacheKVStore) GetBalance(
+	address, denom string,
+) uint64 {
+	balanceBytes := ck.get(newBalanceKey(address, denom))
+	if balanceBytes == nil {
+		return 0
+	}
+
+	balance, err := strconv.ParseUint(string(balanceBytes), 10, 64)
+	if err != nil {
+		panic("failed to parse balance")
+	}
+
+	return balance
+}
+
+func (ck *CacheKVStore) TransferCoins(
+	from, to string, coins []interfaces.Coin,
+) error {
+	for _, coin := range coins {
+		if coin.Denom == "" {
+			return fmt.Errorf("invalid coin denom: empty")
+		}
+
+		fromBalance := ck.GetBalance(from, coin.Denom)
+		if fromBalance < coin.Amount {
+			return fmt.Errorf("insufficient balance of %s: %d < %d", coin.Denom, fromBalance, coin.Amount)
+		}
+		ck.setBalance(from, coin.Denom, fromBalance-coin.Amount)
+
+		toBalance := ck.GetBalance(to, coin.Denom)
+		if toBalance > math.MaxUint64-coin.Amount {
+			return fmt.Errorf("balance overflow of %s for %s", coin.Denom, to)
+		}
+		ck.setBalance(to, coin.Denom, toBalance+coin.Amount)
+	}
+
+	return nil
+}
+
+// MintCoins creates new coins in the given address, it is used to set up
+// genesis balances and faucets outside of the contract execution.
+func (ck *CacheKVStore) MintCoins(
+	address string, coins []interfaces.Coin,
+) error {
+	for _, coin := range coins {
+		if coin.Denom == "" {
+			return fmt.Errorf("invalid coin denom: empty")
+		}
+
+		balance := ck.GetBalance(address, coin.Denom)
+		if balance > math.MaxUint64-coin.Amount {
+			return fmt.Errorf("balance overflow of %s for %s", coin.Denom, address)
+		}
+		ck.setBalance(address, coin.Denom, balance+coin.Amount)
+	}
+
+	return nil
+}
+
+func (ck *CacheKVStore) setBalance(address, denom string, amount uint64) {
+	ck.set(newBalanceKey(address, denom), []byte(strconv.FormatUint(amount, 10)))
+}
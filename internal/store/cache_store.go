@@ -70,6 +70,17 @@ func (ck *CacheKVStore) GetContractCodeByContract(
 	return ck.get(codeKey), nil
 }
 
+// GetContractCodeId returns the id of the code the contract was created from
+func (ck *CacheKVStore) GetContractCodeId(
+	contractId string,
+) (uint64, error) {
+	codeKey := ck.get(newContractModuleKey(contractId))
+	if codeKey == nil {
+		return 0, fmt.Errorf("contract does not exist: %s", contractId)
+	}
+	return parseContractCodeKey(codeKey)
+}
+
 func (ck *CacheKVStore) GetContractCodeById(
 	codeId uint64,
 ) ([]byte, error) {
@@ -170,6 +181,56 @@ func (ck *CacheKVStore) set(key, value []byte) {
 	}
 }
 
+func (ck *CacheKVStore) delete(key []byte) {
+	keyStr := string(key)
+	ck.cache[keyStr] = cacheValue{
+		value:   nil,
+		deleted: true,
+	}
+
+	if !ck.updatedKeyMap[keyStr] {
+		ck.updatedKeyList = append(ck.updatedKeyList, keyStr)
+		ck.updatedKeyMap[keyStr] = true
+	}
+}
+
+// Branch returns a new cache on top of this one, its updates are written
+// into this cache on commit and discarded on rollback. The panics of the
+// underlying store are raised as interfaces.StoreError by the branch.
+func (ck *CacheKVStore) Branch() *CacheKVStore {
+	return NewCacheKVStore(
+		func(key []byte) []byte {
+			defer raiseStoreError()
+			return ck.get(key)
+		},
+		ck.set,
+		ck.delete,
+	)
+}
+
+// raiseStoreError wraps a panic of the underlying store in a StoreError
+func raiseStoreError() {
+	if r := recover(); r != nil {
+		if _, ok := r.(interfaces.StoreError); ok {
+			panic(r)
+		}
+		panic(interfaces.StoreError{Value: r})
+	}
+}
+
+// Changeset returns the uncommitted updates in first update order,
+// the old values are read from the underlying store.
+func (ck *CacheKVStore) Changeset() Changeset {
+	changeset := make(Changeset, 0, len(ck.updatedKeyList))
+	for _, keyStr := range ck.updatedKeyList {
+		cached := ck.cache[keyStr]
+		oldValue := ck.getFn([]byte(keyStr))
+
+		changeset = append(changeset, newChange(keyStr, oldValue, cached.value, cached.deleted))
+	}
+	return changeset
+}
+
 func (ck *CacheKVStore) Rollback() {
 	ck.cache = make(map[string]cacheValue)
 	ck.updatedKeyList = make([]string, 0)
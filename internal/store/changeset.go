@@ -0,0 +1,106 @@
+package store
+
+import (
+	"strings"
+)
+
+type ChangeKind string
+
+const (
+	ChangeKindEntity              ChangeKind = "entity"
+	ChangeKindContractModule      ChangeKind = "contract_module"
+	ChangeKindContractInitialized ChangeKind = "contract_initialized"
+	ChangeKindCode                ChangeKind = "code"
+	ChangeKindNextCodeId          ChangeKind = "next_code_id"
+	ChangeKindBalance             ChangeKind = "balance"
+	ChangeKindPubKey              ChangeKind = "pub_key"
+	ChangeKindSequence            ChangeKind = "sequence"
+	ChangeKindUnknown             ChangeKind = "unknown"
+)
+
+// Change is a single key update, decoded into the contract or account it
+// belongs to when the key is in a known namespace.
+type Change struct {
+	Key      string `json:"key"`
+	OldValue []byte `json:"old_value"`
+	NewValue []byte `json:"new_value"`
+	Deleted  bool   `json:"deleted"`
+
+	Kind       ChangeKind `json:"kind"`
+	ContractId string     `json:"contract_id,omitempty"`
+	EntityKey  string     `json:"entity_key,omitempty"`
+	Address    string     `json:"address,omitempty"`
+	Denom      string     `json:"denom,omitempty"`
+}
+
+// Changeset is the ordered list of the key updates, in first update order.
+type Changeset []Change
+
+func newChange(key string, oldValue, newValue []byte, deleted bool) Change {
+	change := Change{
+		Key:      key,
+		OldValue: oldValue,
+		NewValue: newValue,
+		Deleted:  deleted,
+		Kind:     ChangeKindUnknown,
+	}
+
+	// The entities are stored under the initialized prefix, see newContractEntityKey
+	if rest, found := strings.CutPrefix(key, CONTRACT_INITIALIZED_PREFIX+"/"); found {
+		// Contract ids never contain a slash, the entity key may
+		contractId, entityKey, found := strings.Cut(rest, "/")
+		if found {
+			change.Kind = ChangeKindEntity
+			change.ContractId = contractId
+			change.EntityKey = entityKey
+		}
+		return change
+	}
+
+	if contractId, found := strings.CutPrefix(key, CONTRACT_MODULE_PREFIX+"/"); found {
+		change.Kind = ChangeKindContractModule
+		change.ContractId = contractId
+		return change
+	}
+
+	if contractId, found := strings.CutPrefix(key, CONTRACT_ENTITY_PREFIX+"/"); found {
+		change.Kind = ChangeKindContractInitialized
+		change.ContractId = contractId
+		return change
+	}
+
+	if _, err := parseContractCodeKey([]byte(key)); err == nil {
+		change.Kind = ChangeKindCode
+		return change
+	}
+
+	if key == CONTRACT_NEXT_CODE_ID_KEY {
+		change.Kind = ChangeKindNextCodeId
+		return change
+	}
+
+	if rest, found := strings.CutPrefix(key, BANK_BALANCE_PREFIX+"/"); found {
+		// Addresses never contain a slash, the denom may
+		address, denom, found := strings.Cut(rest, "/")
+		if found {
+			change.Kind = ChangeKindBalance
+			change.Address = address
+			change.Denom = denom
+		}
+		return change
+	}
+
+	if address, found := strings.CutPrefix(key, ACCOUNT_PUBKEY_PREFIX+"/"); found {
+		change.Kind = ChangeKindPubKey
+		change.Address = address
+		return change
+	}
+
+	if address, found := strings.CutPrefix(key, ACCOUNT_SEQUENCE_PREFIX+"/"); found {
+		change.Kind = ChangeKindSequence
+		change.Address = address
+		return change
+	}
+
+	return change
+}
@@ -2,6 +2,8 @@ package store
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 const CONTRACT_ENTITY_PREFIX = "contracts/entities"
@@ -11,8 +13,16 @@ const CONTRACT_INITIALIZED_PREFIX = "contracts/initialized"
 const CONTRACT_CODE_PREFIX = "contracts/codes"
 const CONTRACT_NEXT_CODE_ID_KEY = "contracts/next_code_id"
 
+const BANK_BALANCE_PREFIX = "bank/balances"
+
+const ACCOUNT_PUBKEY_PREFIX = "accounts/pubkeys"
+const ACCOUNT_SEQUENCE_PREFIX = "accounts/sequences"
+
 const VERSION_MAP_PREFIX = "version"
 
+// The entities are stored under the initialized prefix and the initialized
+// flags under the entity prefix. The keys are part of the app hash, so the
+// layout of the existing databases is kept.
 func newContractEntityKey(contractId string, id string) []byte {
 	key := fmt.Sprintf("%s/%s/%s", CONTRACT_INITIALIZED_PREFIX, contractId, id)
 	return []byte(key)
@@ -34,12 +44,26 @@ func newContractCodeKey(codeId uint64) []byte {
 }
 
 func parseContractCodeKey(key []byte) (uint64, error) {
-	var codeId uint64
-	_, err := fmt.Sscanf(string(key), "%s/%d", &codeId)
-	if err != nil {
-		return 0, err
+	codeIdStr, found := strings.CutPrefix(string(key), CONTRACT_CODE_PREFIX+"/")
+	if !found {
+		return 0, fmt.Errorf("invalid contract code key: %s", key)
 	}
-	return codeId, nil
+	return strconv.ParseUint(codeIdStr, 10, 64)
+}
+
+func newBalanceKey(address string, denom string) []byte {
+	key := fmt.Sprintf("%s/%s/%s", BANK_BALANCE_PREFIX, address, denom)
+	return []byte(key)
+}
+
+func newPubKeyKey(address string) []byte {
+	key := fmt.Sprintf("%s/%s", ACCOUNT_PUBKEY_PREFIX, address)
+	return []byte(key)
+}
+
+func newSequenceKey(address string) []byte {
+	key := fmt.Sprintf("%s/%s", ACCOUNT_SEQUENCE_PREFIX, address)
+	return []byte(key)
 }
 
 func newVersionKey(id uint64) []byte {
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 
@@ -72,3 +73,54 @@ func (s *Store) GetVersionById(id uint64) (uint64, error) {
 
 	return binary.BigEndian.Uint64(newVersionBytes), nil
 }
+
+// Version returns the latest saved version, zero if none was saved
+func (s *Store) Version() uint64 {
+	return uint64(s.tree.Version())
+}
+
+// GetVersioned returns the value of the key at the saved version
+func (s *Store) GetVersioned(key []byte, version uint64) ([]byte, error) {
+	if !s.tree.VersionExists(int64(version)) {
+		return nil, fmt.Errorf("version %d does not exist", version)
+	}
+	return s.tree.GetVersioned(key, int64(version))
+}
+
+// WorkingVersion returns the version saved by the next SaveVersionWithId
+func (s *Store) WorkingVersion() uint64 {
+	return uint64(s.tree.WorkingVersion())
+}
+
+type Version struct {
+	Version uint64 `json:"version"`
+	Hash    []byte `json:"hash"`
+}
+
+// Versions returns the saved versions with their root hash in ascending order
+func (s *Store) Versions() ([]Version, error) {
+	available := s.tree.AvailableVersions()
+
+	versions := make([]Version, 0, len(available))
+	for _, version := range available {
+		tree, err := s.tree.GetImmutable(int64(version))
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, Version{Version: uint64(version), Hash: tree.Hash()})
+	}
+	return versions, nil
+}
+
+// Iterate calls fn with the committed keys starting with the prefix in
+// ascending order, until fn returns false
+func (s *Store) Iterate(prefix []byte, fn func(key, value []byte) bool) error {
+	_, err := s.tree.Iterate(func(key, value []byte) bool {
+		if !bytes.HasPrefix(key, prefix) {
+			// Keys after the prefix range stop the iteration
+			return bytes.Compare(key, prefix) > 0
+		}
+		return !fn(key, value)
+	})
+	return err
+}
@@ -6,6 +6,9 @@ import (
 	"github.com/cosmos/iavl"
 	dbm "github.com/cosmos/iavl/db"
 	"github.com/stretchr/testify/suite"
+
+	"github.com/dadamu/contract-wasmvm/internal/auth"
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
 )
 
 type TestSuite struct {
@@ -59,3 +62,239 @@ func (s *TestSuite) TestSaveAndLoadVersion() {
 	s.Require().NoError(err)
 	s.Require().Equal(uint64(expectedVersion), version)
 }
+
+func (s *TestSuite) TestTransferCoins() {
+	s.Require().NoError(s.cache.MintCoins("alice", []interfaces.Coin{
+		interfaces.NewCoin("uatom", 100),
+		interfaces.NewCoin("uosmo", 50),
+	}))
+
+	err := s.cache.TransferCoins("alice", "bob", []interfaces.Coin{
+		interfaces.NewCoin("uatom", 30),
+		interfaces.NewCoin("uosmo", 50),
+	})
+	s.Require().NoError(err)
+
+	s.Require().Equal(uint64(70), s.cache.GetBalance("alice", "uatom"))
+	s.Require().Equal(uint64(0), s.cache.GetBalance("alice", "uosmo"))
+	s.Require().Equal(uint64(30), s.cache.GetBalance("bob", "uatom"))
+	s.Require().Equal(uint64(50), s.cache.GetBalance("bob", "uosmo"))
+}
+
+func (s *TestSuite) TestTransferCoinsInsufficientBalance() {
+	s.Require().NoError(s.cache.MintCoins("alice", []interfaces.Coin{interfaces.NewCoin("uatom", 10)}))
+
+	err := s.cache.TransferCoins("alice", "bob", []interfaces.Coin{interfaces.NewCoin("uatom", 11)})
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), "insufficient balance")
+}
+
+func (s *TestSuite) TestRollbackTransfer() {
+	s.Require().NoError(s.cache.MintCoins("alice", []interfaces.Coin{interfaces.NewCoin("uatom", 10)}))
+	s.cache.Commit()
+
+	err := s.cache.TransferCoins("alice", "bob", []interfaces.Coin{interfaces.NewCoin("uatom", 10)})
+	s.Require().NoError(err)
+	s.cache.Rollback()
+
+	s.Require().Equal(uint64(10), s.cache.GetBalance("alice", "uatom"))
+	s.Require().Equal(uint64(0), s.cache.GetBalance("bob", "uatom"))
+}
+
+func (s *TestSuite) TestBranchCommit() {
+	s.cache.SaveEntity("contract", "key", []byte("value1"))
+
+	branch := s.cache.Branch()
+	s.Require().Equal([]byte("value1"), branch.LoadEntity("contract", "key"))
+
+	branch.SaveEntity("contract", "key", []byte("value2"))
+	s.Require().Equal([]byte("value1"), s.cache.LoadEntity("contract", "key"))
+
+	branch.Commit()
+	s.Require().Equal([]byte("value2"), s.cache.LoadEntity("contract", "key"))
+}
+
+func (s *TestSuite) TestBranchRollback() {
+	s.cache.SaveEntity("contract", "key", []byte("value1"))
+
+	branch := s.cache.Branch()
+	branch.SaveEntity("contract", "key", []byte("value2"))
+	branch.Rollback()
+	branch.Commit()
+
+	// The parent cache keeps its own uncommitted updates
+	s.Require().Equal([]byte("value1"), s.cache.LoadEntity("contract", "key"))
+}
+
+func (s *TestSuite) TestChangeset() {
+	s.cache.SaveEntity("contract", "counter", []byte{1})
+	s.Require().NoError(s.cache.MintCoins("alice", []interfaces.Coin{interfaces.NewCoin("uatom", 10)}))
+	s.cache.Commit()
+
+	s.cache.SaveEntity("contract", "counter", []byte{2})
+	s.cache.SaveEntity("contract", "nested/key", []byte{3})
+	s.Require().NoError(s.cache.TransferCoins("alice", "bob", []interfaces.Coin{interfaces.NewCoin("uatom", 4)}))
+	s.Require().NoError(s.cache.CreateConctract(0, "contract2"))
+	s.cache.set([]byte("custom"), []byte("value"))
+
+	changeset := s.cache.Changeset()
+	s.Require().Len(changeset, 6)
+
+	s.Require().Equal(Change{
+		Key:        "contracts/initialized/contract/counter",
+		OldValue:   []byte{1},
+		NewValue:   []byte{2},
+		Kind:       ChangeKindEntity,
+		ContractId: "contract",
+		EntityKey:  "counter",
+	}, changeset[0])
+
+	s.Require().Nil(changeset[1].OldValue)
+	s.Require().Equal("nested/key", changeset[1].EntityKey)
+
+	s.Require().Equal(ChangeKindBalance, changeset[2].Kind)
+	s.Require().Equal("alice", changeset[2].Address)
+	s.Require().Equal("uatom", changeset[2].Denom)
+	s.Require().Equal([]byte("10"), changeset[2].OldValue)
+	s.Require().Equal([]byte("6"), changeset[2].NewValue)
+
+	s.Require().Equal("bob", changeset[3].Address)
+
+	s.Require().Equal(ChangeKindContractModule, changeset[4].Kind)
+	s.Require().Equal("contract2", changeset[4].ContractId)
+
+	s.Require().Equal(ChangeKindUnknown, changeset[5].Kind)
+}
+
+func (s *TestSuite) TestChangesetDeleted() {
+	s.cache.set([]byte("key1"), []byte("value1"))
+	s.cache.Commit()
+
+	s.cache.delete([]byte("key1"))
+
+	changeset := s.cache.Changeset()
+	s.Require().Len(changeset, 1)
+	s.Require().True(changeset[0].Deleted)
+	s.Require().Equal([]byte("value1"), changeset[0].OldValue)
+	s.Require().Nil(changeset[0].NewValue)
+
+	s.cache.Commit()
+	valueInTree, err := s.tree.Get([]byte("key1"))
+	s.Require().NoError(err)
+	s.Require().Nil(valueInTree)
+}
+
+func (s *TestSuite) TestVersions() {
+	s.cache.set([]byte("key1"), []byte("value1"))
+	s.cache.Commit()
+	s.Require().Equal(uint64(1), s.store.WorkingVersion())
+	_, err := s.store.SaveVersionWithId(1)
+	s.Require().NoError(err)
+
+	s.cache.set([]byte("key2"), []byte("value2"))
+	s.cache.Commit()
+	hash, err := s.store.SaveVersionWithId(2)
+	s.Require().NoError(err)
+
+	versions, err := s.store.Versions()
+	s.Require().NoError(err)
+	s.Require().Len(versions, 2)
+	s.Require().Equal(uint64(2), versions[1].Version)
+	s.Require().Equal(hash, versions[1].Hash)
+}
+
+func (s *TestSuite) TestGetVersioned() {
+	s.cache.set([]byte("key"), []byte("value1"))
+	s.cache.Commit()
+	_, err := s.store.SaveVersionWithId(1)
+	s.Require().NoError(err)
+
+	s.cache.set([]byte("key"), []byte("value2"))
+	s.cache.Commit()
+	_, err = s.store.SaveVersionWithId(2)
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(2), s.store.Version())
+
+	value, err := s.store.GetVersioned([]byte("key"), 1)
+	s.Require().NoError(err)
+	s.Require().Equal([]byte("value1"), value)
+
+	_, err = s.store.GetVersioned([]byte("key"), 3)
+	s.Require().ErrorContains(err, "version 3 does not exist")
+}
+
+func (s *TestSuite) TestContractCodeId() {
+	s.Require().NoError(s.cache.CreateConctract(7, "contract"))
+
+	codeId, err := s.cache.GetContractCodeId("contract")
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(7), codeId)
+
+	_, err = s.cache.GetContractCodeId("unknown")
+	s.Require().ErrorContains(err, "contract does not exist: unknown")
+}
+
+func (s *TestSuite) TestKeyLayout() {
+	// The keys are part of the app hash, the layout of the existing databases
+	// is kept
+	s.Require().Equal("contracts/initialized/contract/key", string(newContractEntityKey("contract", "key")))
+	s.Require().Equal("contracts/entities/contract", string(newContractInitializedKey("contract")))
+
+	codeId, err := parseContractCodeKey(newContractCodeKey(12))
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(12), codeId)
+
+	_, err = parseContractCodeKey([]byte("contracts/modules/12"))
+	s.Require().ErrorContains(err, "invalid contract code key")
+}
+
+func (s *TestSuite) TestIterate() {
+	s.cache.set([]byte("a/1"), []byte("1"))
+	s.cache.set([]byte("b/1"), []byte("2"))
+	s.cache.set([]byte("b/2"), []byte("3"))
+	s.cache.set([]byte("c/1"), []byte("4"))
+	s.cache.Commit()
+
+	var keys []string
+	err := s.store.Iterate([]byte("b/"), func(key, value []byte) bool {
+		keys = append(keys, string(key))
+		return true
+	})
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"b/1", "b/2"}, keys)
+}
+
+func (s *TestSuite) TestPubKeyRegistry() {
+	key, err := auth.GenerateSecp256k1PrivKey()
+	s.Require().NoError(err)
+	address := key.PubKey().Address()
+
+	_, found, err := s.cache.GetPubKey(address)
+	s.Require().NoError(err)
+	s.Require().False(found)
+
+	s.cache.SetPubKey(key.PubKey())
+	pubKey, found, err := s.cache.GetPubKey(address)
+	s.Require().NoError(err)
+	s.Require().True(found)
+	s.Require().Equal(key.PubKey(), pubKey)
+
+	changeset := s.cache.Changeset()
+	s.Require().Len(changeset, 1)
+	s.Require().Equal(ChangeKindPubKey, changeset[0].Kind)
+	s.Require().Equal(address, changeset[0].Address)
+}
+
+func (s *TestSuite) TestSequence() {
+	s.Require().Equal(uint64(0), s.cache.GetSequence("alice"))
+
+	s.cache.IncrementSequence("alice")
+	s.cache.IncrementSequence("alice")
+	s.Require().Equal(uint64(2), s.cache.GetSequence("alice"))
+	s.Require().Equal(uint64(0), s.cache.GetSequence("bob"))
+
+	changeset := s.cache.Changeset()
+	s.Require().Len(changeset, 1)
+	s.Require().Equal(ChangeKindSequence, changeset[0].Kind)
+	s.Require().Equal("alice", changeset[0].Address)
+}
@@ -0,0 +1,278 @@
+package transaction
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/dadamu/contract-wasmvm/internal/auth"
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+)
+
+// EncodingVersion is the first byte of the canonical encoding
+const EncodingVersion = 1
+
+// Message type tags of the canonical encoding
+const (
+	messageTagDeploy      byte = 0x01
+	messageTagInstantiate byte = 0x02
+	messageTagExecute     byte = 0x03
+)
+
+// Encode returns the canonical encoding of the transaction, integers are big
+// endian and variable sized fields are prefixed by their uint32 length:
+//
+//	tx          = body count:u32 signature*
+//	body        = version:u8 chain_id:string sequence:u64 timeout_height:u64
+//	              gas_limit:u64 gas_price:u64 state:bytes count:u32 message*
+//	message     = deploy | instantiate | execute
+//	deploy      = 0x01 sender:string code:bytes
+//	instantiate = 0x02 sender:string code_id:u64 args:bytes funds:coins
+//	execute     = 0x03 sender:string contract:string method:string args:bytes funds:coins
+//	coins       = count:u32 (denom:string amount:u64)*
+//	signature   = pub_key:bytes signature:bytes
+//
+// The public keys are encoded by auth.PubKey.Bytes and the body is the sign
+// bytes. Empty and nil fields are encoded the same, so every transaction has a
+// single encoding.
+func (tx *Tx) Encode() ([]byte, error) {
+	bz, err := tx.encodeBody()
+	if err != nil {
+		return nil, err
+	}
+
+	bz = binary.BigEndian.AppendUint32(bz, uint32(len(tx.Signatures)))
+	for _, signature := range tx.Signatures {
+		bz = appendBytes(bz, signature.PubKey.Bytes())
+		bz = appendBytes(bz, signature.Signature)
+	}
+	return bz, nil
+}
+
+func (tx *Tx) encodeBody() ([]byte, error) {
+	bz := []byte{EncodingVersion}
+	bz = appendBytes(bz, []byte(tx.ChainId))
+	bz = binary.BigEndian.AppendUint64(bz, tx.Sequence)
+	bz = binary.BigEndian.AppendUint64(bz, tx.TimeoutHeight)
+	bz = binary.BigEndian.AppendUint64(bz, tx.GasLimit)
+	bz = binary.BigEndian.AppendUint64(bz, tx.GasPrice)
+	bz = appendBytes(bz, tx.State)
+	bz = binary.BigEndian.AppendUint32(bz, uint32(len(tx.Messages)))
+
+	for i, msg := range tx.Messages {
+		var err error
+		if bz, err = appendMessage(bz, msg); err != nil {
+			return nil, fmt.Errorf("failed to encode message %d: %w", i, err)
+		}
+	}
+	return bz, nil
+}
+
+// Decode parses the canonical encoding of a transaction, trailing bytes are
+// rejected
+func Decode(bz []byte) (*Tx, error) {
+	d := decoder{bz: bz}
+	if version := d.byte(); d.err == nil && version != EncodingVersion {
+		return nil, fmt.Errorf("unsupported encoding version %d", version)
+	}
+
+	tx := &Tx{
+		ChainId:       d.string(),
+		Sequence:      d.uint64(),
+		TimeoutHeight: d.uint64(),
+		GasLimit:      d.uint64(),
+		GasPrice:      d.uint64(),
+		State:         d.bytes(),
+	}
+
+	count := d.uint32()
+	// Every message takes at least one byte, which bounds the allocation
+	if d.err == nil && int(count) > d.remaining() {
+		return nil, fmt.Errorf("message count %d exceeds the encoding size", count)
+	}
+	for i := uint32(0); i < count && d.err == nil; i++ {
+		msg, err := d.message()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode message %d: %w", i, err)
+		}
+		tx.Messages = append(tx.Messages, msg)
+	}
+
+	count = d.uint32()
+	// Every signature takes at least eight bytes
+	if d.err == nil && int(count) > d.remaining()/8 {
+		return nil, fmt.Errorf("signature count %d exceeds the encoding size", count)
+	}
+	for i := uint32(0); i < count && d.err == nil; i++ {
+		pubKeyBz, signature := d.bytes(), d.bytes()
+		if d.err != nil {
+			break
+		}
+
+		pubKey, err := auth.ParsePubKey(pubKeyBz)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode signature %d: %w", i, err)
+		}
+		tx.Signatures = append(tx.Signatures, auth.Signature{PubKey: pubKey, Signature: signature})
+	}
+
+	if d.err != nil {
+		return nil, d.err
+	}
+	if d.remaining() != 0 {
+		return nil, fmt.Errorf("%d trailing bytes", d.remaining())
+	}
+	return tx, nil
+}
+
+func appendMessage(bz []byte, msg interfaces.VMMessage) ([]byte, error) {
+	switch msg := msg.(type) {
+	case interfaces.DeployContractCodeMessage:
+		bz = append(bz, messageTagDeploy)
+		bz = appendBytes(bz, []byte(msg.Sender))
+		bz = appendBytes(bz, msg.Code)
+
+	case interfaces.InitializeContractMessage:
+		bz = append(bz, messageTagInstantiate)
+		bz = appendBytes(bz, []byte(msg.Sender))
+		bz = binary.BigEndian.AppendUint64(bz, msg.CodeId)
+		bz = appendBytes(bz, msg.Args)
+		bz = appendCoins(bz, msg.Funds)
+
+	case interfaces.ContractMessage:
+		bz = append(bz, messageTagExecute)
+		bz = appendBytes(bz, []byte(msg.Sender))
+		bz = appendBytes(bz, []byte(msg.Contract))
+		bz = appendBytes(bz, []byte(msg.Method))
+		bz = appendBytes(bz, msg.Args)
+		bz = appendCoins(bz, msg.Funds)
+
+	default:
+		return nil, fmt.Errorf("unsupported message type %T", msg)
+	}
+	return bz, nil
+}
+
+func appendBytes(bz []byte, value []byte) []byte {
+	bz = binary.BigEndian.AppendUint32(bz, uint32(len(value)))
+	return append(bz, value...)
+}
+
+func appendCoins(bz []byte, coins []interfaces.Coin) []byte {
+	bz = binary.BigEndian.AppendUint32(bz, uint32(len(coins)))
+	for _, coin := range coins {
+		bz = appendBytes(bz, []byte(coin.Denom))
+		bz = binary.BigEndian.AppendUint64(bz, coin.Amount)
+	}
+	return bz
+}
+
+// decoder reads the encoding sequentially, the first error stops the reads
+// which then return zero values
+type decoder struct {
+	bz  []byte
+	err error
+}
+
+var errUnexpectedEnd = errors.New("unexpected end of encoding")
+
+func (d *decoder) remaining() int {
+	return len(d.bz)
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > len(d.bz) {
+		d.err = errUnexpectedEnd
+		return nil
+	}
+
+	value := d.bz[:n]
+	d.bz = d.bz[n:]
+	return value
+}
+
+func (d *decoder) byte() byte {
+	if value := d.next(1); value != nil {
+		return value[0]
+	}
+	return 0
+}
+
+func (d *decoder) uint32() uint32 {
+	if value := d.next(4); value != nil {
+		return binary.BigEndian.Uint32(value)
+	}
+	return 0
+}
+
+func (d *decoder) uint64() uint64 {
+	if value := d.next(8); value != nil {
+		return binary.BigEndian.Uint64(value)
+	}
+	return 0
+}
+
+// bytes returns a copy of the length prefixed value, nil if it is empty
+func (d *decoder) bytes() []byte {
+	value := d.next(int(d.uint32()))
+	if len(value) == 0 {
+		return nil
+	}
+	return append([]byte(nil), value...)
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *decoder) coins() []interfaces.Coin {
+	count := d.uint32()
+	// Every coin takes at least twelve bytes
+	if d.err == nil && int(count) > d.remaining()/12 {
+		d.err = fmt.Errorf("coin count %d exceeds the encoding size", count)
+		return nil
+	}
+
+	var coins []interfaces.Coin
+	for i := uint32(0); i < count && d.err == nil; i++ {
+		coins = append(coins, interfaces.NewCoin(d.string(), d.uint64()))
+	}
+	return coins
+}
+
+func (d *decoder) message() (interfaces.VMMessage, error) {
+	var msg interfaces.VMMessage
+	switch tag := d.byte(); tag {
+	case messageTagDeploy:
+		msg = interfaces.DeployContractCodeMessage{
+			Sender: d.string(),
+			Code:   d.bytes(),
+		}
+
+	case messageTagInstantiate:
+		msg = interfaces.InitializeContractMessage{
+			Sender: d.string(),
+			CodeId: d.uint64(),
+			Args:   d.bytes(),
+			Funds:  d.coins(),
+		}
+
+	case messageTagExecute:
+		msg = interfaces.ContractMessage{
+			Sender:   d.string(),
+			Contract: d.string(),
+			Method:   d.string(),
+			Args:     d.bytes(),
+			Funds:    d.coins(),
+		}
+
+	default:
+		if d.err == nil {
+			return nil, fmt.Errorf("unknown message tag 0x%02x", tag)
+		}
+	}
+	return msg, d.err
+}
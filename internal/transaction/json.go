@@ -0,0 +1,163 @@
+package transaction
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dadamu/contract-wasmvm/internal/auth"
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+)
+
+// Message types of the JSON form
+const (
+	MessageTypeDeploy      = "deploy"
+	MessageTypeInstantiate = "instantiate"
+	MessageTypeExecute     = "execute"
+)
+
+type jsonTx struct {
+	ChainId       string `json:"chain_id"`
+	Sequence      uint64 `json:"sequence"`
+	TimeoutHeight uint64 `json:"timeout_height,omitempty"`
+
+	GasLimit uint64        `json:"gas_limit"`
+	GasPrice uint64        `json:"gas_price,omitempty"`
+	State    []byte        `json:"state,omitempty"`
+	Messages []jsonMessage `json:"messages"`
+
+	Signatures []auth.Signature `json:"signatures,omitempty"`
+}
+
+// jsonMessage is the union of the message fields, the fields used depend on the type
+type jsonMessage struct {
+	Type   string `json:"type"`
+	Sender string `json:"sender"`
+
+	Code     []byte     `json:"code,omitempty"`
+	CodeId   uint64     `json:"code_id,omitempty"`
+	Contract string     `json:"contract,omitempty"`
+	Method   string     `json:"method,omitempty"`
+	Args     []byte     `json:"args,omitempty"`
+	Funds    []jsonCoin `json:"funds,omitempty"`
+}
+
+type jsonCoin struct {
+	Denom  string `json:"denom"`
+	Amount uint64 `json:"amount"`
+}
+
+// MarshalJSON encodes the messages as objects with a type field, bytes are base64
+func (tx Tx) MarshalJSON() ([]byte, error) {
+	value := jsonTx{
+		ChainId:       tx.ChainId,
+		Sequence:      tx.Sequence,
+		TimeoutHeight: tx.TimeoutHeight,
+
+		GasLimit: tx.GasLimit,
+		GasPrice: tx.GasPrice,
+		State:    tx.State,
+		Messages: make([]jsonMessage, 0, len(tx.Messages)),
+
+		Signatures: tx.Signatures,
+	}
+
+	for i, msg := range tx.Messages {
+		var jsonMsg jsonMessage
+		switch msg := msg.(type) {
+		case interfaces.DeployContractCodeMessage:
+			jsonMsg = jsonMessage{Type: MessageTypeDeploy, Sender: msg.Sender, Code: msg.Code}
+		case interfaces.InitializeContractMessage:
+			jsonMsg = jsonMessage{
+				Type:   MessageTypeInstantiate,
+				Sender: msg.Sender,
+				CodeId: msg.CodeId,
+				Args:   msg.Args,
+				Funds:  toJSONCoins(msg.Funds),
+			}
+		case interfaces.ContractMessage:
+			jsonMsg = jsonMessage{
+				Type:     MessageTypeExecute,
+				Sender:   msg.Sender,
+				Contract: msg.Contract,
+				Method:   msg.Method,
+				Args:     msg.Args,
+				Funds:    toJSONCoins(msg.Funds),
+			}
+		default:
+			return nil, fmt.Errorf("failed to encode message %d: unsupported message type %T", i, msg)
+		}
+		value.Messages = append(value.Messages, jsonMsg)
+	}
+
+	return json.Marshal(value)
+}
+
+func (tx *Tx) UnmarshalJSON(bz []byte) error {
+	var value jsonTx
+	if err := json.Unmarshal(bz, &value); err != nil {
+		return err
+	}
+
+	messages := make([]interfaces.VMMessage, 0, len(value.Messages))
+	for i, jsonMsg := range value.Messages {
+		var msg interfaces.VMMessage
+		switch jsonMsg.Type {
+		case MessageTypeDeploy:
+			msg = interfaces.DeployContractCodeMessage{Sender: jsonMsg.Sender, Code: jsonMsg.Code}
+		case MessageTypeInstantiate:
+			msg = interfaces.InitializeContractMessage{
+				Sender: jsonMsg.Sender,
+				CodeId: jsonMsg.CodeId,
+				Args:   jsonMsg.Args,
+				Funds:  fromJSONCoins(jsonMsg.Funds),
+			}
+		case MessageTypeExecute:
+			msg = interfaces.ContractMessage{
+				Sender:   jsonMsg.Sender,
+				Contract: jsonMsg.Contract,
+				Method:   jsonMsg.Method,
+				Args:     jsonMsg.Args,
+				Funds:    fromJSONCoins(jsonMsg.Funds),
+			}
+		default:
+			return fmt.Errorf("failed to decode message %d: unknown message type %q", i, jsonMsg.Type)
+		}
+		messages = append(messages, msg)
+	}
+
+	for i, signature := range value.Signatures {
+		if err := signature.PubKey.Validate(); err != nil {
+			return fmt.Errorf("failed to decode signature %d: %w", i, err)
+		}
+	}
+
+	*tx = Tx{
+		ChainId:       value.ChainId,
+		Sequence:      value.Sequence,
+		TimeoutHeight: value.TimeoutHeight,
+
+		GasLimit: value.GasLimit,
+		GasPrice: value.GasPrice,
+		State:    value.State,
+		Messages: messages,
+
+		Signatures: value.Signatures,
+	}
+	return nil
+}
+
+func toJSONCoins(coins []interfaces.Coin) []jsonCoin {
+	var jsonCoins []jsonCoin
+	for _, coin := range coins {
+		jsonCoins = append(jsonCoins, jsonCoin{Denom: coin.Denom, Amount: coin.Amount})
+	}
+	return jsonCoins
+}
+
+func fromJSONCoins(jsonCoins []jsonCoin) []interfaces.Coin {
+	var coins []interfaces.Coin
+	for _, coin := range jsonCoins {
+		coins = append(coins, interfaces.NewCoin(coin.Denom, coin.Amount))
+	}
+	return coins
+}
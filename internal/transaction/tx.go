@@ -0,0 +1,116 @@
+// Package transaction defines the concrete transaction run by the TxRunner with
+// its canonical binary encoding, its JSON form and its hash.
+package transaction
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/dadamu/contract-wasmvm/internal/auth"
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+)
+
+// Tx is a list of VM messages run atomically with a shared gas limit
+type Tx struct {
+	// ChainId is the chain the transaction is valid on
+	ChainId string
+	// Sequence is the sequence of the first signer, which is incremented when
+	// the transaction is included so it cannot be replayed
+	Sequence uint64
+	// TimeoutHeight is the last height the transaction can be included at,
+	// zero if it does not expire
+	TimeoutHeight uint64
+
+	GasLimit uint64
+	// GasPrice is the price offered per gas unit, which orders the
+	// transactions waiting in the mempool
+	GasPrice uint64
+	// State is the global state passed to the contracts
+	State    []byte
+	Messages []interfaces.VMMessage
+
+	// Signatures are made over the sign bytes, the senders of the messages
+	// must be among the signers
+	Signatures []auth.Signature
+}
+
+func NewTx(gasLimit uint64, state []byte, messages ...interfaces.VMMessage) *Tx {
+	return &Tx{
+		GasLimit: gasLimit,
+		State:    state,
+		Messages: messages,
+	}
+}
+
+func (tx *Tx) GetGasLimit() uint64                 { return tx.GasLimit }
+func (tx *Tx) GetState() []byte                    { return tx.State }
+func (tx *Tx) GetMessages() []interfaces.VMMessage { return tx.Messages }
+func (tx *Tx) GetSignatures() []auth.Signature     { return tx.Signatures }
+func (tx *Tx) GetChainId() string                  { return tx.ChainId }
+func (tx *Tx) GetSequence() uint64                 { return tx.Sequence }
+func (tx *Tx) GetTimeoutHeight() uint64            { return tx.TimeoutHeight }
+
+// GetSignBytes returns the canonical encoding of the transaction without its
+// signatures
+func (tx *Tx) GetSignBytes() ([]byte, error) {
+	return tx.encodeBody()
+}
+
+// Sign adds the signature of the key over the sign bytes
+func (tx *Tx) Sign(key auth.PrivKey) error {
+	signBytes, err := tx.GetSignBytes()
+	if err != nil {
+		return err
+	}
+
+	signature, err := auth.Sign(key, signBytes)
+	if err != nil {
+		return err
+	}
+	tx.Signatures = append(tx.Signatures, signature)
+	return nil
+}
+
+// Validate checks the transaction has messages and their required fields are set
+func (tx *Tx) Validate() error {
+	if len(tx.Messages) == 0 {
+		return errors.New("transaction has no messages")
+	}
+
+	for i, msg := range tx.Messages {
+		if err := validateMessage(msg); err != nil {
+			return fmt.Errorf("invalid message %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Hash returns the SHA-256 of the canonical encoding of the transaction,
+// signatures included
+func (tx *Tx) Hash() ([]byte, error) {
+	bz, err := tx.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(bz)
+	return hash[:], nil
+}
+
+func validateMessage(msg interfaces.VMMessage) error {
+	switch msg := msg.(type) {
+	case interfaces.DeployContractCodeMessage:
+		if len(msg.Code) == 0 {
+			return errors.New("deploy message has no code")
+		}
+	case interfaces.InitializeContractMessage:
+	case interfaces.ContractMessage:
+		if msg.Contract == "" || msg.Method == "" {
+			return errors.New("execute message has no contract or method")
+		}
+	default:
+		return fmt.Errorf("unsupported message type %T", msg)
+	}
+	return nil
+}
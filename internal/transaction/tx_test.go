@@ -0,0 +1,210 @@
+package transaction
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dadamu/contract-wasmvm/internal/auth"
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+)
+
+func newSignedTestTx(t *testing.T) *Tx {
+	tx := newTestTx()
+
+	ed25519Key, err := auth.NewEd25519PrivKey(make([]byte, 32))
+	require.NoError(t, err)
+	secp256k1Key, err := auth.GenerateSecp256k1PrivKey()
+	require.NoError(t, err)
+
+	require.NoError(t, tx.Sign(ed25519Key))
+	require.NoError(t, tx.Sign(secp256k1Key))
+	return tx
+}
+
+func newTestTx() *Tx {
+	execute := interfaces.NewContractMessage("contract", "transfer", []byte(`{"to":"bob"}`), "alice")
+	execute.Funds = []interfaces.Coin{interfaces.NewCoin("uatom", 10), interfaces.NewCoin("uosmo", 20)}
+
+	tx := NewTx(100_000, []byte("state"),
+		interfaces.DeployContractCodeMessage{Code: []byte{0x00, 0x61, 0x73, 0x6d}, Sender: "alice"},
+		interfaces.InitializeContractMessage{
+			CodeId: 7,
+			Args:   []byte("args"),
+			Sender: "alice",
+			Funds:  []interfaces.Coin{interfaces.NewCoin("uatom", 1)},
+		},
+		execute,
+	)
+	tx.ChainId = "testnet"
+	tx.Sequence = 3
+	tx.TimeoutHeight = 100
+	tx.GasPrice = 5
+	return tx
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	tx := newSignedTestTx(t)
+
+	bz, err := tx.Encode()
+	require.NoError(t, err)
+
+	decoded, err := Decode(bz)
+	require.NoError(t, err)
+	require.Equal(t, tx, decoded)
+}
+
+func TestCanonicalEncoding(t *testing.T) {
+	bz, err := NewTx(5, nil, interfaces.DeployContractCodeMessage{Code: []byte{0xff}, Sender: "a"}).Encode()
+	require.NoError(t, err)
+	require.Equal(t, "01"+
+		"00000000"+ // chain id
+		"0000000000000000"+ // sequence
+		"0000000000000000"+ // timeout height
+		"0000000000000005"+ // gas limit
+		"0000000000000000"+ // gas price
+		"00000000"+ // state
+		"00000001"+ // message count
+		"01"+"0000000161"+"00000001ff"+ // deploy sender and code
+		"00000000", // signature count
+		hex.EncodeToString(bz))
+
+	// Nil and empty fields have the same encoding
+	withEmpty, err := NewTx(5, []byte{}, interfaces.InitializeContractMessage{Args: []byte{}, Funds: []interfaces.Coin{}}).Encode()
+	require.NoError(t, err)
+	withNil, err := NewTx(5, nil, interfaces.InitializeContractMessage{}).Encode()
+	require.NoError(t, err)
+	require.Equal(t, withNil, withEmpty)
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	bz, err := newTestTx().Encode()
+	require.NoError(t, err)
+
+	_, err = Decode(bz[:len(bz)-1])
+	require.ErrorContains(t, err, "unexpected end of encoding")
+
+	_, err = Decode(append(bz, 0x00))
+	require.ErrorContains(t, err, "1 trailing bytes")
+
+	_, err = Decode(append([]byte{2}, bz[1:]...))
+	require.ErrorContains(t, err, "unsupported encoding version 2")
+
+	// A message count larger than the encoding is rejected before allocating
+	header := hex.EncodeToString(make([]byte, 4+8+8+8+8+4))
+	bz, _ = hex.DecodeString("01" + header + "ffffffff")
+	_, err = Decode(bz)
+	require.ErrorContains(t, err, "exceeds the encoding size")
+
+	bz, _ = hex.DecodeString("01" + header + "00000001" + "09")
+	_, err = Decode(bz)
+	require.ErrorContains(t, err, "unknown message tag 0x09")
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	tx := newSignedTestTx(t)
+
+	bz, err := json.Marshal(tx)
+	require.NoError(t, err)
+
+	var decoded Tx
+	require.NoError(t, json.Unmarshal(bz, &decoded))
+	require.Equal(t, tx, &decoded)
+
+	err = json.Unmarshal([]byte(`{"gas_limit":1,"messages":[{"type":"unknown"}]}`), &decoded)
+	require.ErrorContains(t, err, `unknown message type "unknown"`)
+}
+
+func TestJSONForm(t *testing.T) {
+	execute := interfaces.NewContractMessage("contract", "method", []byte("args"), "alice")
+	execute.Funds = []interfaces.Coin{interfaces.NewCoin("uatom", 10)}
+
+	tx := NewTx(10, nil, execute)
+	tx.ChainId = "testnet"
+	tx.Sequence = 1
+
+	bz, err := json.Marshal(tx)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"chain_id": "testnet",
+		"sequence": 1,
+		"gas_limit": 10,
+		"messages": [{
+			"type": "execute",
+			"sender": "alice",
+			"contract": "contract",
+			"method": "method",
+			"args": "YXJncw==",
+			"funds": [{"denom": "uatom", "amount": 10}]
+		}]
+	}`, string(bz))
+}
+
+func TestHash(t *testing.T) {
+	tx := newSignedTestTx(t)
+	hash, err := tx.Hash()
+	require.NoError(t, err)
+	require.Len(t, hash, 32)
+
+	// The hash is defined over the canonical bytes, so it survives the JSON form
+	bz, err := json.Marshal(tx)
+	require.NoError(t, err)
+	var decoded Tx
+	require.NoError(t, json.Unmarshal(bz, &decoded))
+	decodedHash, err := decoded.Hash()
+	require.NoError(t, err)
+	require.Equal(t, hash, decodedHash)
+
+	tx.Sequence++
+	otherHash, err := tx.Hash()
+	require.NoError(t, err)
+	require.NotEqual(t, hash, otherHash)
+}
+
+func TestSignBytesExcludeSignatures(t *testing.T) {
+	tx := newTestTx()
+	unsigned, err := tx.GetSignBytes()
+	require.NoError(t, err)
+
+	signed := newSignedTestTx(t)
+	signBytes, err := signed.GetSignBytes()
+	require.NoError(t, err)
+	require.Equal(t, unsigned, signBytes)
+
+	signers, err := auth.VerifySignatures(signBytes, signed.Signatures)
+	require.NoError(t, err)
+	require.Len(t, signers, 2)
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, newTestTx().Validate())
+
+	require.ErrorContains(t, NewTx(10, nil).Validate(), "transaction has no messages")
+	require.ErrorContains(t,
+		NewTx(10, nil, interfaces.DeployContractCodeMessage{Sender: "alice"}).Validate(),
+		"invalid message 0: deploy message has no code")
+	require.ErrorContains(t,
+		NewTx(10, nil, interfaces.NewContractMessage("contract", "", nil, "alice")).Validate(),
+		"execute message has no contract or method")
+}
+
+func TestVerifySignatures(t *testing.T) {
+	key, err := auth.GenerateEd25519PrivKey()
+	require.NoError(t, err)
+	address := key.PubKey().Address()
+
+	tx := NewTx(10, nil, interfaces.NewContractMessage("contract", "method", nil, address))
+	_, err = interfaces.VerifySignatures(tx)
+	require.ErrorIs(t, err, auth.ErrUnauthorized)
+
+	require.NoError(t, tx.Sign(key))
+	signer, err := interfaces.VerifySignatures(tx)
+	require.NoError(t, err)
+	require.Equal(t, address, signer)
+
+	tx.Messages = append(tx.Messages, interfaces.NewContractMessage("contract", "method", nil, "bob"))
+	_, err = interfaces.VerifySignatures(tx)
+	require.ErrorIs(t, err, auth.ErrUnauthorized)
+}
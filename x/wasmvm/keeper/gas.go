@@ -0,0 +1,37 @@
+package keeper
+
+import (
+	"math"
+
+	"cosmossdk.io/core/gas"
+
+	vmgas "github.com/dadamu/contract-wasmvm/internal/contract/gas"
+)
+
+// DefaultGasMultiplier is the VM gas bought by one unit of gas of the meter,
+// the VM charges every instruction so its gas is much finer than the SDK gas
+const DefaultGasMultiplier = 100
+
+// GasDescriptor describes the gas consumed by the VM in the meter
+const GasDescriptor = "wasmvm"
+
+// vmGasLimit converts the gas remaining in the meter to the gas limit of the
+// VM, an infinite meter gives an unlimited execution
+func (k Keeper) vmGasLimit(meter gas.Meter) uint64 {
+	remaining := meter.Remaining()
+	if remaining > math.MaxUint64/k.gasMultiplier {
+		return math.MaxUint64
+	}
+	return remaining * k.gasMultiplier
+}
+
+// consumeGas charges the meter with the VM gas used rounded up, it fails with
+// gas.ErrOutOfGas if the meter runs out
+func (k Keeper) consumeGas(meter gas.Meter, report *vmgas.Report) error {
+	used := report.Limit - report.Remaining
+	amount := used / k.gasMultiplier
+	if used%k.gasMultiplier != 0 {
+		amount++
+	}
+	return meter.Consume(amount, GasDescriptor)
+}
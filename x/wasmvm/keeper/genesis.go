@@ -0,0 +1,154 @@
+package keeper
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corestore "cosmossdk.io/core/store"
+
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+	"github.com/dadamu/contract-wasmvm/internal/runner"
+	"github.com/dadamu/contract-wasmvm/internal/store"
+	"github.com/dadamu/contract-wasmvm/x/wasmvm/types"
+)
+
+// InitGenesis writes the state into an empty module store
+func (k Keeper) InitGenesis(ctx context.Context, gs *types.GenesisState) error {
+	if err := gs.Validate(); err != nil {
+		return err
+	}
+
+	repository := k.Repository(ctx)
+	if repository.GetTotalContractAmount() != 0 {
+		return fmt.Errorf("module store already has codes")
+	}
+
+	// The codes are ordered by id, so they are stored with their id. They are
+	// checked as deployed code since they are run without further checks.
+	r := runner.NewTxRunner(k.executor, repository, k.runnerOpts...)
+	for _, code := range gs.Codes {
+		if err := r.CheckStoredCode(code.Code); err != nil {
+			return fmt.Errorf("invalid code %d: %w", code.CodeId, err)
+		}
+		repository.StoreContractCode(code.Code)
+	}
+
+	for _, contract := range gs.Contracts {
+		if err := repository.CreateConctract(contract.CodeId, contract.ContractId); err != nil {
+			return err
+		}
+		if contract.Initialized {
+			if err := repository.TryInitializeContract(contract.ContractId); err != nil {
+				return err
+			}
+		}
+		for _, entity := range contract.Entities {
+			repository.SaveEntity(contract.ContractId, entity.Key, entity.Value)
+		}
+	}
+
+	for _, balance := range gs.Balances {
+		if err := repository.MintCoins(balance.Address, balance.Coins); err != nil {
+			return err
+		}
+	}
+
+	repository.Commit()
+	return nil
+}
+
+// ExportGenesis reads the state of the module store
+func (k Keeper) ExportGenesis(ctx context.Context) (*types.GenesisState, error) {
+	kv := k.storeService.OpenKVStore(ctx)
+	repository := newRepository(kv)
+	gs := types.DefaultGenesis()
+
+	// The code keys are not ordered by id, so the codes are read by id
+	for codeId := range repository.GetTotalContractAmount() {
+		code, err := repository.GetContractCodeById(codeId)
+		if err != nil {
+			return nil, err
+		}
+		gs.Codes = append(gs.Codes, types.Code{CodeId: codeId, Code: code})
+	}
+
+	err := iteratePrefix(kv, store.CONTRACT_MODULE_PREFIX+"/", func(contractId string, _ []byte) error {
+		codeId, err := repository.GetContractCodeId(contractId)
+		if err != nil {
+			return err
+		}
+
+		// The initialized flags and the entities are stored under each
+		// other's prefix
+		initialized, err := kv.Has([]byte(store.CONTRACT_ENTITY_PREFIX + "/" + contractId))
+		if err != nil {
+			return err
+		}
+
+		contract := types.Contract{ContractId: contractId, CodeId: codeId, Initialized: initialized}
+		err = iteratePrefix(kv, store.CONTRACT_INITIALIZED_PREFIX+"/"+contractId+"/", func(key string, value []byte) error {
+			contract.Entities = append(contract.Entities, types.Entity{Key: key, Value: value})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		gs.Contracts = append(gs.Contracts, contract)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The keys of an address are contiguous since addresses have no slash
+	err = iteratePrefix(kv, store.BANK_BALANCE_PREFIX+"/", func(key string, value []byte) error {
+		address, denom, found := strings.Cut(key, "/")
+		if !found {
+			return fmt.Errorf("invalid balance key: %s", key)
+		}
+
+		amount, err := strconv.ParseUint(string(value), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid balance of %s for %s: %w", denom, address, err)
+		}
+		if amount == 0 {
+			return nil
+		}
+
+		coin := interfaces.NewCoin(denom, amount)
+		if last := len(gs.Balances) - 1; last >= 0 && gs.Balances[last].Address == address {
+			gs.Balances[last].Coins = append(gs.Balances[last].Coins, coin)
+			return nil
+		}
+		gs.Balances = append(gs.Balances, types.Balance{Address: address, Coins: []interfaces.Coin{coin}})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return gs, nil
+}
+
+// iteratePrefix calls fn with the keys starting with the prefix, without the
+// prefix, in ascending order
+func iteratePrefix(kv corestore.KVStore, prefix string, fn func(key string, value []byte) error) error {
+	// The prefixes end with a slash, the end of the range is the next byte
+	end := []byte(prefix)
+	end[len(end)-1]++
+
+	iterator, err := kv.Iterator([]byte(prefix), end)
+	if err != nil {
+		return err
+	}
+	defer iterator.Close()
+
+	for ; iterator.Valid(); iterator.Next() {
+		if err := fn(strings.TrimPrefix(string(iterator.Key()), prefix), iterator.Value()); err != nil {
+			return err
+		}
+	}
+	return iterator.Error()
+}
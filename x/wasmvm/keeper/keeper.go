@@ -0,0 +1,117 @@
+// Package keeper adapts the VM to a Cosmos SDK module, the contracts are
+// stored in the module store with the key layout of the standalone node and
+// run by a TxRunner charging the gas meter of the context.
+package keeper
+
+import (
+	"context"
+
+	"cosmossdk.io/core/gas"
+	corestore "cosmossdk.io/core/store"
+
+	"github.com/dadamu/contract-wasmvm/internal/contract/executor"
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+	"github.com/dadamu/contract-wasmvm/internal/runner"
+	"github.com/dadamu/contract-wasmvm/internal/store"
+	"github.com/dadamu/contract-wasmvm/internal/transaction"
+)
+
+type Keeper struct {
+	storeService corestore.KVStoreService
+	gasService   gas.Service
+	executor     executor.ContractExecutor
+
+	runnerOpts []runner.Option
+
+	// gasMultiplier is the VM gas bought by one unit of gas of the meter
+	gasMultiplier uint64
+}
+
+type Option func(*Keeper)
+
+// WithRunnerOptions configures the runner of the messages. The messages carry
+// no signatures, their signers are authenticated by the caller as documented
+// by types.Msg, so signature verification should not be enabled.
+func WithRunnerOptions(opts ...runner.Option) Option {
+	return func(k *Keeper) {
+		k.runnerOpts = append(k.runnerOpts, opts...)
+	}
+}
+
+// WithGasMultiplier overrides the VM gas bought by one unit of gas of the meter
+func WithGasMultiplier(multiplier uint64) Option {
+	return func(k *Keeper) {
+		k.gasMultiplier = multiplier
+	}
+}
+
+func NewKeeper(
+	storeService corestore.KVStoreService,
+	gasService gas.Service,
+	executor executor.ContractExecutor,
+	opts ...Option,
+) Keeper {
+	keeper := Keeper{
+		storeService:  storeService,
+		gasService:    gasService,
+		executor:      executor,
+		gasMultiplier: DefaultGasMultiplier,
+	}
+
+	for _, opt := range opts {
+		opt(&keeper)
+	}
+	return keeper
+}
+
+// Repository returns the contract repository of the module store of the
+// context, the writes are cached until it is committed
+func (k Keeper) Repository(ctx context.Context) *store.CacheKVStore {
+	return newRepository(k.storeService.OpenKVStore(ctx))
+}
+
+// newRepository wraps the store in the CacheKVStore of the standalone node,
+// so both share the key layout. The errors of the store are raised as panics
+// like in the store of the node, the runner raises them again with their
+// original value so the SDK handles them, such as its out of gas panics.
+func newRepository(kv corestore.KVStore) *store.CacheKVStore {
+	return store.NewCacheKVStore(
+		func(key []byte) []byte {
+			value, err := kv.Get(key)
+			if err != nil {
+				panic(err)
+			}
+			return value
+		},
+		func(key, value []byte) {
+			if err := kv.Set(key, value); err != nil {
+				panic(err)
+			}
+		},
+		func(key []byte) {
+			if err := kv.Delete(key); err != nil {
+				panic(err)
+			}
+		},
+	)
+}
+
+// runMessage runs the message with the gas left in the meter and charges the
+// gas used even if it failed, the writes are committed only if it succeeded
+func (k Keeper) runMessage(ctx context.Context, msg interfaces.VMMessage) (*runner.TxResult, error) {
+	meter := k.gasService.GasMeter(ctx)
+	repository := k.Repository(ctx)
+	defer repository.Rollback()
+
+	r := runner.NewTxRunner(k.executor, repository, k.runnerOpts...)
+	result, err := r.RunTransaction(transaction.NewTx(k.vmGasLimit(meter), nil, msg))
+	if gasErr := k.consumeGas(meter, result.GasReport); gasErr != nil {
+		return nil, gasErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	repository.Commit()
+	return result, nil
+}
@@ -0,0 +1,248 @@
+package keeper
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"cosmossdk.io/core/gas"
+	corestore "cosmossdk.io/core/store"
+	"github.com/bytecodealliance/wasmtime-go/v31"
+	dbm "github.com/cosmos/iavl/db"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/dadamu/contract-wasmvm/internal/contract/executor"
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+	"github.com/dadamu/contract-wasmvm/internal/contract/runtime"
+	"github.com/dadamu/contract-wasmvm/x/wasmvm/types"
+)
+
+// memStoreService opens the same in-memory store for every context
+type memStoreService struct {
+	db *dbm.MemDB
+}
+
+func (s memStoreService) OpenKVStore(context.Context) corestore.KVStore { return s.db }
+
+type testMeter struct {
+	limit    uint64
+	consumed uint64
+}
+
+func (m *testMeter) Consume(amount gas.Gas, _ string) error {
+	if amount > m.limit-m.consumed {
+		m.consumed = m.limit
+		return gas.ErrOutOfGas
+	}
+	m.consumed += amount
+	return nil
+}
+
+func (m *testMeter) Refund(amount gas.Gas, _ string) error {
+	m.consumed -= min(amount, m.consumed)
+	return nil
+}
+
+func (m *testMeter) Remaining() gas.Gas { return m.limit - m.consumed }
+func (m *testMeter) Limit() gas.Gas     { return m.limit }
+
+type testGasService struct {
+	meter *testMeter
+}
+
+func (s testGasService) GasMeter(context.Context) gas.Meter      { return s.meter }
+func (s testGasService) BlockGasMeter(context.Context) gas.Meter { return s.meter }
+func (s testGasService) GasConfig(context.Context) gas.GasConfig { return gas.GasConfig{} }
+
+type KeeperTestSuite struct {
+	suite.Suite
+	db        *dbm.MemDB
+	meter     *testMeter
+	keeper    Keeper
+	msgServer types.MsgServer
+
+	contract string
+}
+
+func (s *KeeperTestSuite) newKeeper(db *dbm.MemDB) Keeper {
+	executor := executor.NewContractExecutor(runtime.NewEngine())
+	return NewKeeper(memStoreService{db: db}, testGasService{meter: s.meter}, *executor)
+}
+
+func (s *KeeperTestSuite) SetupTest() {
+	s.db = dbm.NewMemDB()
+	s.meter = &testMeter{limit: gas.NoGasLimit}
+	s.keeper = s.newKeeper(s.db)
+	s.msgServer = NewMsgServerImpl(s.keeper)
+
+	code, err := os.ReadFile("../../../internal/runner/testdata/test.wasm")
+	s.Require().NoError(err)
+
+	ctx := context.Background()
+	stored, err := s.msgServer.StoreCode(ctx, &types.MsgStoreCode{Sender: "alice", Code: code})
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(0), stored.CodeId)
+
+	instantiated, err := s.msgServer.InstantiateContract(ctx, &types.MsgInstantiateContract{Sender: "alice", CodeId: 0})
+	s.Require().NoError(err)
+	s.Require().NotEmpty(instantiated.ContractId)
+	s.contract = instantiated.ContractId
+}
+
+func TestKeeperTestSuite(t *testing.T) {
+	suite.Run(t, new(KeeperTestSuite))
+}
+
+// -----------------------------------------------------------------------------
+
+func (s *KeeperTestSuite) execute(method string) (*types.MsgExecuteContractResponse, error) {
+	return s.msgServer.ExecuteContract(context.Background(), &types.MsgExecuteContract{
+		Sender:   "alice",
+		Contract: s.contract,
+		Method:   method,
+	})
+}
+
+func (s *KeeperTestSuite) entity() []byte {
+	return s.keeper.Repository(context.Background()).LoadEntity(s.contract, "test")
+}
+
+func (s *KeeperTestSuite) TestExecuteContract() {
+	consumed := s.meter.consumed
+
+	_, err := s.execute("addOne")
+	s.Require().NoError(err)
+	s.Require().Equal([]byte{1, 0, 0, 0}, s.entity())
+
+	// The keys are those of the standalone node
+	value, err := s.db.Get([]byte("contracts/initialized/" + s.contract + "/test"))
+	s.Require().NoError(err)
+	s.Require().Equal([]byte{1, 0, 0, 0}, value)
+
+	// The VM gas is charged to the meter of the context
+	s.Require().Greater(s.meter.consumed, consumed)
+
+	response, err := s.execute("emitEvent")
+	s.Require().NoError(err)
+	s.Require().Len(response.Events, 1)
+}
+
+func (s *KeeperTestSuite) TestFailedExecution() {
+	consumed := s.meter.consumed
+
+	_, err := s.execute("crash")
+	s.Require().Error(err)
+	s.Require().Nil(s.entity())
+
+	// The gas burned is charged even if the execution failed
+	s.Require().Greater(s.meter.consumed, consumed)
+}
+
+func (s *KeeperTestSuite) TestOutOfGas() {
+	// One unit of gas buys too little VM gas to run the method
+	s.meter.limit = s.meter.consumed + 1
+
+	_, err := s.execute("addOne")
+	s.Require().Error(err)
+	s.Require().Nil(s.entity())
+	s.Require().Zero(s.meter.Remaining())
+}
+
+func (s *KeeperTestSuite) TestInvalidMessage() {
+	_, err := s.msgServer.StoreCode(context.Background(), &types.MsgStoreCode{Sender: "alice"})
+	s.Require().ErrorContains(err, "missing code")
+
+	_, err = s.msgServer.ExecuteContract(context.Background(), &types.MsgExecuteContract{Sender: "alice", Contract: s.contract})
+	s.Require().ErrorContains(err, "missing contract or method")
+
+	_, err = s.msgServer.InstantiateContract(context.Background(), &types.MsgInstantiateContract{
+		Sender: "alice",
+		Funds:  []interfaces.Coin{interfaces.NewCoin("", 1)},
+	})
+	s.Require().ErrorContains(err, "invalid coin denom")
+}
+
+func (s *KeeperTestSuite) TestGenesisRoundTrip() {
+	ctx := context.Background()
+	_, err := s.execute("addOne")
+	s.Require().NoError(err)
+
+	repository := s.keeper.Repository(ctx)
+	s.Require().NoError(repository.MintCoins("bob", []interfaces.Coin{interfaces.NewCoin("uatom", 10), interfaces.NewCoin("ibc/denom", 5)}))
+	s.Require().NoError(repository.MintCoins("alice", []interfaces.Coin{interfaces.NewCoin("uatom", 3)}))
+	repository.Commit()
+
+	exported, err := s.keeper.ExportGenesis(ctx)
+	s.Require().NoError(err)
+	s.Require().NoError(exported.Validate())
+	s.Require().Len(exported.Codes, 1)
+	s.Require().Equal([]types.Contract{{
+		ContractId:  s.contract,
+		CodeId:      0,
+		Initialized: true,
+		Entities:    []types.Entity{{Key: "test", Value: []byte{1, 0, 0, 0}}},
+	}}, exported.Contracts)
+	s.Require().Equal([]types.Balance{
+		{Address: "alice", Coins: []interfaces.Coin{interfaces.NewCoin("uatom", 3)}},
+		{Address: "bob", Coins: []interfaces.Coin{interfaces.NewCoin("ibc/denom", 5), interfaces.NewCoin("uatom", 10)}},
+	}, exported.Balances)
+
+	// The imported store exports the same state and runs the contract
+	imported := s.newKeeper(dbm.NewMemDB())
+	s.Require().NoError(imported.InitGenesis(ctx, exported))
+
+	reexported, err := imported.ExportGenesis(ctx)
+	s.Require().NoError(err)
+	s.Require().Equal(exported, reexported)
+
+	s.keeper = imported
+	s.msgServer = NewMsgServerImpl(imported)
+	_, err = s.execute("addOne")
+	s.Require().NoError(err)
+	s.Require().Equal([]byte{2, 0, 0, 0}, s.entity())
+
+	// The genesis is only written into an empty store
+	s.Require().ErrorContains(imported.InitGenesis(ctx, exported), "module store already has codes")
+}
+
+func (s *KeeperTestSuite) TestGenesisChecksCodes() {
+	ctx := context.Background()
+
+	// The code imports the gas function without calling it, it would run
+	// with unlimited fuel
+	unmetered, err := wasmtime.Wat2Wasm(`
+		(module
+		  (import "runtime" "stack_overflow" (func))
+		  (import "runtime" "gas" (func (param i64)))
+		  (memory (export "memory") 1)
+		  (func (export "__new") (param i32 i32) (result i32) (i32.const 1024))
+		  (func (export "init") (param i32 i32 i32) (loop $loop (br $loop))))
+	`)
+	s.Require().NoError(err)
+
+	// The code has not been stack limited by a deploy
+	unlimited, err := os.ReadFile("../../../internal/runner/testdata/test.wasm")
+	s.Require().NoError(err)
+
+	for code, expected := range map[string]string{
+		string(unmetered): "unknown host function runtime.gas",
+		string(unlimited): "code is not stack limited",
+		"not wasm":        "failed to check code",
+	} {
+		gs := types.DefaultGenesis()
+		gs.Codes = []types.Code{{CodeId: 0, Code: []byte(code)}}
+
+		keeper := s.newKeeper(dbm.NewMemDB())
+		s.Require().ErrorContains(keeper.InitGenesis(ctx, gs), expected)
+		s.Require().Zero(keeper.Repository(ctx).GetTotalContractAmount())
+	}
+}
+
+func (s *KeeperTestSuite) TestDefaultGenesis() {
+	keeper := s.newKeeper(dbm.NewMemDB())
+	s.Require().NoError(keeper.InitGenesis(context.Background(), types.DefaultGenesis()))
+
+	exported, err := keeper.ExportGenesis(context.Background())
+	s.Require().NoError(err)
+	s.Require().Equal(types.DefaultGenesis(), exported)
+}
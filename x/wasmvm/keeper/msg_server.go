@@ -0,0 +1,64 @@
+package keeper
+
+import (
+	"context"
+
+	"github.com/dadamu/contract-wasmvm/x/wasmvm/types"
+)
+
+type msgServer struct {
+	Keeper
+}
+
+var _ types.MsgServer = msgServer{}
+
+// NewMsgServerImpl returns the handlers of the module messages. The handlers
+// trust the sender of the messages, the caller must authenticate it first.
+func NewMsgServerImpl(k Keeper) types.MsgServer {
+	return msgServer{Keeper: k}
+}
+
+func (s msgServer) StoreCode(ctx context.Context, msg *types.MsgStoreCode) (*types.MsgStoreCodeResponse, error) {
+	if err := msg.Validate(); err != nil {
+		return nil, err
+	}
+
+	// The code is stored with the next code id
+	codeId := s.Repository(ctx).GetTotalContractAmount()
+	if _, err := s.runMessage(ctx, msg.VMMessage()); err != nil {
+		return nil, err
+	}
+	return &types.MsgStoreCodeResponse{CodeId: codeId}, nil
+}
+
+func (s msgServer) InstantiateContract(ctx context.Context, msg *types.MsgInstantiateContract) (*types.MsgInstantiateContractResponse, error) {
+	if err := msg.Validate(); err != nil {
+		return nil, err
+	}
+
+	result, err := s.runMessage(ctx, msg.VMMessage())
+	if err != nil {
+		return nil, err
+	}
+
+	response := &types.MsgInstantiateContractResponse{Events: result.Events}
+	for _, event := range result.Events {
+		if event.Event == "initialized" {
+			response.ContractId = event.ContractId
+			break
+		}
+	}
+	return response, nil
+}
+
+func (s msgServer) ExecuteContract(ctx context.Context, msg *types.MsgExecuteContract) (*types.MsgExecuteContractResponse, error) {
+	if err := msg.Validate(); err != nil {
+		return nil, err
+	}
+
+	result, err := s.runMessage(ctx, msg.VMMessage())
+	if err != nil {
+		return nil, err
+	}
+	return &types.MsgExecuteContractResponse{Events: result.Events}, nil
+}
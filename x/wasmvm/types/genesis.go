@@ -0,0 +1,101 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+)
+
+// GenesisState is the state of the module, the accounts are left to the
+// authentication of the chain
+type GenesisState struct {
+	// Codes are ordered by id, the ids start from zero without gaps
+	Codes     []Code     `json:"codes"`
+	Contracts []Contract `json:"contracts"`
+	Balances  []Balance  `json:"balances"`
+}
+
+// Code is the stored code, it was already instrumented when it was deployed.
+// InitGenesis checks it again against the runner of the keeper.
+type Code struct {
+	CodeId uint64 `json:"code_id"`
+	Code   []byte `json:"code"`
+}
+
+type Contract struct {
+	ContractId  string   `json:"contract_id"`
+	CodeId      uint64   `json:"code_id"`
+	Initialized bool     `json:"initialized"`
+	Entities    []Entity `json:"entities"`
+}
+
+// Entity is a value saved by the contract in its namespace
+type Entity struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type Balance struct {
+	Address string            `json:"address"`
+	Coins   []interfaces.Coin `json:"coins"`
+}
+
+func DefaultGenesis() *GenesisState {
+	return &GenesisState{}
+}
+
+// Validate checks the codes are ordered by id and the contracts reference
+// them, and the contract ids, entity keys and balances are unique
+func (gs GenesisState) Validate() error {
+	for i, code := range gs.Codes {
+		if code.CodeId != uint64(i) {
+			return fmt.Errorf("invalid code id %d at index %d", code.CodeId, i)
+		}
+		if len(code.Code) == 0 {
+			return fmt.Errorf("empty code %d", code.CodeId)
+		}
+	}
+
+	contracts := make(map[string]bool, len(gs.Contracts))
+	for _, contract := range gs.Contracts {
+		if contract.ContractId == "" {
+			return errors.New("empty contract id")
+		}
+		if contracts[contract.ContractId] {
+			return fmt.Errorf("duplicate contract %s", contract.ContractId)
+		}
+		contracts[contract.ContractId] = true
+
+		if contract.CodeId >= uint64(len(gs.Codes)) {
+			return fmt.Errorf("contract %s references unknown code %d", contract.ContractId, contract.CodeId)
+		}
+
+		keys := make(map[string]bool, len(contract.Entities))
+		for _, entity := range contract.Entities {
+			if keys[entity.Key] {
+				return fmt.Errorf("duplicate entity %s of contract %s", entity.Key, contract.ContractId)
+			}
+			keys[entity.Key] = true
+		}
+	}
+
+	balances := make(map[string]bool)
+	for _, balance := range gs.Balances {
+		if balance.Address == "" {
+			return errors.New("empty balance address")
+		}
+		for _, coin := range balance.Coins {
+			if coin.Denom == "" {
+				return fmt.Errorf("invalid coin denom of %s: empty", balance.Address)
+			}
+
+			key := balance.Address + "/" + coin.Denom
+			if balances[key] {
+				return fmt.Errorf("duplicate balance of %s for %s", coin.Denom, balance.Address)
+			}
+			balances[key] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+)
+
+func TestGenesisValidate(t *testing.T) {
+	require.NoError(t, DefaultGenesis().Validate())
+
+	valid := func() GenesisState {
+		return GenesisState{
+			Codes:     []Code{{CodeId: 0, Code: []byte{0x00}}},
+			Contracts: []Contract{{ContractId: "contract", CodeId: 0, Entities: []Entity{{Key: "key"}}}},
+			Balances:  []Balance{{Address: "alice", Coins: []interfaces.Coin{interfaces.NewCoin("uatom", 1)}}},
+		}
+	}
+	require.NoError(t, valid().Validate())
+
+	testCases := []struct {
+		name   string
+		modify func(gs *GenesisState)
+		err    string
+	}{
+		{"code id gap", func(gs *GenesisState) { gs.Codes[0].CodeId = 1 }, "invalid code id 1 at index 0"},
+		{"empty code", func(gs *GenesisState) { gs.Codes[0].Code = nil }, "empty code 0"},
+		{"unknown code", func(gs *GenesisState) { gs.Contracts[0].CodeId = 1 }, "references unknown code 1"},
+		{"duplicate contract", func(gs *GenesisState) { gs.Contracts = append(gs.Contracts, gs.Contracts[0]) }, "duplicate contract"},
+		{"duplicate entity", func(gs *GenesisState) {
+			gs.Contracts[0].Entities = append(gs.Contracts[0].Entities, Entity{Key: "key"})
+		}, "duplicate entity key"},
+		{"empty denom", func(gs *GenesisState) { gs.Balances[0].Coins[0].Denom = "" }, "invalid coin denom"},
+		{"duplicate balance", func(gs *GenesisState) { gs.Balances = append(gs.Balances, gs.Balances[0]) }, "duplicate balance"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gs := valid()
+			tc.modify(&gs)
+			require.ErrorContains(t, gs.Validate(), tc.err)
+		})
+	}
+}
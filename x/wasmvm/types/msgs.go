@@ -0,0 +1,143 @@
+// Package types defines the messages and the genesis state of the wasmvm
+// module.
+package types
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+)
+
+// ModuleName is the name of the module and the prefix of its store
+const ModuleName = "wasmvm"
+
+// Msg is a message of the module. The messages are plain Go types rather than
+// protobuf sdk.Msg types, so the SDK can neither route them nor authenticate
+// their sender: the caller must check the signers signed the transaction
+// before calling the MsgServer, which runs the message as the sender.
+type Msg interface {
+	Validate() error
+	// GetSigners returns the addresses which must have signed the message
+	GetSigners() []string
+	VMMessage() interfaces.VMMessage
+}
+
+var (
+	_ Msg = MsgStoreCode{}
+	_ Msg = MsgInstantiateContract{}
+	_ Msg = MsgExecuteContract{}
+)
+
+// MsgServer handles the messages of the module, the senders must already be
+// authenticated
+type MsgServer interface {
+	StoreCode(ctx context.Context, msg *MsgStoreCode) (*MsgStoreCodeResponse, error)
+	InstantiateContract(ctx context.Context, msg *MsgInstantiateContract) (*MsgInstantiateContractResponse, error)
+	ExecuteContract(ctx context.Context, msg *MsgExecuteContract) (*MsgExecuteContractResponse, error)
+}
+
+// MsgStoreCode deploys the code, it is checked and instrumented before it is
+// stored
+type MsgStoreCode struct {
+	Sender string `json:"sender"`
+	Code   []byte `json:"code"`
+}
+
+type MsgStoreCodeResponse struct {
+	CodeId uint64 `json:"code_id"`
+}
+
+func (msg MsgStoreCode) Validate() error {
+	if msg.Sender == "" {
+		return errors.New("missing sender")
+	}
+	if len(msg.Code) == 0 {
+		return errors.New("missing code")
+	}
+	return nil
+}
+
+func (msg MsgStoreCode) GetSigners() []string {
+	return []string{msg.Sender}
+}
+
+func (msg MsgStoreCode) VMMessage() interfaces.VMMessage {
+	return interfaces.DeployContractCodeMessage{Code: msg.Code, Sender: msg.Sender}
+}
+
+// MsgInstantiateContract creates a contract from the code and runs its init
+// method
+type MsgInstantiateContract struct {
+	Sender string            `json:"sender"`
+	CodeId uint64            `json:"code_id"`
+	Args   []byte            `json:"args,omitempty"`
+	Funds  []interfaces.Coin `json:"funds,omitempty"`
+}
+
+type MsgInstantiateContractResponse struct {
+	ContractId string                   `json:"contract_id"`
+	Events     []interfaces.ResultEvent `json:"events"`
+}
+
+func (msg MsgInstantiateContract) Validate() error {
+	if msg.Sender == "" {
+		return errors.New("missing sender")
+	}
+	return validateFunds(msg.Funds)
+}
+
+func (msg MsgInstantiateContract) GetSigners() []string {
+	return []string{msg.Sender}
+}
+
+func (msg MsgInstantiateContract) VMMessage() interfaces.VMMessage {
+	return interfaces.InitializeContractMessage{
+		CodeId: msg.CodeId,
+		Args:   msg.Args,
+		Sender: msg.Sender,
+		Funds:  msg.Funds,
+	}
+}
+
+// MsgExecuteContract runs a method of the contract
+type MsgExecuteContract struct {
+	Sender   string            `json:"sender"`
+	Contract string            `json:"contract"`
+	Method   string            `json:"method"`
+	Args     []byte            `json:"args,omitempty"`
+	Funds    []interfaces.Coin `json:"funds,omitempty"`
+}
+
+type MsgExecuteContractResponse struct {
+	Events []interfaces.ResultEvent `json:"events"`
+}
+
+func (msg MsgExecuteContract) Validate() error {
+	if msg.Sender == "" {
+		return errors.New("missing sender")
+	}
+	if msg.Contract == "" || msg.Method == "" {
+		return errors.New("missing contract or method")
+	}
+	return validateFunds(msg.Funds)
+}
+
+func (msg MsgExecuteContract) GetSigners() []string {
+	return []string{msg.Sender}
+}
+
+func (msg MsgExecuteContract) VMMessage() interfaces.VMMessage {
+	message := interfaces.NewContractMessage(msg.Contract, msg.Method, msg.Args, msg.Sender)
+	message.Funds = msg.Funds
+	return message
+}
+
+func validateFunds(funds []interfaces.Coin) error {
+	for _, coin := range funds {
+		if coin.Denom == "" {
+			return errors.New("invalid coin denom: empty")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dadamu/contract-wasmvm/internal/contract/interfaces"
+)
+
+func TestMsgSigners(t *testing.T) {
+	msgs := []Msg{
+		MsgStoreCode{Sender: "alice", Code: []byte{0x00}},
+		MsgInstantiateContract{Sender: "alice"},
+		MsgExecuteContract{Sender: "alice", Contract: "contract", Method: "method"},
+	}
+
+	// The message runs as its signer, so authenticating the signers
+	// authenticates the sender of the VM
+	for _, msg := range msgs {
+		require.Equal(t, []string{"alice"}, msg.GetSigners())
+		require.Equal(t, "alice", interfaces.MessageSender(msg.VMMessage()))
+	}
+}